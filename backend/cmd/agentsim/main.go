@@ -0,0 +1,224 @@
+// backend/cmd/agentsim/main.go
+//
+// agentsim runs thousands of simulated agents in one process against the
+// real agent.Manager, job queue and monitoring code, for load and
+// integration testing without real servers. Example:
+//
+//	go run ./cmd/agentsim -agents 5000 -job-rate 200 -duration 10m \
+//	    -patterns steady=80,spike=10,leak=5,failure=5
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/jobqueue"
+	"github.com/autosysadmin/backend/internal/monitoring"
+	"github.com/autosysadmin/backend/internal/tsdb"
+)
+
+const simVersion = "sim-1.0.0"
+
+type config struct {
+	agents            int
+	duration          time.Duration
+	patterns          string
+	heartbeatInterval time.Duration
+	statsInterval     time.Duration
+
+	spikeProbability   float64
+	leakPerTick        float64
+	failureProbability float64
+	failureDowntime    time.Duration
+
+	jobRate        float64
+	jobLatency     time.Duration
+	jobJitter      time.Duration
+	jobFailureRate float64
+	jobTimeout     time.Duration
+	maxInflight    int
+	workers        int
+
+	redisAddr   string
+	redisPrefix string
+
+	monitorInterval time.Duration
+	cpuThreshold    float64
+	memThreshold    float64
+
+	reportInterval time.Duration
+	seed           int64
+}
+
+func main() {
+	var cfg config
+	flag.IntVar(&cfg.agents, "agents", 1000, "number of simulated agents")
+	flag.DurationVar(&cfg.duration, "duration", 0, "how long to run (0 runs until interrupted)")
+	flag.StringVar(&cfg.patterns, "patterns", "steady=70,spike=10,leak=10,failure=10", "stats pattern weights")
+	flag.DurationVar(&cfg.heartbeatInterval, "heartbeat-interval", 10*time.Second, "heartbeat interval per agent")
+	flag.DurationVar(&cfg.statsInterval, "stats-interval", 15*time.Second, "stats report interval per agent")
+	flag.Float64Var(&cfg.spikeProbability, "spike-probability", 0.05, "chance per stats tick that a spike agent starts a CPU burst")
+	flag.Float64Var(&cfg.leakPerTick, "leak-per-tick", 1.5, "average memory percent a leak agent gains per stats tick")
+	flag.Float64Var(&cfg.failureProbability, "failure-probability", 0.02, "chance per stats tick that a failure agent goes down")
+	flag.DurationVar(&cfg.failureDowntime, "failure-downtime", 2*time.Minute, "typical downtime of a failed agent")
+	flag.Float64Var(&cfg.jobRate, "job-rate", 20, "jobs submitted per second across the fleet (0 disables)")
+	flag.DurationVar(&cfg.jobLatency, "job-latency", 200*time.Millisecond, "base time an agent takes to run a job")
+	flag.DurationVar(&cfg.jobJitter, "job-jitter", 300*time.Millisecond, "random extra job latency")
+	flag.Float64Var(&cfg.jobFailureRate, "job-failure-rate", 0.02, "fraction of jobs that fail")
+	flag.DurationVar(&cfg.jobTimeout, "job-timeout", 30*time.Second, "give up waiting on a job after this long")
+	flag.IntVar(&cfg.maxInflight, "max-inflight", 2000, "maximum jobs awaiting completion at once")
+	flag.IntVar(&cfg.workers, "workers", 32, "job executor goroutines")
+	flag.StringVar(&cfg.redisAddr, "redis", "", "Redis address for the job queue (in-memory queue when empty)")
+	flag.StringVar(&cfg.redisPrefix, "redis-prefix", "agentsim", "Redis key prefix")
+	flag.DurationVar(&cfg.monitorInterval, "monitor-interval", 15*time.Second, "monitoring poll interval per agent (0 disables)")
+	flag.Float64Var(&cfg.cpuThreshold, "cpu-threshold", 90, "CPU alert threshold")
+	flag.Float64Var(&cfg.memThreshold, "memory-threshold", 90, "memory alert threshold")
+	flag.DurationVar(&cfg.reportInterval, "report-interval", 10*time.Second, "how often to print a summary")
+	flag.Int64Var(&cfg.seed, "seed", time.Now().UnixNano(), "random seed")
+	flag.Parse()
+
+	if cfg.agents <= 0 || cfg.workers <= 0 || cfg.maxInflight <= 0 {
+		log.Fatal("agents, workers and max-inflight must be positive")
+	}
+	names, weights, err := parsePatterns(cfg.patterns)
+	if err != nil {
+		log.Fatalf("Invalid -patterns: %v", err)
+	}
+
+	var queue jobqueue.JobQueue
+	if cfg.redisAddr != "" {
+		queue = jobqueue.NewRedisJobQueue(cfg.redisAddr, cfg.redisPrefix)
+	} else {
+		queue = jobqueue.NewInMemoryJobQueue()
+	}
+	defer queue.Close()
+
+	manager := agent.NewManager(queue)
+	// A silent agent's stats go stale after a few missed reports, so the
+	// failure pattern shows up as the agent being down
+	manager.SetStatsMaxAge(3 * cfg.statsInterval)
+	manager.SetHeartbeatTimeout(3 * cfg.heartbeatInterval)
+	rules := monitoring.NewRuleEngine(manager, monitoring.NewAlertManager())
+	// Memory-only; a simulated fleet's history isn't worth keeping
+	metricsDB, err := tsdb.Open("", tsdb.DefaultOptions)
+	if err != nil {
+		log.Fatalf("Failed to open metrics store: %v", err)
+	}
+	monitor := monitoring.NewMonitor(manager, rules, monitoring.NewTSDBMetricsStorage(metricsDB))
+	if cfg.monitorInterval > 0 {
+		// Fleet-wide rules; per-agent thresholds would cost one rule per agent
+		// on every evaluation
+		for metric, threshold := range map[string]float64{"cpu": cfg.cpuThreshold, "memory": cfg.memThreshold} {
+			if _, err := rules.AddRule(monitoring.AlertRule{
+				Name:      metric + " high",
+				Metric:    metric,
+				Operator:  monitoring.OpGreater,
+				Threshold: threshold,
+				For:       2 * cfg.monitorInterval,
+			}); err != nil {
+				log.Fatalf("Failed to add %s rule: %v", metric, err)
+			}
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if cfg.duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+		defer cancel()
+	}
+	go metricsDB.Run(ctx)
+	go rules.Run(ctx)
+
+	// Register the fleet
+	agents := make(map[string]*simAgent, cfg.agents)
+	ids := make([]string, 0, cfg.agents)
+	patternCounts := make(map[string]int)
+	rng := rand.New(rand.NewSource(cfg.seed))
+	start := time.Now()
+	for i := 0; i < cfg.agents; i++ {
+		pick := rng.Intn(weights[len(weights)-1])
+		pattern := names[sort.SearchInts(weights, pick+1)]
+
+		s := newSimAgent(i, pattern, rng.Int63())
+		s.agent.LastHeartbeat = time.Now()
+		manager.RegisterAgent(s.agent)
+		agents[s.agent.ID] = s
+		ids = append(ids, s.agent.ID)
+		patternCounts[pattern]++
+
+		if cfg.monitorInterval > 0 {
+			if err := monitor.StartMonitoring(s.agent.ID, cfg.monitorInterval); err != nil {
+				log.Fatalf("Failed to start monitoring %s: %v", s.agent.ID, err)
+			}
+		}
+	}
+	log.Printf("Registered %d agents in %s %v", cfg.agents, time.Since(start).Round(time.Millisecond), patternCounts)
+
+	c := &counters{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, s := range agents {
+			go runAgent(ctx, s, manager, cfg, c)
+		}
+		go runExecutors(ctx, queue, agents, cfg)
+		runLoad(ctx, manager, ids, cfg, c)
+	}()
+
+	ticker := time.NewTicker(cfg.reportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			report(manager, monitor, queue, ids, cfg, c)
+		case <-ctx.Done():
+			<-done
+			report(manager, monitor, queue, ids, cfg, c)
+			if cfg.monitorInterval > 0 {
+				for _, id := range ids {
+					monitor.StopMonitoring(id)
+				}
+			}
+			return
+		}
+	}
+}
+
+func report(manager *agent.Manager, monitor monitoring.Monitor, queue jobqueue.JobQueue, ids []string, cfg config, c *counters) {
+	stale := 0
+	cutoff := time.Now().Add(-3 * cfg.heartbeatInterval)
+	for _, a := range manager.ListAgents() {
+		if a.LastHeartbeat.Before(cutoff) {
+			stale++
+		}
+	}
+
+	alerts := 0
+	if cfg.monitorInterval > 0 {
+		for _, id := range ids {
+			if list, err := monitor.GetAlerts(id); err == nil {
+				alerts += len(list)
+			}
+		}
+	}
+
+	queued := -1
+	if mq, ok := queue.(*jobqueue.InMemoryJobQueue); ok {
+		queued = mq.Len()
+	}
+
+	n, p50, p95, p99 := c.drainLatencies()
+	log.Printf("agents=%d stale=%d heartbeats=%d (errors %d) stats=%d | jobs enqueued=%d completed=%d failed=%d timed_out=%d dropped=%d queued=%d | latency n=%d p50=%s p95=%s p99=%s | alerts=%d",
+		len(ids), stale, c.heartbeats.Load(), c.heartbeatErrors.Load(), c.statsReports.Load(),
+		c.jobsEnqueued.Load(), c.jobsCompleted.Load(), c.jobsFailed.Load(), c.jobsTimedOut.Load(), c.jobsDropped.Load(), queued,
+		n, p50.Round(time.Millisecond), p95.Round(time.Millisecond), p99.Round(time.Millisecond), alerts)
+}
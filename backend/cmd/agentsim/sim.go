@@ -0,0 +1,356 @@
+// backend/cmd/agentsim/sim.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/jobqueue"
+)
+
+// Stats patterns a simulated agent can follow.
+const (
+	patternSteady  = "steady"  // small noise around a baseline
+	patternSpike   = "spike"   // occasional CPU bursts well above alert thresholds
+	patternLeak    = "leak"    // memory climbs steadily until a simulated restart
+	patternFailure = "failure" // goes silent for a while: no heartbeats, stats or jobs
+)
+
+// parsePatterns parses "steady=70,spike=10,..." into cumulative weights.
+func parsePatterns(spec string) ([]string, []int, error) {
+	var names []string
+	var cumulative []int
+	total := 0
+	for _, part := range strings.Split(spec, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid pattern %q, want name=weight", part)
+		}
+		switch name {
+		case patternSteady, patternSpike, patternLeak, patternFailure:
+		default:
+			return nil, nil, fmt.Errorf("unknown pattern %q", name)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, nil, fmt.Errorf("invalid weight for %s", name)
+		}
+		total += w
+		names = append(names, name)
+		cumulative = append(cumulative, total)
+	}
+	if total == 0 {
+		return nil, nil, fmt.Errorf("pattern weights sum to zero")
+	}
+	return names, cumulative, nil
+}
+
+type simAgent struct {
+	agent   *agent.Agent
+	pattern string
+	rng     *rand.Rand
+
+	mu          sync.Mutex
+	baseCPU     float64
+	baseMemory  float64
+	memory      float64
+	spikeTicks  int
+	downUntil   time.Time
+	networkBase float64
+	bytesIn     float64 // eth0 counters
+	bytesOut    float64
+}
+
+func newSimAgent(id int, pattern string, seed int64) *simAgent {
+	rng := rand.New(rand.NewSource(seed))
+	a := &agent.Agent{
+		ID:           fmt.Sprintf("sim-%05d", id),
+		Name:         fmt.Sprintf("sim-%05d", id),
+		Hostname:     fmt.Sprintf("sim-%05d.sim.local", id),
+		IPAddress:    fmt.Sprintf("10.%d.%d.%d", (id>>16)&0xff, (id>>8)&0xff, id&0xff),
+		OS:           "linux",
+		Architecture: "amd64",
+		Version:      simVersion,
+		Status:       "online",
+		Tags:         []string{"sim", "pattern:" + pattern},
+	}
+	s := &simAgent{
+		agent:       a,
+		pattern:     pattern,
+		rng:         rng,
+		baseCPU:     10 + rng.Float64()*30,
+		baseMemory:  20 + rng.Float64()*30,
+		networkBase: 100 + rng.Float64()*900,
+	}
+	s.memory = s.baseMemory
+	return s
+}
+
+func (s *simAgent) down(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Before(s.downUntil)
+}
+
+// tick advances the agent's synthetic state by one stats interval and
+// returns the stats to report, or nil while the agent is down.
+func (s *simAgent) tick(now time.Time, cfg config) *agent.AgentStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Before(s.downUntil) {
+		return nil
+	}
+
+	noise := func(scale float64) float64 { return (s.rng.Float64()*2 - 1) * scale }
+	cpu := s.baseCPU + noise(5)
+	memory := s.baseMemory + noise(2)
+
+	switch s.pattern {
+	case patternSpike:
+		if s.spikeTicks == 0 && s.rng.Float64() < cfg.spikeProbability {
+			s.spikeTicks = 1 + s.rng.Intn(5)
+		}
+		if s.spikeTicks > 0 {
+			s.spikeTicks--
+			cpu = 92 + s.rng.Float64()*8
+		}
+	case patternLeak:
+		s.memory += cfg.leakPerTick * (0.5 + s.rng.Float64())
+		if s.memory >= 99 {
+			// Simulated OOM kill and restart
+			s.memory = s.baseMemory
+		}
+		memory = s.memory
+	case patternFailure:
+		if s.rng.Float64() < cfg.failureProbability {
+			s.downUntil = now.Add(cfg.failureDowntime/2 + time.Duration(s.rng.Int63n(int64(cfg.failureDowntime))))
+			return nil
+		}
+	}
+
+	disk := clamp(40 + noise(1))
+	networkIn := math.Max(0, s.networkBase+noise(s.networkBase/4))
+	networkOut := math.Max(0, s.networkBase/2+noise(s.networkBase/8))
+	s.bytesIn += networkIn * cfg.statsInterval.Seconds()
+	s.bytesOut += networkOut * cfg.statsInterval.Seconds()
+
+	return &agent.AgentStats{
+		AgentID: s.agent.ID,
+		System: agent.SystemStats{
+			CPUUsage:    clamp(cpu),
+			MemoryUsage: clamp(memory),
+			DiskUsage:   disk,
+			NetworkIn:   networkIn,
+			NetworkOut:  networkOut,
+			Disks: []agent.DiskStats{
+				{Mount: "/", Device: "/dev/vda1", Usage: disk},
+				{Mount: "/var", Device: "/dev/vdb1", Usage: clamp(disk/2 + noise(1))},
+			},
+			Interfaces: []agent.InterfaceStats{{Name: "eth0", BytesIn: s.bytesIn, BytesOut: s.bytesOut}},
+			Timestamp:  now,
+		},
+		Processes: []agent.ProcessStats{
+			{PID: 1, Name: "systemd", CPUUsage: 0.1, MemoryUsage: 0.4},
+			{PID: 812, Name: "sim-workload", CPUUsage: clamp(cpu * 0.8), MemoryUsage: clamp(memory * 0.7)},
+		},
+	}
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(100, v))
+}
+
+// counters are the simulator's own measurements, read by the reporter.
+type counters struct {
+	heartbeats      atomic.Int64
+	heartbeatErrors atomic.Int64
+	statsReports    atomic.Int64
+	jobsEnqueued    atomic.Int64
+	jobsCompleted   atomic.Int64
+	jobsFailed      atomic.Int64
+	jobsTimedOut    atomic.Int64
+	jobsDropped     atomic.Int64 // not enqueued because too many were in flight
+
+	mu        sync.Mutex
+	latencies []time.Duration // since the last report
+}
+
+func (c *counters) observe(d time.Duration) {
+	c.mu.Lock()
+	c.latencies = append(c.latencies, d)
+	c.mu.Unlock()
+}
+
+// drainLatencies returns p50, p95 and p99 of the latencies observed since
+// the previous call.
+func (c *counters) drainLatencies() (int, time.Duration, time.Duration, time.Duration) {
+	c.mu.Lock()
+	l := c.latencies
+	c.latencies = nil
+	c.mu.Unlock()
+
+	if len(l) == 0 {
+		return 0, 0, 0, 0
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+	at := func(p float64) time.Duration { return l[int(math.Ceil(p*float64(len(l))))-1] }
+	return len(l), at(0.50), at(0.95), at(0.99)
+}
+
+// runAgent drives one simulated agent's heartbeat and stats loops. Start
+// times are staggered so thousands of agents don't fire in lockstep.
+func runAgent(ctx context.Context, s *simAgent, manager *agent.Manager, cfg config, c *counters) {
+	offset := time.Duration(s.rng.Int63n(int64(cfg.heartbeatInterval)))
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(offset):
+	}
+
+	heartbeat := time.NewTicker(cfg.heartbeatInterval)
+	defer heartbeat.Stop()
+	stats := time.NewTicker(cfg.statsInterval)
+	defer stats.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-heartbeat.C:
+			if s.down(now) {
+				continue
+			}
+			if err := manager.Heartbeat(s.agent.ID, simVersion); err != nil {
+				c.heartbeatErrors.Add(1)
+				continue
+			}
+			c.heartbeats.Add(1)
+		case now := <-stats.C:
+			report := s.tick(now, cfg)
+			if report == nil {
+				continue
+			}
+			if err := manager.ReportStats(s.agent.ID, report); err == nil {
+				c.statsReports.Add(1)
+			}
+		}
+	}
+}
+
+// runExecutors plays the agent side of the job queue: it dequeues jobs and
+// completes or fails them after a simulated latency.
+func runExecutors(ctx context.Context, queue jobqueue.JobQueue, agents map[string]*simAgent, cfg config) {
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+
+			for ctx.Err() == nil {
+				job, err := queue.Dequeue(ctx)
+				if err != nil || job == nil {
+					select {
+					case <-ctx.Done():
+					case <-time.After(10 * time.Millisecond):
+					}
+					continue
+				}
+
+				latency := cfg.jobLatency
+				if cfg.jobJitter > 0 {
+					latency += time.Duration(rng.Int63n(int64(cfg.jobJitter)))
+				}
+				fail := rng.Float64() < cfg.jobFailureRate
+				s := agents[job.AgentID]
+
+				go func(job *jobqueue.Job) {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(latency):
+					}
+					switch {
+					case s == nil:
+						queue.FailJob(context.Background(), job.ID, "unknown agent")
+					case s.down(time.Now()):
+						queue.FailJob(context.Background(), job.ID, "agent unreachable")
+					case fail:
+						queue.FailJob(context.Background(), job.ID, "simulated failure")
+					default:
+						queue.CompleteJob(context.Background(), job.ID, fmt.Sprintf("ok: %s %s", job.Command, strings.Join(job.Args, " ")))
+					}
+				}(job)
+			}
+		}(cfg.seed + int64(i))
+	}
+	wg.Wait()
+}
+
+// runLoad submits jobs to random agents at cfg.jobRate per second through
+// Manager.RunCommandAndWait and records end-to-end latency. Latency
+// includes WaitForJob's polling interval.
+func runLoad(ctx context.Context, manager *agent.Manager, ids []string, cfg config, c *counters) {
+	if cfg.jobRate <= 0 {
+		return
+	}
+	rng := rand.New(rand.NewSource(cfg.seed))
+	inflight := make(chan struct{}, cfg.maxInflight)
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.jobRate))
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		select {
+		case inflight <- struct{}{}:
+		default:
+			c.jobsDropped.Add(1)
+			continue
+		}
+
+		agentID := ids[rng.Intn(len(ids))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inflight }()
+
+			jobCtx, cancel := context.WithTimeout(ctx, cfg.jobTimeout)
+			defer cancel()
+
+			c.jobsEnqueued.Add(1)
+			start := time.Now()
+			job, err := manager.RunCommandAndWait(jobCtx, agentID, agent.AgentCommand{
+				Command: "uptime",
+				Timeout: cfg.jobTimeout,
+			})
+			switch {
+			case err == nil:
+				c.jobsCompleted.Add(1)
+				c.observe(time.Since(start))
+			case job != nil && job.Status == "failed":
+				c.jobsFailed.Add(1)
+				c.observe(time.Since(start))
+			case ctx.Err() == nil && jobCtx.Err() == context.DeadlineExceeded:
+				c.jobsTimedOut.Add(1)
+			}
+		}()
+	}
+}
@@ -0,0 +1,119 @@
+// backend/cmd/inventory-import/main.go
+//
+// inventory-import sends an Ansible INI/YAML inventory or CSV host list to
+// the backend's import endpoint and prints the enrollment command for each
+// new host along with any conflicts. Example:
+//
+//	inventory-import -file hosts.ini -server https://autosysadmin.example.com -dry-run
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/inventory"
+)
+
+func main() {
+	file := flag.String("file", "", "inventory file to import")
+	format := flag.String("format", "", "ini, yaml or csv (detected when empty)")
+	server := flag.String("server", os.Getenv("AUTOSYSADMIN_URL"), "backend base URL")
+	token := flag.String("token", os.Getenv("AUTOSYSADMIN_TOKEN"), "API bearer token")
+	dryRun := flag.Bool("dry-run", false, "report what would be imported without creating agents")
+	local := flag.Bool("local", false, "only parse the file and print the hosts, without contacting the server")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("Failed to read inventory: %v", err)
+	}
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*file)) {
+		case ".yml", ".yaml":
+			*format = inventory.FormatYAML
+		case ".csv":
+			*format = inventory.FormatCSV
+		}
+	}
+
+	if *local {
+		hosts, err := inventory.Parse(*format, data)
+		if err != nil {
+			log.Fatalf("Failed to parse inventory: %v", err)
+		}
+		for _, h := range hosts {
+			fmt.Printf("%s\taddress=%s\tgroups=%s\n", h.Name, h.Address, strings.Join(h.Groups, ","))
+		}
+		return
+	}
+
+	if *server == "" || *token == "" {
+		log.Fatal("-server and -token (or AUTOSYSADMIN_URL and AUTOSYSADMIN_TOKEN) are required")
+	}
+
+	query := url.Values{}
+	if *format != "" {
+		query.Set("format", *format)
+	}
+	if *dryRun {
+		query.Set("dry_run", "true")
+	}
+	endpoint := strings.TrimRight(*server, "/") + "/api/v1/inventory/import?" + query.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		log.Fatalf("Failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatalf("Import request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		log.Fatalf("Import failed (%s): %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		Import inventory.ImportResult `json:"import"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		log.Fatalf("Unexpected response: %v", err)
+	}
+
+	verb := "Imported"
+	if out.Import.DryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d hosts, %d conflicts\n\n", verb, len(out.Import.Hosts), len(out.Import.Conflicts))
+	for _, h := range out.Import.Hosts {
+		fmt.Printf("%s (%s) groups=%s\n", h.Name, h.AgentID, strings.Join(h.Groups, ","))
+		if h.EnrollmentCommand != "" {
+			fmt.Printf("  %s\n", h.EnrollmentCommand)
+		}
+	}
+	if len(out.Import.Conflicts) > 0 {
+		fmt.Println("\nConflicts:")
+		for _, c := range out.Import.Conflicts {
+			fmt.Printf("  %s: %s %q already used by agent %s\n", c.Host, c.Field, c.Value, c.ExistingAgentID)
+		}
+	}
+}
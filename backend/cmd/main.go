@@ -2,20 +2,42 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 
+	"github.com/autosysadmin/backend/internal/accounts"
 	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/agentupdate"
 	"github.com/autosysadmin/backend/internal/api"
 	"github.com/autosysadmin/backend/internal/auth"
 	"github.com/autosysadmin/backend/internal/billing"
+	"github.com/autosysadmin/backend/internal/containers"
+	"github.com/autosysadmin/backend/internal/desiredstate"
+	"github.com/autosysadmin/backend/internal/inventory"
 	"github.com/autosysadmin/backend/internal/jobqueue"
+	"github.com/autosysadmin/backend/internal/logs"
+	"github.com/autosysadmin/backend/internal/maintenance"
 	"github.com/autosysadmin/backend/internal/monitoring"
+	"github.com/autosysadmin/backend/internal/oncall"
 	"github.com/autosysadmin/backend/internal/patching"
+	"github.com/autosysadmin/backend/internal/process"
+	"github.com/autosysadmin/backend/internal/runbook"
+	"github.com/autosysadmin/backend/internal/scheduler"
+	"github.com/autosysadmin/backend/internal/scripts"
 	"github.com/autosysadmin/backend/internal/security"
 	"github.com/autosysadmin/backend/internal/subscriptions"
+	"github.com/autosysadmin/backend/internal/systemd"
+	"github.com/autosysadmin/backend/internal/topology"
+	"github.com/autosysadmin/backend/internal/transfer"
+	"github.com/autosysadmin/backend/internal/tsdb"
 	"github.com/autosysadmin/backend/internal/usage"
 )
 
@@ -24,12 +46,76 @@ func main() {
 	authService := auth.NewAuthService()
 	jobQueue := jobqueue.NewRedisJobQueue()
 	agentManager := agent.NewManager(jobQueue)
-	monitoringService := monitoring.NewMonitor()
-	patchingService := patching.NewPatchManager()
+	maintenanceService := maintenance.NewService(agentManager)
+	notificationTemplates := monitoring.NewTemplateRenderer(agentManager)
+	alertNotifiers := loadAlertNotifiers(notificationTemplates)
+	defaultNotifiers := make([]monitoring.AlertNotifier, 0, len(alertNotifiers))
+	for _, notifier := range alertNotifiers {
+		defaultNotifiers = append(defaultNotifiers, notifier)
+	}
+	alertManager := monitoring.NewAlertManager(defaultNotifiers...)
+	for name, notifier := range alertNotifiers {
+		alertManager.AddReceiver(name, notifier)
+	}
+	alertManager.SetMaintenance(maintenanceService)
+	if getEnv("ALERT_GROUPING", "true") == "true" {
+		if err := alertManager.SetGrouping(monitoring.DefaultGroupConfig); err != nil {
+			log.Fatalf("Failed to configure alert grouping: %v", err)
+		}
+	}
+	alertRuleEngine := monitoring.NewRuleEngine(agentManager, alertManager)
+	alertRuleStore, err := monitoring.NewFileRuleStore(getEnv("ALERT_RULES_DIR", "/var/lib/autosysadmin/alert-rules"))
+	if err != nil {
+		log.Fatalf("Failed to open alert rule store: %v", err)
+	}
+	if err := alertRuleEngine.SetStore(alertRuleStore); err != nil {
+		log.Fatalf("Failed to load alert rules: %v", err)
+	}
+	metricsDB, err := tsdb.Open(getEnv("METRICS_DIR", "/var/lib/autosysadmin/metrics"), tsdb.DefaultOptions)
+	if err != nil {
+		log.Fatalf("Failed to open metrics store: %v", err)
+	}
+	monitoringService := monitoring.NewMonitor(agentManager, alertRuleEngine, monitoring.NewTSDBMetricsStorage(metricsDB))
+	notificationTemplates.SetMetricSource(monitoringService)
+	patchingService := patching.NewPatchManager(agentManager, jobQueue, maintenanceService)
 	securityScanner := security.NewVulnerabilityScanner()
 	billingService := billing.NewBillingService()
 	subscriptionService := subscriptions.NewService()
 	usageTracker := usage.NewTracker()
+	transferService := transfer.NewService(agentManager, transfer.DefaultConfig())
+	systemdService := systemd.NewService(agentManager)
+	logService := logs.NewService(agentManager, logs.NewInMemoryStore(), logs.DefaultRetention)
+	logRuleEvaluator := monitoring.NewLogRuleEvaluator(agentManager, alertManager)
+
+	releaseStore, err := agentupdate.NewFileReleaseStore(getEnv("RELEASE_DIR", "/var/lib/autosysadmin/releases"), loadReleaseSigningKey())
+	if err != nil {
+		log.Fatalf("Failed to open agent release store: %v", err)
+	}
+	rolloutService := agentupdate.NewRolloutService(agentManager, releaseStore, getEnv("PUBLIC_URL", "http://localhost:8080"))
+	desiredStateService := desiredstate.NewService(agentManager, transferService, alertManager)
+	runbookService := runbook.NewService(agentManager, transferService, runbook.NewInMemoryRepository())
+	scriptService := scripts.NewService(agentManager)
+	inventoryService := inventory.NewService(agentManager, getEnv("PUBLIC_URL", "http://localhost:8080"))
+	schedulerService := scheduler.NewService(agentManager, maintenanceService)
+	processService := process.NewService(agentManager)
+	processRuleEvaluator := monitoring.NewProcessRuleEvaluator(agentManager, alertManager)
+	containerService := containers.NewService(agentManager)
+	topologyService := topology.NewService(agentManager)
+	accountService := accounts.NewService(agentManager, alertManager)
+	oncallService := oncall.NewService(alertManager, loadPager(notificationTemplates))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Feed every ingested log line to the log alert rules
+	logService.OnIngest(logRuleEvaluator.Process)
+	go logRuleEvaluator.Run(ctx)
+	go schedulerService.Run(ctx)
+	go processRuleEvaluator.Run(ctx)
+	go alertRuleEngine.Run(ctx)
+	go alertManager.Run(ctx)
+	go oncallService.Run(ctx)
+	go metricsDB.Run(ctx)
 
 	// Start the API server
 	apiServer := api.NewServer(
@@ -41,6 +127,30 @@ func main() {
 		billingService,
 		subscriptionService,
 		usageTracker,
+		transferService,
+		systemdService,
+		logService,
+		logRuleEvaluator,
+		releaseStore,
+		rolloutService,
+		desiredStateService,
+		runbookService,
+		scriptService,
+		inventoryService,
+		maintenanceService,
+		schedulerService,
+		processService,
+		processRuleEvaluator,
+		containerService,
+		topologyService,
+		accountService,
+		alertRuleEngine,
+		alertManager,
+		oncallService,
+		notificationTemplates,
+		metricsDB,
+		jobQueue,
+		getEnv("METRICS_TOKEN", ""),
 	)
 
 	go func() {
@@ -57,5 +167,102 @@ func main() {
 	log.Println("Shutting down server...")
 	apiServer.Stop()
 	jobQueue.Close()
+	if err := metricsDB.Close(); err != nil {
+		log.Printf("Failed to close metrics store: %v", err)
+	}
 	log.Println("Server exited properly")
-}
\ No newline at end of file
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// loadAlertNotifiers builds the configured notification channels, keyed
+// by the receiver name alert rules route to. Alerts without receivers go
+// to all of them.
+func loadAlertNotifiers(templates *monitoring.TemplateRenderer) map[string]monitoring.AlertNotifier {
+	notifiers := make(map[string]monitoring.AlertNotifier)
+
+	if sender := loadSMTPSender(); sender != nil {
+		notifiers["email"] = &monitoring.EmailNotifier{
+			Sender:    sender,
+			To:        strings.FieldsFunc(os.Getenv("ALERT_EMAIL_TO"), func(r rune) bool { return r == ',' }),
+			Templates: templates,
+		}
+	}
+	if url := os.Getenv("SLACK_WEBHOOK_URL"); url != "" {
+		notifiers["slack"] = &monitoring.SlackNotifier{WebhookURL: url, Templates: templates}
+	}
+	if url := os.Getenv("ALERT_WEBHOOK_URL"); url != "" {
+		notifiers["webhook"] = &monitoring.WebhookNotifier{URL: url, Secret: os.Getenv("ALERT_WEBHOOK_SECRET")}
+	}
+	if key := os.Getenv("PAGERDUTY_ROUTING_KEY"); key != "" {
+		notifiers["pagerduty"] = &monitoring.PagerDutyNotifier{RoutingKey: key, Templates: templates}
+	}
+	if key := os.Getenv("OPSGENIE_API_KEY"); key != "" {
+		notifiers["opsgenie"] = &monitoring.OpsgenieNotifier{APIKey: key, URL: os.Getenv("OPSGENIE_API_URL"), Templates: templates}
+	}
+	return notifiers
+}
+
+// loadSMTPSender returns the configured SMTP sender, or nil without SMTP_HOST.
+func loadSMTPSender() monitoring.EmailSender {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil
+	}
+	port, err := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	if err != nil {
+		log.Fatalf("SMTP_PORT must be a number: %v", err)
+	}
+	return monitoring.NewSMTPSender(monitoring.SMTPConfig{
+		Server:   host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getEnv("SMTP_FROM", "alerts@autosysadmin.local"),
+	})
+}
+
+// loadPager pages on-call users through the contacts in
+// ONCALL_CONTACTS_FILE. Without one, or for users it doesn't list, pages
+// are only logged.
+func loadPager(templates *monitoring.TemplateRenderer) oncall.Pager {
+	path := os.Getenv("ONCALL_CONTACTS_FILE")
+	if path == "" {
+		return oncall.LogPager{}
+	}
+	contacts, err := oncall.LoadContacts(path)
+	if err != nil {
+		log.Fatalf("Failed to load on-call contacts: %v", err)
+	}
+	return &oncall.NotifierPager{
+		Contacts:  contacts,
+		Sender:    loadSMTPSender(),
+		Templates: templates,
+		Fallback:  oncall.LogPager{},
+	}
+}
+
+// loadReleaseSigningKey reads the base64 ed25519 seed used to sign agent
+// releases. Without one, an ephemeral key is generated and agents will not
+// accept releases across backend restarts.
+func loadReleaseSigningKey() ed25519.PrivateKey {
+	if seed := os.Getenv("RELEASE_SIGNING_KEY"); seed != "" {
+		raw, err := base64.StdEncoding.DecodeString(seed)
+		if err != nil || len(raw) != ed25519.SeedSize {
+			log.Fatalf("RELEASE_SIGNING_KEY must be a base64 %d-byte ed25519 seed", ed25519.SeedSize)
+		}
+		return ed25519.NewKeyFromSeed(raw)
+	}
+
+	log.Println("RELEASE_SIGNING_KEY not set, using an ephemeral release signing key")
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatalf("Failed to generate release signing key: %v", err)
+	}
+	return key
+}
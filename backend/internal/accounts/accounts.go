@@ -0,0 +1,112 @@
+// backend/internal/accounts/accounts.go
+package accounts
+
+import (
+	"context"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type Service interface {
+	DeclareUser(user ManagedUser) (*ManagedUser, error)
+	DeclareGroup(group ManagedGroup) (*ManagedGroup, error)
+	DeclareSudoRule(rule SudoRule) (*SudoRule, error)
+	DeleteUser(id string) error
+	DeleteGroup(id string) error
+	DeleteSudoRule(id string) error
+	ListUsers() []ManagedUser
+	ListGroups() []ManagedGroup
+	ListSudoRules() []SudoRule
+	// PlanForAgent is the full account plan an apply sends to the agent.
+	PlanForAgent(agentID string) (*agent.AccountPlan, error)
+	// Apply queues an account.apply job on every matching agent.
+	Apply(ctx context.Context, sel agent.Selector) []ApplyResult
+	// Scan asks the agent for its accounts and records the drift.
+	Scan(ctx context.Context, agentID string) ([]Drift, error)
+	ReportAccounts(agentID string, report agent.AccountReport) ([]Drift, error)
+	GetDrift(agentID string) []Drift
+	ListDrift() map[string][]Drift
+	// Offboard removes every declaration for the user and locks or deletes
+	// the account on all agents.
+	Offboard(ctx context.Context, req OffboardRequest) (*Offboarding, error)
+	GetOffboarding(id string) (*Offboarding, error)
+}
+
+// ManagedUser declares a local account on every matching agent. Sudo, when
+// set, is the sudoers specification granted to the user, e.g.
+// "ALL=(ALL) NOPASSWD: ALL".
+type ManagedUser struct {
+	ID             string         `json:"id"`
+	Selector       agent.Selector `json:"selector"`
+	Username       string         `json:"username"`
+	UID            int            `json:"uid,omitempty"`
+	Shell          string         `json:"shell,omitempty"`
+	Comment        string         `json:"comment,omitempty"`
+	Groups         []string       `json:"groups,omitempty"`
+	AuthorizedKeys []string       `json:"authorized_keys,omitempty"`
+	Sudo           string         `json:"sudo,omitempty"`
+	State          string         `json:"state"` // present, absent
+}
+
+type ManagedGroup struct {
+	ID       string         `json:"id"`
+	Selector agent.Selector `json:"selector"`
+	Name     string         `json:"name"`
+	GID      int            `json:"gid,omitempty"`
+	State    string         `json:"state"` // present, absent
+}
+
+// SudoRule grants Spec to a user or, with a leading %, a group.
+type SudoRule struct {
+	ID        string         `json:"id"`
+	Selector  agent.Selector `json:"selector"`
+	Principal string         `json:"principal"`
+	Spec      string         `json:"spec"`
+}
+
+// Drift kinds.
+const (
+	DriftUser    = "user"
+	DriftGroup   = "group"
+	DriftUID0    = "uid0"    // a UID 0 account other than root
+	DriftSudoer  = "sudoer"  // a user with sudo who isn't granted it by a declaration
+	DriftSudoers = "sudoers" // a managed sudo rule missing from the host
+)
+
+type Drift struct {
+	AgentID    string    `json:"agent_id"`
+	Kind       string    `json:"kind"`
+	Resource   string    `json:"resource"`
+	DeclaredBy string    `json:"declared_by,omitempty"`
+	Field      string    `json:"field"` // exists, uid, shell, groups, authorized_keys, gid, sudo
+	Expected   string    `json:"expected"`
+	Actual     string    `json:"actual"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+type ApplyResult struct {
+	AgentID string `json:"agent_id"`
+	JobID   string `json:"job_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type OffboardRequest struct {
+	Username string `json:"username"`
+	// Delete removes the account and home directory; otherwise it's locked
+	Delete bool   `json:"delete"`
+	Reason string `json:"reason"`
+	By     string `json:"by"`
+}
+
+type Offboarding struct {
+	ID                  string            `json:"id"`
+	Username            string            `json:"username"`
+	Delete              bool              `json:"delete"`
+	Reason              string            `json:"reason"`
+	By                  string            `json:"by"`
+	RemovedDeclarations []string          `json:"removed_declarations"`
+	Jobs                map[string]string `json:"jobs"` // agentID -> job ID
+	Errors              map[string]string `json:"errors,omitempty"`
+	CreatedAt           time.Time         `json:"created_at"`
+}
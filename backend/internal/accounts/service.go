@@ -0,0 +1,702 @@
+// backend/internal/accounts/service.go
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/monitoring"
+)
+
+type service struct {
+	agentManager *agent.Manager
+	alertManager *monitoring.AlertManager
+	users        map[string]ManagedUser  // declarationID -> user
+	groups       map[string]ManagedGroup // declarationID -> group
+	sudoRules    map[string]SudoRule     // declarationID -> rule
+	drift        map[string][]Drift      // agentID -> drift from the last report
+	alertIDs     map[string]string       // agentID|kind|resource|field -> alertID
+	offboardings map[string]Offboarding  // offboardingID -> offboarding
+	mu           sync.RWMutex
+}
+
+func NewService(agentManager *agent.Manager, alertManager *monitoring.AlertManager) Service {
+	return &service{
+		agentManager: agentManager,
+		alertManager: alertManager,
+		users:        make(map[string]ManagedUser),
+		groups:       make(map[string]ManagedGroup),
+		sudoRules:    make(map[string]SudoRule),
+		drift:        make(map[string][]Drift),
+		alertIDs:     make(map[string]string),
+		offboardings: make(map[string]Offboarding),
+	}
+}
+
+func validState(state *string) error {
+	if *state == "" {
+		*state = "present"
+	}
+	if *state != "present" && *state != "absent" {
+		return fmt.Errorf("state must be present or absent")
+	}
+	return nil
+}
+
+// singleLine rejects values that would let one sudoers or authorized_keys
+// entry smuggle in another.
+func singleLine(field, value string) error {
+	if strings.ContainsAny(value, "\n\r\x00") {
+		return fmt.Errorf("%s must be a single line", field)
+	}
+	return nil
+}
+
+func (s *service) DeclareUser(user ManagedUser) (*ManagedUser, error) {
+	if !agent.AccountNamePattern.MatchString(user.Username) {
+		return nil, fmt.Errorf("invalid username %q", user.Username)
+	}
+	if err := validState(&user.State); err != nil {
+		return nil, err
+	}
+	if user.Username == "root" {
+		return nil, fmt.Errorf("root can't be managed")
+	}
+	if user.UID < 0 {
+		return nil, fmt.Errorf("uid must not be negative")
+	}
+	if user.Shell != "" && !filepath.IsAbs(user.Shell) {
+		return nil, fmt.Errorf("shell must be an absolute path")
+	}
+	if strings.ContainsAny(user.Comment, ":\n") {
+		return nil, fmt.Errorf("comment must not contain ':' or newlines")
+	}
+	for _, g := range user.Groups {
+		if !agent.AccountNamePattern.MatchString(g) {
+			return nil, fmt.Errorf("invalid group name %q", g)
+		}
+	}
+	for _, key := range user.AuthorizedKeys {
+		if err := singleLine("authorized key", key); err != nil {
+			return nil, err
+		}
+		if len(strings.Fields(key)) < 2 {
+			return nil, fmt.Errorf("authorized key must be \"<type> <base64> [comment]\"")
+		}
+	}
+	if err := singleLine("sudo", user.Sudo); err != nil {
+		return nil, err
+	}
+	if user.ID == "" {
+		user.ID = fmt.Sprintf("user-%d", time.Now().UnixNano())
+	}
+
+	s.mu.Lock()
+	s.users[user.ID] = user
+	s.mu.Unlock()
+
+	return &user, nil
+}
+
+func (s *service) DeclareGroup(group ManagedGroup) (*ManagedGroup, error) {
+	if !agent.AccountNamePattern.MatchString(group.Name) {
+		return nil, fmt.Errorf("invalid group name %q", group.Name)
+	}
+	if err := validState(&group.State); err != nil {
+		return nil, err
+	}
+	if group.Name == "root" {
+		return nil, fmt.Errorf("root can't be managed")
+	}
+	if group.GID < 0 {
+		return nil, fmt.Errorf("gid must not be negative")
+	}
+	if group.ID == "" {
+		group.ID = fmt.Sprintf("group-%d", time.Now().UnixNano())
+	}
+
+	s.mu.Lock()
+	s.groups[group.ID] = group
+	s.mu.Unlock()
+
+	return &group, nil
+}
+
+func (s *service) DeclareSudoRule(rule SudoRule) (*SudoRule, error) {
+	if !agent.AccountNamePattern.MatchString(strings.TrimPrefix(rule.Principal, "%")) {
+		return nil, fmt.Errorf("principal must be a user or %%group")
+	}
+	if strings.TrimSpace(rule.Spec) == "" {
+		return nil, fmt.Errorf("spec is required")
+	}
+	if err := singleLine("spec", rule.Spec); err != nil {
+		return nil, err
+	}
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("sudo-%d", time.Now().UnixNano())
+	}
+
+	s.mu.Lock()
+	s.sudoRules[rule.ID] = rule
+	s.mu.Unlock()
+
+	return &rule, nil
+}
+
+func (s *service) DeleteUser(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[id]; !exists {
+		return fmt.Errorf("managed user not found")
+	}
+	delete(s.users, id)
+	return nil
+}
+
+func (s *service) DeleteGroup(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.groups[id]; !exists {
+		return fmt.Errorf("managed group not found")
+	}
+	delete(s.groups, id)
+	return nil
+}
+
+func (s *service) DeleteSudoRule(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sudoRules[id]; !exists {
+		return fmt.Errorf("sudo rule not found")
+	}
+	delete(s.sudoRules, id)
+	return nil
+}
+
+func (s *service) ListUsers() []ManagedUser {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]ManagedUser, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (s *service) ListGroups() []ManagedGroup {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make([]ManagedGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups
+}
+
+func (s *service) ListSudoRules() []SudoRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]SudoRule, 0, len(s.sudoRules))
+	for _, r := range s.sudoRules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// declarationsFor returns the declarations applying to an agent, users and
+// groups keyed by name. When several match the same name, the one with the
+// lowest ID wins so the outcome is stable.
+func (s *service) declarationsFor(a *agent.Agent) (map[string]ManagedUser, map[string]ManagedGroup, []SudoRule) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make(map[string]ManagedUser)
+	for _, u := range s.users {
+		if !u.Selector.Matches(a) {
+			continue
+		}
+		if existing, ok := users[u.Username]; !ok || u.ID < existing.ID {
+			users[u.Username] = u
+		}
+	}
+
+	groups := make(map[string]ManagedGroup)
+	for _, g := range s.groups {
+		if !g.Selector.Matches(a) {
+			continue
+		}
+		if existing, ok := groups[g.Name]; !ok || g.ID < existing.ID {
+			groups[g.Name] = g
+		}
+	}
+
+	var rules []SudoRule
+	for _, r := range s.sudoRules {
+		if r.Selector.Matches(a) {
+			rules = append(rules, r)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return users, groups, rules
+}
+
+func (s *service) PlanForAgent(agentID string) (*agent.AccountPlan, error) {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+	users, groups, rules := s.declarationsFor(a)
+	return buildPlan(users, groups, rules), nil
+}
+
+func buildPlan(users map[string]ManagedUser, groups map[string]ManagedGroup, rules []SudoRule) *agent.AccountPlan {
+	plan := &agent.AccountPlan{
+		Groups:    []agent.GroupSpec{},
+		Users:     []agent.UserSpec{},
+		SudoRules: []agent.SudoSpec{},
+	}
+	for _, g := range groups {
+		plan.Groups = append(plan.Groups, agent.GroupSpec{Name: g.Name, GID: g.GID, State: g.State})
+	}
+	for _, u := range users {
+		plan.Users = append(plan.Users, agent.UserSpec{
+			Name:           u.Username,
+			UID:            u.UID,
+			Shell:          u.Shell,
+			Comment:        u.Comment,
+			Groups:         u.Groups,
+			AuthorizedKeys: u.AuthorizedKeys,
+			State:          u.State,
+		})
+		if u.State == "present" && u.Sudo != "" {
+			plan.SudoRules = append(plan.SudoRules, agent.SudoSpec{Name: "user-" + u.Username, Principal: u.Username, Spec: u.Sudo})
+		}
+	}
+	for _, r := range rules {
+		plan.SudoRules = append(plan.SudoRules, agent.SudoSpec{Name: "rule-" + r.ID, Principal: r.Principal, Spec: r.Spec})
+	}
+
+	sort.Slice(plan.Groups, func(i, j int) bool { return plan.Groups[i].Name < plan.Groups[j].Name })
+	sort.Slice(plan.Users, func(i, j int) bool { return plan.Users[i].Name < plan.Users[j].Name })
+	sort.Slice(plan.SudoRules, func(i, j int) bool { return plan.SudoRules[i].Name < plan.SudoRules[j].Name })
+	return plan
+}
+
+func (s *service) Apply(ctx context.Context, sel agent.Selector) []ApplyResult {
+	agents := s.agentManager.SelectAgents(sel)
+	results := make([]ApplyResult, 0, len(agents))
+
+	for _, a := range agents {
+		result := ApplyResult{AgentID: a.ID}
+		users, groups, rules := s.declarationsFor(a)
+		plan, err := json.Marshal(buildPlan(users, groups, rules))
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		out, err := s.agentManager.RunCommandOnAgent(ctx, a.ID, agent.AgentCommand{
+			Command: agent.AccountApplyCommand,
+			Args:    []string{string(plan)},
+			Timeout: 10 * time.Minute,
+		})
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.JobID = queuedJobID(out)
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].AgentID < results[j].AgentID })
+	return results
+}
+
+func (s *service) Scan(ctx context.Context, agentID string) ([]Drift, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	job, err := s.agentManager.RunCommandAndWait(ctx, agentID, agent.AgentCommand{
+		Command: agent.AccountReportCommand,
+		Timeout: 2 * time.Minute,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var report agent.AccountReport
+	if err := json.Unmarshal([]byte(job.Result), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse account report: %w", err)
+	}
+	return s.ReportAccounts(agentID, report)
+}
+
+func (s *service) ReportAccounts(agentID string, report agent.AccountReport) ([]Drift, error) {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+
+	users, groups, rules := s.declarationsFor(a)
+	drift := detectDrift(agentID, users, groups, rules, report, time.Now())
+
+	s.updateAlerts(agentID, drift)
+
+	s.mu.Lock()
+	s.drift[agentID] = drift
+	s.mu.Unlock()
+
+	return drift, nil
+}
+
+func detectDrift(agentID string, users map[string]ManagedUser, groups map[string]ManagedGroup, rules []SudoRule, report agent.AccountReport, now time.Time) []Drift {
+	drift := []Drift{}
+	add := func(kind, resource, declaredBy, field, expected, actual string) {
+		drift = append(drift, Drift{
+			AgentID: agentID, Kind: kind, Resource: resource, DeclaredBy: declaredBy,
+			Field: field, Expected: expected, Actual: actual, DetectedAt: now,
+		})
+	}
+
+	localUsers := make(map[string]agent.LocalUser)
+	for _, u := range report.Users {
+		localUsers[u.Name] = u
+	}
+	localGroups := make(map[string]agent.LocalGroup)
+	for _, g := range report.Groups {
+		localGroups[g.Name] = g
+	}
+
+	for name, desired := range users {
+		actual, exists := localUsers[name]
+		if desired.State == "absent" {
+			if exists {
+				add(DriftUser, name, desired.ID, "exists", "false", "true")
+			}
+			continue
+		}
+		if !exists {
+			add(DriftUser, name, desired.ID, "exists", "true", "false")
+			continue
+		}
+		if desired.UID > 0 && actual.UID != desired.UID {
+			add(DriftUser, name, desired.ID, "uid", strconv.Itoa(desired.UID), strconv.Itoa(actual.UID))
+		}
+		if desired.Shell != "" && actual.Shell != desired.Shell {
+			add(DriftUser, name, desired.ID, "shell", desired.Shell, actual.Shell)
+		}
+		if !sameSet(actual.Groups, desired.Groups) {
+			add(DriftUser, name, desired.ID, "groups", joinSorted(desired.Groups), joinSorted(actual.Groups))
+		}
+		if len(desired.AuthorizedKeys) > 0 && !sameSet(actual.AuthorizedKeys, desired.AuthorizedKeys) {
+			add(DriftUser, name, desired.ID, "authorized_keys",
+				fmt.Sprintf("%d managed keys", len(desired.AuthorizedKeys)),
+				fmt.Sprintf("%d keys, %d unexpected", len(actual.AuthorizedKeys), len(difference(actual.AuthorizedKeys, desired.AuthorizedKeys))))
+		}
+	}
+
+	for name, desired := range groups {
+		actual, exists := localGroups[name]
+		switch {
+		case desired.State == "absent" && exists:
+			add(DriftGroup, name, desired.ID, "exists", "false", "true")
+		case desired.State == "present" && !exists:
+			add(DriftGroup, name, desired.ID, "exists", "true", "false")
+		case desired.State == "present" && desired.GID > 0 && actual.GID != desired.GID:
+			add(DriftGroup, name, desired.ID, "gid", strconv.Itoa(desired.GID), strconv.Itoa(actual.GID))
+		}
+	}
+
+	// Managed sudo rules must be installed as written
+	installed := make(map[string]bool)
+	for _, e := range report.Sudoers {
+		installed[e.File+"|"+e.Principal+"|"+e.Spec] = true
+	}
+	plan := buildPlan(users, groups, rules)
+	for _, r := range plan.SudoRules {
+		file := filepath.Join("/etc/sudoers.d", agent.SudoersFileName(r.Name))
+		if !installed[file+"|"+r.Principal+"|"+strings.Join(strings.Fields(r.Spec), " ")] {
+			add(DriftSudoers, r.Principal, r.Name, "sudo", r.Spec, "missing")
+		}
+	}
+
+	for _, u := range report.Users {
+		// Declarations can't request UID 0, so only root may have it
+		if u.UID != 0 || u.Name == "root" {
+			continue
+		}
+		add(DriftUID0, u.Name, "", "uid", "non-zero", "0")
+	}
+
+	for name, grant := range unknownSudoers(users, rules, report) {
+		add(DriftSudoer, name, "", "sudo", "none", grant)
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		if drift[i].Kind != drift[j].Kind {
+			return drift[i].Kind < drift[j].Kind
+		}
+		if drift[i].Resource != drift[j].Resource {
+			return drift[i].Resource < drift[j].Resource
+		}
+		return drift[i].Field < drift[j].Field
+	})
+	return drift
+}
+
+// unknownSudoers returns the users who can use sudo on the host without a
+// declaration granting it, mapped to one grant that gives it to them.
+// Group grants are expanded to the group's members, including users whose
+// primary group it is.
+func unknownSudoers(users map[string]ManagedUser, rules []SudoRule, report agent.AccountReport) map[string]string {
+	known := map[string]bool{"root": true}
+	sudoGroups := make(map[string]bool)
+	for name, u := range users {
+		if u.State == "present" && u.Sudo != "" {
+			known[name] = true
+		}
+	}
+	for _, r := range rules {
+		if strings.HasPrefix(r.Principal, "%") {
+			sudoGroups[strings.TrimPrefix(r.Principal, "%")] = true
+		} else {
+			known[r.Principal] = true
+		}
+	}
+	// Declared members of declared sudo groups are granted by declaration
+	for name, u := range users {
+		for _, g := range u.Groups {
+			if u.State == "present" && sudoGroups[g] {
+				known[name] = true
+			}
+		}
+	}
+
+	members := make(map[string][]string)
+	gids := make(map[string]int)
+	for _, g := range report.Groups {
+		members[g.Name] = g.Members
+		gids[g.Name] = g.GID
+	}
+
+	unknown := make(map[string]string)
+	flag := func(name string, e agent.SudoersEntry) {
+		if !known[name] {
+			if _, seen := unknown[name]; !seen {
+				unknown[name] = fmt.Sprintf("%s: %s %s", e.File, e.Principal, e.Spec)
+			}
+		}
+	}
+	for _, e := range report.Sudoers {
+		group, isGroup := strings.CutPrefix(e.Principal, "%")
+		if !isGroup {
+			// Includes ALL and User_Alias names, which can't be resolved
+			// here and are worth a look either way
+			flag(e.Principal, e)
+			continue
+		}
+		for _, m := range members[group] {
+			flag(m, e)
+		}
+		if gid, ok := gids[group]; ok {
+			for _, u := range report.Users {
+				if u.GID == gid {
+					flag(u.Name, e)
+				}
+			}
+		}
+	}
+	return unknown
+}
+
+// updateAlerts raises an alert for each newly detected drift and resolves
+// alerts for drift that is gone.
+func (s *service) updateAlerts(agentID string, drift []Drift) {
+	current := make(map[string]Drift)
+	for _, d := range drift {
+		current[driftKey(d)] = d
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.drift[agentID] {
+		key := driftKey(d)
+		if _, still := current[key]; still {
+			continue
+		}
+		if alertID, ok := s.alertIDs[key]; ok {
+			s.alertManager.ResolveAlert(alertID)
+			delete(s.alertIDs, key)
+		}
+	}
+
+	for key, d := range current {
+		if _, alerted := s.alertIDs[key]; alerted {
+			continue
+		}
+		var message string
+		switch d.Kind {
+		case DriftUID0:
+			message = fmt.Sprintf("Unexpected UID 0 account %s", d.Resource)
+		case DriftSudoer:
+			message = fmt.Sprintf("Unknown sudoer %s (%s)", d.Resource, d.Actual)
+		default:
+			message = fmt.Sprintf("%s %s drifted: %s expected %q, found %q", d.Kind, d.Resource, d.Field, d.Expected, d.Actual)
+		}
+		alert := monitoring.Alert{
+			ID:        fmt.Sprintf("accounts-%s-%d", agentID, time.Now().UnixNano()),
+			AgentID:   agentID,
+			Metric:    fmt.Sprintf("accounts:%s:%s", d.Kind, d.Field),
+			Message:   message,
+			Timestamp: d.DetectedAt,
+			Status:    "active",
+		}
+		s.alertIDs[key] = alert.ID
+		s.alertManager.AddAlert(alert)
+	}
+}
+
+func (s *service) GetDrift(agentID string) []Drift {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Drift(nil), s.drift[agentID]...)
+}
+
+func (s *service) ListDrift() map[string][]Drift {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string][]Drift)
+	for agentID, drift := range s.drift {
+		if len(drift) > 0 {
+			result[agentID] = append([]Drift(nil), drift...)
+		}
+	}
+	return result
+}
+
+// Offboard drops the user's declarations first so no later apply brings
+// the account back, then queues account.offboard on every agent, whether
+// or not a declaration covered it: access granted by hand is revoked too.
+func (s *service) Offboard(ctx context.Context, req OffboardRequest) (*Offboarding, error) {
+	if !agent.AccountNamePattern.MatchString(req.Username) || req.Username == "root" {
+		return nil, fmt.Errorf("invalid username %q", req.Username)
+	}
+
+	o := Offboarding{
+		ID:                  fmt.Sprintf("offboard-%d", time.Now().UnixNano()),
+		Username:            req.Username,
+		Delete:              req.Delete,
+		Reason:              req.Reason,
+		By:                  req.By,
+		RemovedDeclarations: []string{},
+		Jobs:                make(map[string]string),
+		Errors:              make(map[string]string),
+		CreatedAt:           time.Now(),
+	}
+
+	s.mu.Lock()
+	for id, u := range s.users {
+		if u.Username == req.Username {
+			delete(s.users, id)
+			o.RemovedDeclarations = append(o.RemovedDeclarations, id)
+		}
+	}
+	for id, r := range s.sudoRules {
+		if r.Principal == req.Username {
+			delete(s.sudoRules, id)
+			o.RemovedDeclarations = append(o.RemovedDeclarations, id)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(o.RemovedDeclarations)
+
+	mode := "lock"
+	if req.Delete {
+		mode = "delete"
+	}
+	for _, a := range s.agentManager.ListAgents() {
+		out, err := s.agentManager.RunCommandOnAgent(ctx, a.ID, agent.AgentCommand{
+			Command: agent.AccountOffboardCommand,
+			Args:    []string{req.Username, mode},
+			Timeout: 5 * time.Minute,
+		})
+		if err != nil {
+			o.Errors[a.ID] = err.Error()
+			continue
+		}
+		o.Jobs[a.ID] = queuedJobID(out)
+	}
+
+	s.mu.Lock()
+	s.offboardings[o.ID] = o
+	s.mu.Unlock()
+
+	return &o, nil
+}
+
+func (s *service) GetOffboarding(id string) (*Offboarding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, exists := s.offboardings[id]
+	if !exists {
+		return nil, fmt.Errorf("offboarding not found")
+	}
+	return &o, nil
+}
+
+func queuedJobID(out []byte) string {
+	var queued struct {
+		JobID string `json:"job_id"`
+	}
+	json.Unmarshal(out, &queued)
+	return queued.JobID
+}
+
+func driftKey(d Drift) string {
+	return d.AgentID + "|" + d.Kind + "|" + d.Resource + "|" + d.Field
+}
+
+func sameSet(a, b []string) bool {
+	return len(difference(a, b)) == 0 && len(difference(b, a)) == 0
+}
+
+// difference returns the elements of a that aren't in b.
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, v := range b {
+		in[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if !in[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func joinSorted(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
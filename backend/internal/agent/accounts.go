@@ -0,0 +1,616 @@
+// backend/internal/agent/accounts.go
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/jobqueue"
+)
+
+// Job commands for local account management.
+const (
+	AccountApplyCommand    = "account.apply"    // Args: JSON AccountPlan
+	AccountReportCommand   = "account.report"   // no args
+	AccountOffboardCommand = "account.offboard" // Args: username, lock|delete
+)
+
+const (
+	sudoersDir = "/etc/sudoers.d"
+	// Every sudoers file we write starts with this prefix; files with it
+	// that aren't in the plan are removed on apply
+	managedSudoersPrefix = "autosysadmin-"
+)
+
+var (
+	// Same rules as useradd's default NAME_REGEX
+	AccountNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+	// sudo skips files in sudoers.d whose names contain a dot or end in ~
+	sudoersFileUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+)
+
+// AccountPlan is the complete set of managed accounts for one host.
+type AccountPlan struct {
+	Groups    []GroupSpec `json:"groups"`
+	Users     []UserSpec  `json:"users"`
+	SudoRules []SudoSpec  `json:"sudo_rules"`
+}
+
+type GroupSpec struct {
+	Name  string `json:"name"`
+	GID   int    `json:"gid,omitempty"` // 0 lets the host pick
+	State string `json:"state"`         // present, absent
+}
+
+type UserSpec struct {
+	Name    string   `json:"name"`
+	UID     int      `json:"uid,omitempty"` // 0 lets the host pick
+	Shell   string   `json:"shell,omitempty"`
+	Comment string   `json:"comment,omitempty"`
+	Groups  []string `json:"groups,omitempty"` // exact supplementary groups
+	// Replaces ~/.ssh/authorized_keys when non-empty
+	AuthorizedKeys []string `json:"authorized_keys,omitempty"`
+	State          string   `json:"state"` // present, absent
+}
+
+// SudoSpec becomes /etc/sudoers.d/autosysadmin-<Name> containing
+// "<Principal> <Spec>".
+type SudoSpec struct {
+	Name      string `json:"name"`
+	Principal string `json:"principal"` // user, or %group
+	Spec      string `json:"spec"`      // e.g. ALL=(ALL) NOPASSWD: ALL
+}
+
+func SudoersFileName(name string) string {
+	return managedSudoersPrefix + sudoersFileUnsafe.ReplaceAllString(name, "_")
+}
+
+// AccountReport is the account state of a host, used for drift detection.
+type AccountReport struct {
+	Users      []LocalUser    `json:"users"`
+	Groups     []LocalGroup   `json:"groups"`
+	Sudoers    []SudoersEntry `json:"sudoers"`
+	ReportedAt time.Time      `json:"reported_at"`
+}
+
+type LocalUser struct {
+	Name           string   `json:"name"`
+	UID            int      `json:"uid"`
+	GID            int      `json:"gid"`
+	Home           string   `json:"home"`
+	Shell          string   `json:"shell"`
+	Groups         []string `json:"groups"` // supplementary
+	AuthorizedKeys []string `json:"authorized_keys,omitempty"`
+	Locked         bool     `json:"locked"`
+}
+
+type LocalGroup struct {
+	Name    string   `json:"name"`
+	GID     int      `json:"gid"`
+	Members []string `json:"members"`
+}
+
+type SudoersEntry struct {
+	File      string `json:"file"`
+	Principal string `json:"principal"`
+	Spec      string `json:"spec"`
+}
+
+// AccountChange describes one thing apply or offboard did.
+type AccountChange struct {
+	Kind     string `json:"kind"` // group, user, keys, sudo
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+}
+
+func HandleAccountJob(job jobqueue.Job) (string, error) {
+	var result interface{}
+
+	switch job.Command {
+	case AccountReportCommand:
+		report, err := CollectAccounts()
+		if err != nil {
+			return "", err
+		}
+		result = report
+
+	case AccountApplyCommand:
+		if len(job.Args) != 1 {
+			return "", fmt.Errorf("%s expects 1 argument", job.Command)
+		}
+		var plan AccountPlan
+		if err := json.Unmarshal([]byte(job.Args[0]), &plan); err != nil {
+			return "", fmt.Errorf("invalid account plan: %w", err)
+		}
+		changes, err := ApplyAccounts(plan)
+		if err != nil {
+			return "", err
+		}
+		result = changes
+
+	case AccountOffboardCommand:
+		if len(job.Args) != 2 {
+			return "", fmt.Errorf("%s expects 2 arguments", job.Command)
+		}
+		changes, err := OffboardAccount(job.Args[0], job.Args[1] == "delete")
+		if err != nil {
+			return "", err
+		}
+		result = changes
+
+	default:
+		return "", fmt.Errorf("unknown account command %q", job.Command)
+	}
+
+	data, err := json.Marshal(result)
+	return string(data), err
+}
+
+// ApplyAccounts brings the host in line with the plan. Failures on one
+// account don't stop the rest; they are recorded in the returned changes.
+func ApplyAccounts(plan AccountPlan) ([]AccountChange, error) {
+	current, err := CollectAccounts()
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[string]LocalUser)
+	for _, u := range current.Users {
+		users[u.Name] = u
+	}
+	groups := make(map[string]LocalGroup)
+	for _, g := range current.Groups {
+		groups[g.Name] = g
+	}
+
+	changes := []AccountChange{}
+	record := func(kind, resource, action string, err error) {
+		c := AccountChange{Kind: kind, Resource: resource, Action: action}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		changes = append(changes, c)
+	}
+
+	// Groups first so users can join them; removals last so nothing still
+	// references what's removed
+	for _, g := range plan.Groups {
+		if _, exists := groups[g.Name]; g.State != "absent" && !exists {
+			args := []string{}
+			if g.GID > 0 {
+				args = append(args, "-g", strconv.Itoa(g.GID))
+			}
+			record("group", g.Name, "create", runAccountCommand("groupadd", append(args, g.Name)...))
+		}
+	}
+
+	for _, u := range plan.Users {
+		if u.State == "absent" {
+			continue
+		}
+		existing, exists := users[u.Name]
+		if !exists {
+			args := []string{"-m"}
+			args = append(args, userFlags(u)...)
+			if u.UID > 0 {
+				args = append(args, "-u", strconv.Itoa(u.UID))
+			}
+			err := runAccountCommand("useradd", append(args, u.Name)...)
+			record("user", u.Name, "create", err)
+			if err != nil {
+				continue
+			}
+			existing, _ = lookupLocalUser(u.Name)
+		} else if userNeedsUpdate(existing, u) {
+			args := userFlags(u)
+			if u.Groups == nil {
+				args = append(args, "-G", "")
+			}
+			record("user", u.Name, "update", runAccountCommand("usermod", append(args, u.Name)...))
+		}
+
+		if len(u.AuthorizedKeys) > 0 && !sameStrings(existing.AuthorizedKeys, u.AuthorizedKeys) {
+			record("keys", u.Name, "write", writeAuthorizedKeys(existing, u.AuthorizedKeys))
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, rule := range plan.SudoRules {
+		file := SudoersFileName(rule.Name)
+		wanted[file] = true
+		content := fmt.Sprintf("# Managed by autosysadmin; local changes are overwritten\n%s %s\n", rule.Principal, rule.Spec)
+		path := filepath.Join(sudoersDir, file)
+		if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+			continue
+		}
+		record("sudo", rule.Principal, "write", writeSudoersFile(path, content))
+	}
+	if entries, err := os.ReadDir(sudoersDir); err == nil {
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), managedSudoersPrefix) && !wanted[e.Name()] {
+				record("sudo", e.Name(), "remove", os.Remove(filepath.Join(sudoersDir, e.Name())))
+			}
+		}
+	}
+
+	for _, u := range plan.Users {
+		if _, exists := users[u.Name]; u.State == "absent" && exists {
+			record("user", u.Name, "delete", removeUser(u.Name, true))
+		}
+	}
+	for _, g := range plan.Groups {
+		if _, exists := groups[g.Name]; g.State == "absent" && exists {
+			record("group", g.Name, "delete", runAccountCommand("groupdel", g.Name))
+		}
+	}
+
+	return changes, nil
+}
+
+func userFlags(u UserSpec) []string {
+	var args []string
+	if u.Shell != "" {
+		args = append(args, "-s", u.Shell)
+	}
+	if u.Comment != "" {
+		args = append(args, "-c", u.Comment)
+	}
+	if len(u.Groups) > 0 {
+		args = append(args, "-G", strings.Join(u.Groups, ","))
+	}
+	return args
+}
+
+func userNeedsUpdate(existing LocalUser, u UserSpec) bool {
+	if u.Shell != "" && existing.Shell != u.Shell {
+		return true
+	}
+	return !sameStrings(existing.Groups, u.Groups)
+}
+
+// OffboardAccount locks the user out: the password and account are
+// expired, their processes are killed, their SSH keys and any managed sudo
+// rule are removed, and with delete the account and home directory go too.
+func OffboardAccount(name string, remove bool) ([]AccountChange, error) {
+	if !AccountNamePattern.MatchString(name) || name == "root" {
+		return nil, fmt.Errorf("invalid username %q", name)
+	}
+	u, exists := lookupLocalUser(name)
+	if !exists {
+		return []AccountChange{{Kind: "user", Resource: name, Action: "absent"}}, nil
+	}
+	if u.UID == 0 {
+		return nil, fmt.Errorf("refusing to offboard a UID 0 account")
+	}
+
+	changes := []AccountChange{}
+	record := func(kind, action string, err error) {
+		c := AccountChange{Kind: kind, Resource: name, Action: action}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		changes = append(changes, c)
+	}
+
+	record("user", "lock", runAccountCommand("usermod", "-L", "-e", "1", name))
+	// pkill exits 1 when nothing matched
+	if err := exec.Command("pkill", "-KILL", "-u", name).Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			record("user", "kill-processes", err)
+		}
+	} else {
+		record("user", "kill-processes", nil)
+	}
+	if len(u.AuthorizedKeys) > 0 {
+		// Don't follow a ~/.ssh symlink out of the user's home
+		dir := filepath.Join(u.Home, ".ssh")
+		if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+			record("keys", "remove", fmt.Errorf("refusing to remove keys: %s is not a directory", dir))
+		} else {
+			record("keys", "remove", os.Remove(filepath.Join(dir, "authorized_keys")))
+		}
+	}
+	if remove {
+		record("user", "delete", removeUser(name, true))
+	}
+
+	// Managed files hold a single rule, so any naming the user (its
+	// user-<name> grant or a rule-<id> sudo rule) is removed whole. Grants
+	// in files we don't manage can't be edited safely; surface them so
+	// someone removes them by hand
+	if report, err := CollectAccounts(); err == nil {
+		removed := make(map[string]bool)
+		for _, e := range report.Sudoers {
+			if e.Principal != name {
+				continue
+			}
+			if strings.HasPrefix(filepath.Base(e.File), managedSudoersPrefix) {
+				if !removed[e.File] {
+					removed[e.File] = true
+					record("sudo", "remove", os.Remove(e.File))
+				}
+				continue
+			}
+			changes = append(changes, AccountChange{
+				Kind: "sudo", Resource: name, Action: "manual-removal-required",
+				Error: fmt.Sprintf("unmanaged sudo rule in %s: %s", e.File, e.Spec),
+			})
+		}
+	}
+	return changes, nil
+}
+
+func removeUser(name string, removeHome bool) error {
+	args := []string{name}
+	if removeHome {
+		args = []string{"-r", name}
+	}
+	return runAccountCommand("userdel", args...)
+}
+
+func runAccountCommand(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// writeAuthorizedKeys runs as root inside a directory the user controls,
+// so it refuses a ~/.ssh that is a symlink or belongs to someone else and
+// never follows a path the user could have planted: the keys go to a fresh
+// temp file that is chowned through its descriptor and renamed into place.
+func writeAuthorizedKeys(u LocalUser, keys []string) error {
+	if u.Home == "" {
+		return fmt.Errorf("user %s has no home directory", u.Name)
+	}
+	dir := filepath.Join(u.Home, ".ssh")
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := os.Lchown(dir, u.UID, u.GID); err != nil {
+			return fmt.Errorf("failed to chown %s: %w", dir, err)
+		}
+		info, err = os.Lstat(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", dir, err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		return fmt.Errorf("refusing to write keys: %s is not a directory", dir)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != u.UID {
+		return fmt.Errorf("refusing to write keys: %s is not owned by %s", dir, u.Name)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".authorized_keys-autosysadmin-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strings.Join(keys, "\n") + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write keys: %w", err)
+	}
+	if err := tmp.Chown(u.UID, u.GID); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to chown %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write keys: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, "authorized_keys"))
+}
+
+// writeSudoersFile validates the rule with visudo before installing it, since
+// a broken sudoers file locks everyone out of sudo.
+func writeSudoersFile(path, content string) error {
+	tmp, err := os.CreateTemp(sudoersDir, ".autosysadmin-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write sudoers file: %w", err)
+	}
+	tmp.Close()
+	if err := os.Chmod(tmp.Name(), 0440); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("visudo"); err == nil {
+		if err := runAccountCommand("visudo", "-c", "-q", "-f", tmp.Name()); err != nil {
+			return fmt.Errorf("sudo rule rejected: %w", err)
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// CollectAccounts reads users, groups and sudo rules from the local files.
+func CollectAccounts() (*AccountReport, error) {
+	report := &AccountReport{
+		Users:      []LocalUser{},
+		Groups:     []LocalGroup{},
+		Sudoers:    []SudoersEntry{},
+		ReportedAt: time.Now(),
+	}
+
+	groupLines, err := readColonFile("/etc/group", 4)
+	if err != nil {
+		return nil, err
+	}
+	memberOf := make(map[string][]string)
+	for _, f := range groupLines {
+		gid, _ := strconv.Atoi(f[2])
+		g := LocalGroup{Name: f[0], GID: gid, Members: []string{}}
+		if f[3] != "" {
+			g.Members = strings.Split(f[3], ",")
+		}
+		for _, m := range g.Members {
+			memberOf[m] = append(memberOf[m], g.Name)
+		}
+		report.Groups = append(report.Groups, g)
+	}
+
+	locked := make(map[string]bool)
+	if shadow, err := readColonFile("/etc/shadow", 2); err == nil {
+		for _, f := range shadow {
+			locked[f[0]] = strings.HasPrefix(f[1], "!")
+		}
+	}
+
+	passwdLines, err := readColonFile("/etc/passwd", 7)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range passwdLines {
+		uid, _ := strconv.Atoi(f[2])
+		gid, _ := strconv.Atoi(f[3])
+		u := LocalUser{
+			Name:   f[0],
+			UID:    uid,
+			GID:    gid,
+			Home:   f[5],
+			Shell:  f[6],
+			Groups: memberOf[f[0]],
+			Locked: locked[f[0]],
+		}
+		if u.Groups == nil {
+			u.Groups = []string{}
+		}
+		sort.Strings(u.Groups)
+		u.AuthorizedKeys = readAuthorizedKeys(u.Home)
+		report.Users = append(report.Users, u)
+	}
+
+	report.Sudoers = append(report.Sudoers, readSudoers("/etc/sudoers")...)
+	if entries, err := os.ReadDir(sudoersDir); err == nil {
+		for _, e := range entries {
+			if e.IsDir() || strings.Contains(e.Name(), ".") || strings.HasSuffix(e.Name(), "~") {
+				continue
+			}
+			report.Sudoers = append(report.Sudoers, readSudoers(filepath.Join(sudoersDir, e.Name()))...)
+		}
+	}
+	return report, nil
+}
+
+func lookupLocalUser(name string) (LocalUser, bool) {
+	report, err := CollectAccounts()
+	if err != nil {
+		return LocalUser{}, false
+	}
+	for _, u := range report.Users {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return LocalUser{}, false
+}
+
+func readColonFile(path string, minFields int) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var lines [][]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) >= minFields {
+			lines = append(lines, fields)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func readAuthorizedKeys(home string) []string {
+	if home == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".ssh", "authorized_keys"))
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
+	}
+	return keys
+}
+
+// readSudoers extracts the user specifications from a sudoers file: every
+// line that isn't a comment, include, Defaults or alias definition.
+func readSudoers(path string) []SudoersEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entries []SudoersEntry
+	// Join backslash continuations first
+	text := strings.ReplaceAll(string(data), "\\\n", " ")
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(fields[0], "Defaults"),
+			fields[0] == "User_Alias", fields[0] == "Runas_Alias",
+			fields[0] == "Host_Alias", fields[0] == "Cmnd_Alias", fields[0] == "Cmd_Alias":
+			continue
+		}
+		for _, principal := range strings.Split(fields[0], ",") {
+			entries = append(entries, SudoersEntry{
+				File:      path,
+				Principal: principal,
+				Spec:      strings.Join(fields[1:], " "),
+			})
+		}
+	}
+	return entries
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]string(nil), a...)
+	y := append([]string(nil), b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -27,27 +27,42 @@ type AgentCommand struct {
 	Command string        `json:"command"`
 	Args    []string      `json:"args"`
 	Timeout time.Duration `json:"timeout"`
+	// Set for script.run jobs; see jobqueue.Job
+	Script    string `json:"script,omitempty"`
+	ScriptRef string `json:"script_ref,omitempty"`
 }
 
 func (a *Agent) ExecuteCommand(ctx context.Context, cmd AgentCommand, queue jobqueue.JobQueue) ([]byte, error) {
+	jobID, err := a.enqueue(ctx, cmd, queue)
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers that need the job outcome use Manager.RunCommandAndWait
+	return json.Marshal(map[string]interface{}{
+		"job_id":    jobID,
+		"status":    "queued",
+		"timestamp": time.Now(),
+	})
+}
+
+func (a *Agent) enqueue(ctx context.Context, cmd AgentCommand, queue jobqueue.JobQueue) (string, error) {
 	job := jobqueue.Job{
-		ID:        fmt.Sprintf("%s-%d", a.ID, time.Now().Unix()),
+		// Nanosecond resolution so that several jobs queued for the same agent
+		// within one second (e.g. file chunks) don't overwrite each other
+		ID:        fmt.Sprintf("%s-%d", a.ID, time.Now().UnixNano()),
 		AgentID:   a.ID,
 		Command:   cmd.Command,
 		Args:      cmd.Args,
 		Timeout:   cmd.Timeout,
 		CreatedAt: time.Now(),
+		Script:    cmd.Script,
+		ScriptRef: cmd.ScriptRef,
 	}
 
 	if err := queue.Enqueue(ctx, job); err != nil {
-		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+		return "", fmt.Errorf("failed to enqueue job: %w", err)
 	}
 
-	// In a real implementation, we'd wait for the response
-	// This is simplified for demonstration
-	return json.Marshal(map[string]interface{}{
-		"job_id":    job.ID,
-		"status":    "queued",
-		"timestamp": time.Now(),
-	})
-}
\ No newline at end of file
+	return job.ID, nil
+}
@@ -0,0 +1,409 @@
+// backend/internal/agent/docker.go
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/jobqueue"
+)
+
+// Job commands for Docker hosts. The agent talks to the local Engine API
+// over its unix socket.
+const (
+	DockerInventoryCommand = "docker.inventory"
+	DockerContainerCommand = "docker.container" // Args: action, container
+	DockerLogsCommand      = "docker.logs"      // Args: container, tail
+)
+
+const (
+	DockerSocket     = "/var/run/docker.sock"
+	dockerAPIVersion = "v1.41"
+	maxDockerLogs    = 10000
+)
+
+// DockerContainerActions are the actions accepted by DockerContainerCommand.
+var DockerContainerActions = map[string]bool{
+	"start":   true,
+	"stop":    true,
+	"restart": true,
+}
+
+// Container names and IDs; the leading character keeps them from being
+// read as a path segment like ".."
+var containerRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+func ValidContainerRef(ref string) bool {
+	return containerRefPattern.MatchString(ref)
+}
+
+type DockerInventory struct {
+	Containers  []ContainerInfo `json:"containers"`
+	Images      []ImageInfo     `json:"images"`
+	CollectedAt time.Time       `json:"collected_at"`
+}
+
+type ContainerInfo struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Image   string            `json:"image"`
+	State   string            `json:"state"`  // running, exited, paused, ...
+	Status  string            `json:"status"` // e.g. "Up 3 hours"
+	Created time.Time         `json:"created"`
+	Ports   []string          `json:"ports,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Stats   *ContainerStats   `json:"stats,omitempty"` // running containers only
+}
+
+type ContainerStats struct {
+	CPUUsage      float64 `json:"cpu_usage"` // percent of one CPU
+	MemoryUsage   int64   `json:"memory_usage"`
+	MemoryLimit   int64   `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+	NetworkIn     int64   `json:"network_in"`  // bytes received
+	NetworkOut    int64   `json:"network_out"` // bytes sent
+}
+
+type ImageInfo struct {
+	ID      string    `json:"id"`
+	Tags    []string  `json:"tags"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+type ContainerLogLine struct {
+	Stream  string `json:"stream"` // stdout, stderr
+	Message string `json:"message"`
+}
+
+// DockerClient is a minimal Docker Engine API client.
+type DockerClient struct {
+	http *http.Client
+}
+
+func NewDockerClient(socket string) *DockerClient {
+	return &DockerClient{
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+func (c *DockerClient) do(ctx context.Context, method, path string, query url.Values) ([]byte, error) {
+	u := "http://docker/" + dockerAPIVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker API response: %w", err)
+	}
+	// 304 is "already started/stopped", which is what the caller asked for
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotModified {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return nil, fmt.Errorf("docker API %s %s: %s", method, path, apiErr.Message)
+	}
+	return body, nil
+}
+
+func (c *DockerClient) Inventory(ctx context.Context) (*DockerInventory, error) {
+	body, err := c.do(ctx, http.MethodGet, "/containers/json", url.Values{"all": {"1"}})
+	if err != nil {
+		return nil, err
+	}
+	var raw []struct {
+		ID      string            `json:"Id"`
+		Names   []string          `json:"Names"`
+		Image   string            `json:"Image"`
+		State   string            `json:"State"`
+		Status  string            `json:"Status"`
+		Created int64             `json:"Created"`
+		Labels  map[string]string `json:"Labels"`
+		Ports   []struct {
+			IP          string `json:"IP"`
+			PrivatePort int    `json:"PrivatePort"`
+			PublicPort  int    `json:"PublicPort"`
+			Type        string `json:"Type"`
+		} `json:"Ports"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse container list: %w", err)
+	}
+
+	inv := &DockerInventory{
+		Containers:  make([]ContainerInfo, len(raw)),
+		Images:      []ImageInfo{},
+		CollectedAt: time.Now(),
+	}
+	for i, r := range raw {
+		info := ContainerInfo{
+			ID:      r.ID,
+			Image:   r.Image,
+			State:   r.State,
+			Status:  r.Status,
+			Created: time.Unix(r.Created, 0),
+			Labels:  r.Labels,
+		}
+		if len(r.Names) > 0 {
+			info.Name = strings.TrimPrefix(r.Names[0], "/")
+		}
+		for _, p := range r.Ports {
+			if p.PublicPort != 0 {
+				info.Ports = append(info.Ports, fmt.Sprintf("%s:%d->%d/%s", p.IP, p.PublicPort, p.PrivatePort, p.Type))
+			} else {
+				info.Ports = append(info.Ports, fmt.Sprintf("%d/%s", p.PrivatePort, p.Type))
+			}
+		}
+		inv.Containers[i] = info
+	}
+
+	// A one-shot stats read takes about a second per container, so fetch
+	// them concurrently
+	var wg sync.WaitGroup
+	for i := range inv.Containers {
+		if inv.Containers[i].State != "running" {
+			continue
+		}
+		wg.Add(1)
+		go func(info *ContainerInfo) {
+			defer wg.Done()
+			if stats, err := c.Stats(ctx, info.ID); err == nil {
+				info.Stats = stats
+			}
+		}(&inv.Containers[i])
+	}
+
+	images, err := c.Images(ctx)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	inv.Images = images
+	return inv, nil
+}
+
+func (c *DockerClient) Images(ctx context.Context) ([]ImageInfo, error) {
+	body, err := c.do(ctx, http.MethodGet, "/images/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	var raw []struct {
+		ID       string   `json:"Id"`
+		RepoTags []string `json:"RepoTags"`
+		Size     int64    `json:"Size"`
+		Created  int64    `json:"Created"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse image list: %w", err)
+	}
+
+	images := make([]ImageInfo, len(raw))
+	for i, r := range raw {
+		images[i] = ImageInfo{ID: r.ID, Tags: r.RepoTags, Size: r.Size, Created: time.Unix(r.Created, 0)}
+	}
+	return images, nil
+}
+
+func (c *DockerClient) Stats(ctx context.Context, id string) (*ContainerStats, error) {
+	body, err := c.do(ctx, http.MethodGet, "/containers/"+id+"/stats", url.Values{"stream": {"false"}})
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		CPUStats    dockerCPUStats `json:"cpu_stats"`
+		PreCPUStats dockerCPUStats `json:"precpu_stats"`
+		MemoryStats struct {
+			Usage int64            `json:"usage"`
+			Limit int64            `json:"limit"`
+			Stats map[string]int64 `json:"stats"`
+		} `json:"memory_stats"`
+		Networks map[string]struct {
+			RxBytes int64 `json:"rx_bytes"`
+			TxBytes int64 `json:"tx_bytes"`
+		} `json:"networks"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse container stats: %w", err)
+	}
+
+	stats := &ContainerStats{MemoryLimit: raw.MemoryStats.Limit}
+
+	// Same calculation as `docker stats`
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	cpus := float64(raw.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUUsage = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// Page cache is reclaimable; cgroup v2 reports it as inactive_file and
+	// v1 as total_inactive_file
+	stats.MemoryUsage = raw.MemoryStats.Usage
+	if cache, ok := raw.MemoryStats.Stats["inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	} else if cache, ok := raw.MemoryStats.Stats["total_inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, n := range raw.Networks {
+		stats.NetworkIn += n.RxBytes
+		stats.NetworkOut += n.TxBytes
+	}
+	return stats, nil
+}
+
+type dockerCPUStats struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  int    `json:"online_cpus"`
+}
+
+func (c *DockerClient) Control(ctx context.Context, id, action string) error {
+	if !DockerContainerActions[action] {
+		return fmt.Errorf("unsupported container action %q", action)
+	}
+	_, err := c.do(ctx, http.MethodPost, "/containers/"+id+"/"+action, nil)
+	return err
+}
+
+func (c *DockerClient) Logs(ctx context.Context, id string, tail int) ([]ContainerLogLine, error) {
+	body, err := c.do(ctx, http.MethodGet, "/containers/"+id+"/logs", url.Values{
+		"stdout":     {"1"},
+		"stderr":     {"1"},
+		"timestamps": {"1"},
+		"tail":       {strconv.Itoa(tail)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return demuxLogs(body), nil
+}
+
+// demuxLogs splits the Engine API log stream into lines. Containers without
+// a TTY multiplex stdout and stderr in frames with an 8-byte header (stream
+// type, 3 zero bytes, big-endian length); TTY containers send raw output.
+func demuxLogs(data []byte) []ContainerLogLine {
+	var lines []ContainerLogLine
+	appendLines := func(stream string, chunk []byte) {
+		for _, l := range strings.Split(strings.TrimRight(string(chunk), "\n"), "\n") {
+			if l != "" {
+				lines = append(lines, ContainerLogLine{Stream: stream, Message: l})
+			}
+		}
+	}
+
+	multiplexed := len(data) >= 8 && data[0] <= 2 && bytes.Equal(data[1:4], []byte{0, 0, 0})
+	if !multiplexed {
+		appendLines("stdout", data)
+		return lines
+	}
+
+	for len(data) >= 8 {
+		stream := "stdout"
+		if data[0] == 2 {
+			stream = "stderr"
+		}
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			size = len(data)
+		}
+		appendLines(stream, data[:size])
+		data = data[size:]
+	}
+	return lines
+}
+
+// HandleDockerJob runs a Docker job on the host and returns the string the
+// agent reports back through CompleteJob.
+func HandleDockerJob(ctx context.Context, client *DockerClient, job jobqueue.Job) (string, error) {
+	var result interface{}
+
+	switch job.Command {
+	case DockerInventoryCommand:
+		inv, err := client.Inventory(ctx)
+		if err != nil {
+			return "", err
+		}
+		result = inv
+
+	case DockerContainerCommand:
+		if len(job.Args) != 2 {
+			return "", fmt.Errorf("%s expects action and container", job.Command)
+		}
+		action, id := job.Args[0], job.Args[1]
+		if !ValidContainerRef(id) {
+			return "", fmt.Errorf("invalid container %q", id)
+		}
+		if err := client.Control(ctx, id, action); err != nil {
+			return "", err
+		}
+		result = map[string]string{"container": id, "action": action}
+
+	case DockerLogsCommand:
+		if len(job.Args) != 2 {
+			return "", fmt.Errorf("%s expects container and tail", job.Command)
+		}
+		id := job.Args[0]
+		if !ValidContainerRef(id) {
+			return "", fmt.Errorf("invalid container %q", id)
+		}
+		tail, err := strconv.Atoi(job.Args[1])
+		if err != nil || tail <= 0 || tail > maxDockerLogs {
+			return "", fmt.Errorf("tail must be between 1 and %d", maxDockerLogs)
+		}
+		lines, err := client.Logs(ctx, id, tail)
+		if err != nil {
+			return "", err
+		}
+		result = lines
+
+	default:
+		return "", fmt.Errorf("unknown docker command %q", job.Command)
+	}
+
+	data, err := json.Marshal(result)
+	return string(data), err
+}
@@ -0,0 +1,236 @@
+// backend/internal/agent/files.go
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/autosysadmin/backend/internal/jobqueue"
+)
+
+// Job commands used to move files between the backend and an agent.
+const (
+	FileStatCommand       = "file.stat"
+	FileReadChunkCommand  = "file.read-chunk"
+	FileWriteChunkCommand = "file.write-chunk"
+	FileCommitCommand     = "file.commit"
+)
+
+// FileInfo is the result of a file.stat job.
+type FileInfo struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	Size   int64  `json:"size"`
+	Mode   string `json:"mode"`
+	SHA256 string `json:"sha256"`
+}
+
+// HandleFileJob runs a file transfer job on the host and returns the string
+// the agent reports back through CompleteJob.
+func HandleFileJob(job jobqueue.Job) (string, error) {
+	switch job.Command {
+	case FileStatCommand:
+		if len(job.Args) != 1 {
+			return "", fmt.Errorf("%s expects 1 argument", job.Command)
+		}
+		info, err := StatFile(job.Args[0])
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(info)
+		return string(data), err
+
+	case FileReadChunkCommand:
+		if len(job.Args) != 3 {
+			return "", fmt.Errorf("%s expects 3 arguments", job.Command)
+		}
+		offset, err := strconv.ParseInt(job.Args[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid offset: %w", err)
+		}
+		length, err := strconv.ParseInt(job.Args[2], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid length: %w", err)
+		}
+		chunk, err := ReadChunk(job.Args[0], offset, length)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(chunk), nil
+
+	case FileWriteChunkCommand:
+		if len(job.Args) != 3 {
+			return "", fmt.Errorf("%s expects 3 arguments", job.Command)
+		}
+		offset, err := strconv.ParseInt(job.Args[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid offset: %w", err)
+		}
+		chunk, err := base64.StdEncoding.DecodeString(job.Args[2])
+		if err != nil {
+			return "", fmt.Errorf("invalid chunk encoding: %w", err)
+		}
+		if err := WriteChunk(job.Args[0], offset, chunk); err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(offset+int64(len(chunk)), 10), nil
+
+	case FileCommitCommand:
+		if len(job.Args) != 6 {
+			return "", fmt.Errorf("%s expects 6 arguments", job.Command)
+		}
+		mode, err := strconv.ParseUint(job.Args[3], 8, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid mode: %w", err)
+		}
+		if err := CommitFile(job.Args[0], job.Args[1], job.Args[2], os.FileMode(mode), job.Args[4], job.Args[5]); err != nil {
+			return "", err
+		}
+		return "committed", nil
+	}
+
+	return "", fmt.Errorf("unknown file command %q", job.Command)
+}
+
+// StatFile reports size, mode and content hash of a file. A missing file is
+// not an error so that resumable uploads can probe their partial file.
+func StatFile(path string) (*FileInfo, error) {
+	info := &FileInfo{Path: path}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return info, nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if st.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	info.Exists = true
+	info.Size = st.Size()
+	info.Mode = fmt.Sprintf("%04o", st.Mode().Perm())
+	info.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return info, nil
+}
+
+// ReadChunk reads up to length bytes starting at offset.
+func ReadChunk(path string, offset, length int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, length)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return buf[:n], nil
+}
+
+// WriteChunk writes data at offset into a partial file, truncating anything
+// past the end of the chunk so a retried chunk never leaves stale bytes.
+func WriteChunk(path string, offset int64, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteAt(data, offset); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Truncate(offset + int64(len(data))); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", path, err)
+	}
+	return f.Sync()
+}
+
+// CommitFile verifies the partial file against the expected hash, applies
+// mode and ownership, and atomically renames it over the destination.
+func CommitFile(partPath, destPath, expectedSHA256 string, mode os.FileMode, owner, group string) error {
+	info, err := StatFile(partPath)
+	if err != nil {
+		return err
+	}
+	if !info.Exists {
+		return fmt.Errorf("partial file %s not found", partPath)
+	}
+	if info.SHA256 != expectedSHA256 {
+		os.Remove(partPath)
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedSHA256, info.SHA256)
+	}
+
+	if err := os.Chmod(partPath, mode); err != nil {
+		return fmt.Errorf("failed to set mode: %w", err)
+	}
+
+	if owner != "" || group != "" {
+		uid, gid, err := lookupOwner(owner, group)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(partPath, uid, gid); err != nil {
+			return fmt.Errorf("failed to set owner: %w", err)
+		}
+	}
+
+	// partPath lives next to destPath, so the rename stays on one filesystem
+	// and readers see either the old or the new file, never a mix
+	if err := os.Rename(partPath, destPath); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", destPath, err)
+	}
+	return nil
+}
+
+func lookupOwner(owner, group string) (int, int, error) {
+	uid, gid := -1, -1
+
+	if owner != "" {
+		u, err := user.Lookup(owner)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unknown user %s: %w", owner, err)
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return 0, 0, fmt.Errorf("invalid uid for %s: %w", owner, err)
+		}
+	}
+
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unknown group %s: %w", group, err)
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return 0, 0, fmt.Errorf("invalid gid for %s: %w", group, err)
+		}
+	}
+
+	return uid, gid, nil
+}
@@ -3,38 +3,68 @@ package agent
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/autosysadmin/backend/internal/jobqueue"
 )
 
+// DefaultStatsMaxAge is how long reported stats are served before the agent
+// is considered silent; a few report intervals.
+const DefaultStatsMaxAge = 2 * time.Minute
+
+// DefaultHeartbeatTimeout is how long after its last heartbeat an agent is
+// considered down.
+const DefaultHeartbeatTimeout = 90 * time.Second
+
 type Manager struct {
-	agents  map[string]*Agent
-	mu      sync.RWMutex
-	queue   jobqueue.JobQueue
-	timeout time.Duration
+	agents      map[string]*Agent
+	stats       map[string]*AgentStats // agentID -> last reported stats
+	reportedAt  map[string]time.Time   // agentID -> when the stats arrived
+	statsMaxAge time.Duration
+	hbTimeout   time.Duration
+	mu          sync.RWMutex
+	queue       jobqueue.JobQueue
+	timeout     time.Duration
 }
 
 func NewManager(queue jobqueue.JobQueue) *Manager {
 	return &Manager{
-		agents:  make(map[string]*Agent),
-		queue:   queue,
-		timeout: 30 * time.Second,
+		agents:      make(map[string]*Agent),
+		stats:       make(map[string]*AgentStats),
+		reportedAt:  make(map[string]time.Time),
+		statsMaxAge: DefaultStatsMaxAge,
+		hbTimeout:   DefaultHeartbeatTimeout,
+		queue:       queue,
+		timeout:     30 * time.Second,
 	}
 }
 
+// SetStatsMaxAge sets how old reported stats may get before GetStats
+// reports the agent as silent.
+func (m *Manager) SetStatsMaxAge(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statsMaxAge = d
+}
+
 func (m *Manager) RegisterAgent(agent *Agent) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.agents[agent.ID] = agent
 }
 
+// GetAgent returns a copy of the agent's record; use UpdateAgent to change
+// it.
 func (m *Manager) GetAgent(id string) (*Agent, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	agent, exists := m.agents[id]
-	return agent, exists
+	if !exists {
+		return nil, false
+	}
+	return agent.clone(), true
 }
 
 func (m *Manager) ListAgents() []*Agent {
@@ -42,7 +72,126 @@ func (m *Manager) ListAgents() []*Agent {
 	defer m.mu.RUnlock()
 	agents := make([]*Agent, 0, len(m.agents))
 	for _, agent := range m.agents {
-		agents = append(agents, agent)
+		agents = append(agents, agent.clone())
+	}
+	return agents
+}
+
+// clone copies the record so callers can read it while Heartbeat and
+// UpdateAgent change the original under the lock.
+func (a *Agent) clone() *Agent {
+	c := *a
+	c.Tags = append([]string(nil), a.Tags...)
+	return &c
+}
+
+// Heartbeat records that the agent is alive and which version it runs.
+func (m *Manager) Heartbeat(agentID, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent, exists := m.agents[agentID]
+	if !exists {
+		return fmt.Errorf("agent not found")
+	}
+
+	agent.LastHeartbeat = time.Now()
+	agent.Status = "online"
+	if version != "" {
+		agent.Version = version
+	}
+	return nil
+}
+
+// SetHeartbeatTimeout sets how long an agent may go without a heartbeat
+// before Alive reports it down.
+func (m *Manager) SetHeartbeatTimeout(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hbTimeout = d
+}
+
+// Alive reports whether the agent has been heard from recently: a heartbeat
+// or a stats report both count. Agents that have sent neither are judged by
+// GetStats alone, so they are reported alive here.
+func (m *Manager) Alive(agentID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	agent, exists := m.agents[agentID]
+	if !exists {
+		return false
+	}
+	lastSeen := agent.LastHeartbeat
+	if reported := m.reportedAt[agentID]; reported.After(lastSeen) {
+		lastSeen = reported
+	}
+	return lastSeen.IsZero() || time.Since(lastSeen) <= m.hbTimeout
+}
+
+// UpdateAgent applies update to the agent's record under the manager's lock.
+func (m *Manager) UpdateAgent(agentID string, update func(a *Agent)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent, exists := m.agents[agentID]
+	if !exists {
+		return fmt.Errorf("agent not found")
+	}
+	update(agent)
+	return nil
+}
+
+// ReportStats stores the stats an agent pushed so monitoring reads real
+// values instead of collecting them locally.
+func (m *Manager) ReportStats(agentID string, stats *AgentStats) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.agents[agentID]; !exists {
+		return fmt.Errorf("agent not found")
+	}
+	stats.AgentID = agentID
+	if stats.System.Timestamp.IsZero() {
+		stats.System.Timestamp = time.Now()
+	}
+	m.stats[agentID] = stats
+	m.reportedAt[agentID] = time.Now()
+	return nil
+}
+
+// GetStats returns the agent's last reported stats, falling back to
+// collecting them directly for agents that don't report. Once an agent
+// that reports stops doing so for longer than the max age, it returns an
+// error rather than the old values.
+func (m *Manager) GetStats(agentID string) (*AgentStats, error) {
+	m.mu.RLock()
+	agent, exists := m.agents[agentID]
+	stats, reported := m.stats[agentID]
+	age := time.Since(m.reportedAt[agentID])
+	maxAge := m.statsMaxAge
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+	if reported {
+		if age > maxAge {
+			return nil, fmt.Errorf("no stats from agent %s for %s", agentID, age.Round(time.Second))
+		}
+		return stats, nil
+	}
+	return agent.CollectStats()
+}
+
+// SelectAgents returns the registered agents matching the selector.
+func (m *Manager) SelectAgents(sel Selector) []*Agent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var agents []*Agent
+	for _, agent := range m.agents {
+		if sel.Matches(agent) {
+			agents = append(agents, agent.clone())
+		}
 	}
 	return agents
 }
@@ -56,6 +205,16 @@ func (m *Manager) RunCommandOnAgent(ctx context.Context, agentID string, cmd Age
 	return agent.ExecuteCommand(ctx, cmd, m.queue)
 }
 
+// EnqueueCommand queues cmd for the agent and returns the job ID, for
+// callers that follow the job with WaitForJob.
+func (m *Manager) EnqueueCommand(ctx context.Context, agentID string, cmd AgentCommand) (string, error) {
+	agent, exists := m.GetAgent(agentID)
+	if !exists {
+		return "", fmt.Errorf("agent not found")
+	}
+	return agent.enqueue(ctx, cmd, m.queue)
+}
+
 func (m *Manager) RunCommandOnAll(ctx context.Context, cmd AgentCommand) map[string]interface{} {
 	results := make(map[string]interface{})
 	agents := m.ListAgents()
@@ -84,4 +243,46 @@ func (m *Manager) RunCommandOnAll(ctx context.Context, cmd AgentCommand) map[str
 
 	wg.Wait()
 	return results
-}
\ No newline at end of file
+}
+
+// RunCommandAndWait enqueues cmd for the agent and blocks until the agent
+// reports the job as completed or failed, or the context is cancelled.
+func (m *Manager) RunCommandAndWait(ctx context.Context, agentID string, cmd AgentCommand) (*jobqueue.Job, error) {
+	agent, exists := m.GetAgent(agentID)
+	if !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+
+	jobID, err := agent.enqueue(ctx, cmd, m.queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.WaitForJob(ctx, jobID)
+}
+
+// WaitForJob polls the queue until the job reaches a terminal state.
+func (m *Manager) WaitForJob(ctx context.Context, jobID string) (*jobqueue.Job, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		job, err := m.queue.GetJob(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch job.Status {
+		case "completed":
+			return job, nil
+		case "failed":
+			return job, fmt.Errorf("job %s failed: %s", jobID, job.Result)
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// backend/internal/agent/netconn.go
+package agent
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/jobqueue"
+)
+
+// NetConnectionsCommand asks the agent for its current connection table.
+// Agents also push the same report periodically to /agents/:id/connections.
+const NetConnectionsCommand = "net.connections"
+
+// TCP socket states as they appear in /proc/net/tcp
+const (
+	tcpEstablished = "01"
+	tcpListen      = "0A"
+)
+
+// Socket is one TCP socket on the host. Remote fields are empty for
+// listening sockets.
+type Socket struct {
+	LocalAddr  string `json:"local_addr"`
+	LocalPort  int    `json:"local_port"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	RemotePort int    `json:"remote_port,omitempty"`
+	PID        int    `json:"pid,omitempty"` // 0 when the owner couldn't be read
+	Process    string `json:"process,omitempty"`
+}
+
+type ConnectionReport struct {
+	Listening   []Socket  `json:"listening"`
+	Established []Socket  `json:"established"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+func HandleNetConnectionsJob(job jobqueue.Job) (string, error) {
+	report, err := CollectConnections()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(report)
+	return string(data), err
+}
+
+// CollectConnections reads the TCP tables from /proc/net and attributes
+// each socket to its owning process by socket inode.
+func CollectConnections() (*ConnectionReport, error) {
+	owners := socketOwners()
+	report := &ConnectionReport{
+		Listening:   []Socket{},
+		Established: []Socket{},
+		CollectedAt: time.Now(),
+	}
+
+	read := 0
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue // tcp6 is absent when IPv6 is disabled
+		}
+		read++
+		err = parseTCPTable(f, owners, report)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	if read == 0 {
+		return nil, fmt.Errorf("no TCP tables found under /proc/net")
+	}
+	return report, nil
+}
+
+type socketOwner struct {
+	pid     int
+	process string
+}
+
+func parseTCPTable(f *os.File, owners map[string]socketOwner, report *ConnectionReport) error {
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		state := fields[3]
+		if state != tcpListen && state != tcpEstablished {
+			continue
+		}
+
+		localAddr, localPort, err := parseProcAddr(fields[1])
+		if err != nil {
+			return err
+		}
+		sock := Socket{LocalAddr: localAddr, LocalPort: localPort}
+		if owner, ok := owners[fields[9]]; ok {
+			sock.PID = owner.pid
+			sock.Process = owner.process
+		}
+
+		if state == tcpListen {
+			report.Listening = append(report.Listening, sock)
+			continue
+		}
+		sock.RemoteAddr, sock.RemotePort, err = parseProcAddr(fields[2])
+		if err != nil {
+			return err
+		}
+		report.Established = append(report.Established, sock)
+	}
+	return scanner.Err()
+}
+
+// parseProcAddr decodes "0100007F:0050". The address is stored as 32-bit
+// words in host (little-endian) byte order.
+func parseProcAddr(s string) (string, int, error) {
+	hexAddr, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("malformed address %q", s)
+	}
+	raw, err := hex.DecodeString(hexAddr)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return "", 0, fmt.Errorf("malformed address %q", s)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed port %q", s)
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip.String(), int(port), nil
+}
+
+// socketOwners maps socket inodes to the process holding them. Processes
+// we can't inspect (without root) are left out.
+func socketOwners() map[string]socketOwner {
+	owners := make(map[string]socketOwner)
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return owners
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue
+		}
+		var process string
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if process == "" {
+				comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+				process = strings.TrimSpace(string(comm))
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+			owners[inode] = socketOwner{pid: pid, process: process}
+		}
+	}
+	return owners
+}
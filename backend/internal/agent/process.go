@@ -0,0 +1,333 @@
+// backend/internal/agent/process.go
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/jobqueue"
+)
+
+// Job commands for inspecting and controlling processes on the host.
+const (
+	ProcessListCommand     = "process.list"
+	ProcessSignalCommand   = "process.signal"    // Args: pid, signal
+	ProcessKillTreeCommand = "process.kill-tree" // Args: pid, signal
+	ProcessReniceCommand   = "process.renice"    // Args: pid, nice
+)
+
+// userHZ is the kernel's clock tick rate for /proc times, which is 100 on
+// every mainstream Linux architecture.
+const userHZ = 100
+
+var signalsByName = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"CONT": syscall.SIGCONT,
+	"STOP": syscall.SIGSTOP,
+}
+
+// ParseSignal accepts a signal name with or without the SIG prefix, or its
+// number.
+func ParseSignal(name string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, fmt.Errorf("invalid signal number %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+	sig, ok := signalsByName[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return 0, fmt.Errorf("unknown signal %q", name)
+	}
+	return sig, nil
+}
+
+// HandleProcessJob runs a process job on the host and returns the string
+// the agent reports back through CompleteJob.
+func HandleProcessJob(job jobqueue.Job) (string, error) {
+	if job.Command == ProcessListCommand {
+		procs, err := CollectProcesses()
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(procs)
+		return string(data), err
+	}
+
+	if len(job.Args) != 2 {
+		return "", fmt.Errorf("%s expects 2 arguments", job.Command)
+	}
+	pid, err := strconv.Atoi(job.Args[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid pid: %w", err)
+	}
+
+	switch job.Command {
+	case ProcessSignalCommand:
+		sig, err := ParseSignal(job.Args[1])
+		if err != nil {
+			return "", err
+		}
+		if err := SignalProcess(pid, sig); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`{"signaled":[%d]}`, pid), nil
+
+	case ProcessKillTreeCommand:
+		sig, err := ParseSignal(job.Args[1])
+		if err != nil {
+			return "", err
+		}
+		pids, err := KillTree(pid, sig)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(map[string][]int{"signaled": pids})
+		return string(data), err
+
+	case ProcessReniceCommand:
+		nice, err := strconv.Atoi(job.Args[1])
+		if err != nil {
+			return "", fmt.Errorf("invalid nice value: %w", err)
+		}
+		if err := Renice(pid, nice); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`{"pid":%d,"nice":%d}`, pid, nice), nil
+	}
+	return "", fmt.Errorf("unknown process command %q", job.Command)
+}
+
+func checkTargetPID(pid int) error {
+	if pid <= 1 {
+		return fmt.Errorf("refusing to act on pid %d", pid)
+	}
+	if pid == os.Getpid() {
+		return fmt.Errorf("refusing to act on the agent itself")
+	}
+	return nil
+}
+
+func SignalProcess(pid int, sig syscall.Signal) error {
+	if err := checkTargetPID(pid); err != nil {
+		return err
+	}
+	if err := syscall.Kill(pid, sig); err != nil {
+		return fmt.Errorf("failed to signal %d: %w", pid, err)
+	}
+	return nil
+}
+
+// KillTree signals pid and all of its descendants. The parent is signaled
+// first so a supervisor can't respawn children we've just killed; the
+// descendants are collected beforehand so re-parenting doesn't hide them.
+func KillTree(pid int, sig syscall.Signal) ([]int, error) {
+	if err := checkTargetPID(pid); err != nil {
+		return nil, err
+	}
+
+	procs, err := CollectProcesses()
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int][]int)
+	for _, p := range procs {
+		children[p.PPID] = append(children[p.PPID], p.PID)
+	}
+
+	tree := []int{pid}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+
+	var signaled []int
+	for _, p := range tree {
+		if p == os.Getpid() {
+			continue
+		}
+		if err := syscall.Kill(p, sig); err != nil {
+			if err == syscall.ESRCH {
+				continue // already gone
+			}
+			return signaled, fmt.Errorf("failed to signal %d: %w", p, err)
+		}
+		signaled = append(signaled, p)
+	}
+	return signaled, nil
+}
+
+func Renice(pid, nice int) error {
+	if err := checkTargetPID(pid); err != nil {
+		return err
+	}
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("nice must be between -20 and 19")
+	}
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice); err != nil {
+		return fmt.Errorf("failed to renice %d: %w", pid, err)
+	}
+	return nil
+}
+
+// CollectProcesses reads every process from /proc. Processes that exit
+// while being read are skipped. CPU usage is the average since the process
+// started.
+func CollectProcesses() ([]ProcessStats, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read /proc: %w", err)
+	}
+
+	bootTime, err := readBootTime()
+	if err != nil {
+		return nil, err
+	}
+	memTotal := readMemTotal()
+	pageSize := int64(os.Getpagesize())
+	users := make(map[string]string)
+	now := time.Now()
+
+	var procs []ProcessStats
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		p, err := readProcess(pid, bootTime, pageSize, users)
+		if err != nil {
+			continue
+		}
+
+		if elapsed := now.Sub(p.StartTime).Seconds(); elapsed > 0 {
+			p.CPUUsage /= elapsed
+			p.CPUUsage *= 100
+		}
+		if memTotal > 0 {
+			p.MemoryUsage = float64(p.RSS) / float64(memTotal) * 100
+		}
+		procs = append(procs, *p)
+	}
+
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs, nil
+}
+
+// readProcess parses /proc/<pid>/stat, status, cmdline and fd. CPUUsage is
+// left as total CPU seconds for the caller to turn into a percentage.
+func readProcess(pid int, bootTime time.Time, pageSize int64, users map[string]string) (*ProcessStats, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// The command name is in parentheses and may itself contain spaces or
+	// parentheses, so split on the last ")"
+	open := strings.IndexByte(string(stat), '(')
+	closing := strings.LastIndexByte(string(stat), ')')
+	if open < 0 || closing < open {
+		return nil, fmt.Errorf("malformed stat for %d", pid)
+	}
+	name := string(stat[open+1 : closing])
+	fields := strings.Fields(string(stat[closing+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed stat for %d", pid)
+	}
+	// fields[0] is field 3 of proc(5)
+	field := func(n int) int64 {
+		v, _ := strconv.ParseInt(fields[n-3], 10, 64)
+		return v
+	}
+
+	p := &ProcessStats{
+		PID:       pid,
+		PPID:      int(field(4)),
+		Name:      name,
+		State:     fields[0],
+		CPUUsage:  float64(field(14)+field(15)) / userHZ,
+		Nice:      int(field(19)),
+		Threads:   int(field(20)),
+		StartTime: bootTime.Add(time.Duration(field(22)) * time.Second / userHZ),
+		RSS:       field(24) * pageSize,
+	}
+
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		p.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+	if uid := readUID(filepath.Join(dir, "status")); uid != "" {
+		if _, cached := users[uid]; !cached {
+			users[uid] = uid
+			if u, err := user.LookupId(uid); err == nil {
+				users[uid] = u.Username
+			}
+		}
+		p.User = users[uid]
+	}
+	if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		p.OpenFDs = len(fds)
+	}
+	return p, nil
+}
+
+func readUID(statusPath string) string {
+	f, err := os.Open(statusPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 2 && fields[0] == "Uid:" {
+			return fields[1] // real UID
+		}
+	}
+	return ""
+}
+
+func readBootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read /proc/stat: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			secs, err := strconv.ParseInt(strings.TrimSpace(line[len("btime "):]), 10, 64)
+			if err != nil {
+				break
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("boot time not found in /proc/stat")
+}
+
+// readMemTotal returns total memory in bytes, or 0 if unknown.
+func readMemTotal() int64 {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
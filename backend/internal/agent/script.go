@@ -0,0 +1,62 @@
+// backend/internal/agent/script.go
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/jobqueue"
+)
+
+// ScriptRunCommand runs job.Script with the interpreter in Args[0].
+const ScriptRunCommand = "script.run"
+
+// AllowedInterpreters are the only programs a script.run job may start.
+var AllowedInterpreters = map[string]bool{
+	"bash":    true,
+	"sh":      true,
+	"python3": true,
+}
+
+const defaultScriptTimeout = 10 * time.Minute
+
+// HandleScriptJob runs a library script on the host and returns its
+// combined output. The script is fed on stdin so it never appears in the
+// process list or touches the disk.
+func HandleScriptJob(job jobqueue.Job) (string, error) {
+	if len(job.Args) != 1 {
+		return "", fmt.Errorf("%s expects 1 argument", job.Command)
+	}
+	interpreter := job.Args[0]
+	if !AllowedInterpreters[interpreter] {
+		return "", fmt.Errorf("interpreter %q is not allowed", interpreter)
+	}
+
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = defaultScriptTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := []string{"-s"}
+	if interpreter == "python3" {
+		args = []string{"-"}
+	}
+	cmd := exec.CommandContext(ctx, interpreter, args...)
+	cmd.Stdin = bytes.NewBufferString(job.Script)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return output.String(), fmt.Errorf("script timed out after %s", timeout)
+		}
+		return output.String(), fmt.Errorf("script failed: %w: %s", err, output.String())
+	}
+	return output.String(), nil
+}
@@ -0,0 +1,45 @@
+// backend/internal/agent/selector.go
+package agent
+
+// Selector picks a set of agents by ID and/or tags. An agent matches when it
+// is listed in AgentIDs (if any are given) and carries every tag in Tags.
+// An empty selector matches every agent.
+type Selector struct {
+	AgentIDs []string `json:"agent_ids,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+func (s Selector) IsEmpty() bool {
+	return len(s.AgentIDs) == 0 && len(s.Tags) == 0
+}
+
+func (s Selector) Matches(a *Agent) bool {
+	if len(s.AgentIDs) > 0 {
+		found := false
+		for _, id := range s.AgentIDs {
+			if id == a.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, tag := range s.Tags {
+		if !a.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *Agent) HasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+// backend/internal/agent/selfupdate.go
+package agent
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Files kept next to the agent binary during a self-update:
+//
+//	<binary>.new      download in progress
+//	<binary>.prev     the binary that was running before the update
+//	<binary>.pending  unix deadline by which the new binary must confirm health
+const (
+	newSuffix     = ".new"
+	prevSuffix    = ".prev"
+	pendingSuffix = ".pending"
+)
+
+// SelfUpdater runs on the agent host and swaps the agent binary for a
+// release published by the backend.
+type SelfUpdater struct {
+	BinaryPath    string
+	PublicKey     ed25519.PublicKey // backend release signing key
+	Credential    string            // agent credential from enrollment
+	HealthTimeout time.Duration
+	Client        *http.Client
+}
+
+// Update downloads and verifies the release, swaps it in and re-executes
+// the agent. It only returns on failure.
+func (u *SelfUpdater) Update(url, expectedSHA256, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if !ed25519.Verify(u.PublicKey, []byte(expectedSHA256), sig) {
+		return fmt.Errorf("release signature does not match checksum")
+	}
+
+	newPath := u.BinaryPath + newSuffix
+	if err := u.download(url, newPath, expectedSHA256); err != nil {
+		os.Remove(newPath)
+		return err
+	}
+
+	prevPath := u.BinaryPath + prevSuffix
+	if err := os.Rename(u.BinaryPath, prevPath); err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("failed to keep previous binary: %w", err)
+	}
+	if err := os.Rename(newPath, u.BinaryPath); err != nil {
+		os.Rename(prevPath, u.BinaryPath)
+		return fmt.Errorf("failed to install new binary: %w", err)
+	}
+
+	deadline := time.Now().Add(u.HealthTimeout).Unix()
+	if err := os.WriteFile(u.BinaryPath+pendingSuffix, []byte(strconv.FormatInt(deadline, 10)), 0600); err != nil {
+		os.Rename(prevPath, u.BinaryPath)
+		return fmt.Errorf("failed to record pending update: %w", err)
+	}
+
+	return u.restart()
+}
+
+// Rollback reinstates the previous binary and re-executes it.
+func (u *SelfUpdater) Rollback() error {
+	prevPath := u.BinaryPath + prevSuffix
+	if _, err := os.Stat(prevPath); err != nil {
+		return fmt.Errorf("no previous binary to roll back to: %w", err)
+	}
+	if err := os.Rename(prevPath, u.BinaryPath); err != nil {
+		return fmt.Errorf("failed to restore previous binary: %w", err)
+	}
+	os.Remove(u.BinaryPath + pendingSuffix)
+	return u.restart()
+}
+
+// CheckPending must be called when the agent starts. If a previous start
+// of this binary never confirmed health before its deadline (for example
+// it crashed and the service manager restarted it), the update is rolled
+// back.
+func (u *SelfUpdater) CheckPending() error {
+	data, err := os.ReadFile(u.BinaryPath + pendingSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	deadline, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || time.Now().Unix() > deadline {
+		return u.Rollback()
+	}
+	return nil
+}
+
+// ConfirmHealthy is called after the first successful heartbeat on the new
+// version and makes the update permanent.
+func (u *SelfUpdater) ConfirmHealthy() {
+	if err := os.Remove(u.BinaryPath + pendingSuffix); err == nil {
+		os.Remove(u.BinaryPath + prevSuffix)
+	}
+}
+
+func (u *SelfUpdater) download(url, path, expectedSHA256 string) error {
+	client := u.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Minute}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build download request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+u.Credential)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download release: %s", resp.Status)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		return fmt.Errorf("failed to write release: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != expectedSHA256 {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedSHA256, got)
+	}
+	return f.Sync()
+}
+
+func (u *SelfUpdater) restart() error {
+	return syscall.Exec(u.BinaryPath, os.Args, os.Environ())
+}
@@ -0,0 +1,127 @@
+// backend/internal/agent/state.go
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// StateReport is what an agent sends back about the files and packages the
+// backend asked it to watch.
+type StateReport struct {
+	Files      []FileState    `json:"files"`
+	Packages   []PackageState `json:"packages"`
+	ReportedAt time.Time      `json:"reported_at"`
+}
+
+type FileState struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	SHA256 string `json:"sha256,omitempty"`
+	Mode   string `json:"mode,omitempty"`
+	Owner  string `json:"owner,omitempty"`
+	Group  string `json:"group,omitempty"`
+}
+
+type PackageState struct {
+	Name      string `json:"name"`
+	Installed bool   `json:"installed"`
+	Version   string `json:"version,omitempty"`
+}
+
+// CollectState gathers the actual state of the given files and packages on
+// the host.
+func CollectState(files, packages []string) (*StateReport, error) {
+	report := &StateReport{ReportedAt: time.Now()}
+
+	for _, path := range files {
+		info, err := StatFile(path)
+		if err != nil {
+			return nil, err
+		}
+		state := FileState{
+			Path:   path,
+			Exists: info.Exists,
+			SHA256: info.SHA256,
+			Mode:   info.Mode,
+		}
+		if info.Exists {
+			state.Owner, state.Group = fileOwner(path)
+		}
+		report.Files = append(report.Files, state)
+	}
+
+	if len(packages) > 0 {
+		installed, err := installedPackages()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range packages {
+			version, ok := installed[name]
+			report.Packages = append(report.Packages, PackageState{
+				Name:      name,
+				Installed: ok,
+				Version:   version,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+func fileOwner(path string) (string, string) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return "", ""
+	}
+	sys, ok := st.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+
+	owner := strconv.FormatUint(uint64(sys.Uid), 10)
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+	group := strconv.FormatUint(uint64(sys.Gid), 10)
+	if g, err := user.LookupGroupId(group); err == nil {
+		group = g.Name
+	}
+	return owner, group
+}
+
+// installedPackages asks dpkg or rpm, whichever the host has, for the
+// installed package versions.
+func installedPackages() (map[string]string, error) {
+	var out []byte
+	var err error
+
+	if _, lookErr := exec.LookPath("dpkg-query"); lookErr == nil {
+		out, err = exec.Command("dpkg-query", "-W", "-f", "${Package} ${Version} ${db:Status-Status}\n").Output()
+	} else if _, lookErr := exec.LookPath("rpm"); lookErr == nil {
+		out, err = exec.Command("rpm", "-qa", "--qf", "%{NAME} %{VERSION}-%{RELEASE} installed\n").Output()
+	} else {
+		return nil, fmt.Errorf("no supported package manager found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	packages := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[2] == "installed" {
+			packages[fields[0]] = fields[1]
+		}
+	}
+	return packages, nil
+}
@@ -6,19 +6,44 @@ import (
 )
 
 type SystemStats struct {
-	CPUUsage    float64   `json:"cpu_usage"`
-	MemoryUsage float64   `json:"memory_usage"`
-	DiskUsage   float64   `json:"disk_usage"`
-	NetworkIn   float64   `json:"network_in"`
-	NetworkOut  float64   `json:"network_out"`
-	Timestamp   time.Time `json:"timestamp"`
+	CPUUsage    float64          `json:"cpu_usage"`
+	MemoryUsage float64          `json:"memory_usage"`
+	DiskUsage   float64          `json:"disk_usage"`
+	NetworkIn   float64          `json:"network_in"`
+	NetworkOut  float64          `json:"network_out"`
+	Disks       []DiskStats      `json:"disks,omitempty"`
+	Interfaces  []InterfaceStats `json:"interfaces,omitempty"`
+	Timestamp   time.Time        `json:"timestamp"`
+}
+
+type DiskStats struct {
+	Mount  string  `json:"mount"`
+	Device string  `json:"device,omitempty"`
+	Usage  float64 `json:"usage"` // percent
+}
+
+// InterfaceStats holds byte counters since boot; the metrics query API
+// turns them into rates.
+type InterfaceStats struct {
+	Name     string  `json:"name"`
+	BytesIn  float64 `json:"bytes_in"`
+	BytesOut float64 `json:"bytes_out"`
 }
 
 type ProcessStats struct {
-	PID         int     `json:"pid"`
-	Name        string  `json:"name"`
-	CPUUsage    float64 `json:"cpu_usage"`
-	MemoryUsage float64 `json:"memory_usage"`
+	PID         int       `json:"pid"`
+	PPID        int       `json:"ppid"`
+	Name        string    `json:"name"`
+	Cmdline     string    `json:"cmdline"`
+	User        string    `json:"user"`
+	State       string    `json:"state"` // R, S, D, Z, T, ...
+	CPUUsage    float64   `json:"cpu_usage"`
+	MemoryUsage float64   `json:"memory_usage"`
+	RSS         int64     `json:"rss"` // bytes
+	OpenFDs     int       `json:"open_fds"`
+	Threads     int       `json:"threads"`
+	Nice        int       `json:"nice"`
+	StartTime   time.Time `json:"start_time"`
 }
 
 type AgentStats struct {
@@ -0,0 +1,170 @@
+// backend/internal/agentupdate/release.go
+package agentupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Release is one agent binary build. Signature is an ed25519 signature over
+// the hex SHA256 digest, made with the backend's release signing key.
+type Release struct {
+	Version    string    `json:"version"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	Signature  string    `json:"signature"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+type ReleaseStore interface {
+	AddRelease(version, osName, arch string, binary []byte) (*Release, error)
+	GetRelease(version, osName, arch string) (*Release, error)
+	ListReleases() []Release
+	ArtifactPath(version, osName, arch string) (string, error)
+	PublicKey() ed25519.PublicKey
+}
+
+var releaseFieldPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// fileReleaseStore keeps artifacts and their metadata under dir so releases
+// survive a backend restart:
+//
+//	dir/<version>/<os>-<arch>/agent
+//	dir/<version>/<os>-<arch>/release.json
+type fileReleaseStore struct {
+	dir        string
+	signingKey ed25519.PrivateKey
+	releases   map[string]Release // version/os/arch -> release
+	mu         sync.RWMutex
+}
+
+func NewFileReleaseStore(dir string, signingKey ed25519.PrivateKey) (ReleaseStore, error) {
+	s := &fileReleaseStore{
+		dir:        dir,
+		signingKey: signingKey,
+		releases:   make(map[string]Release),
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create release directory: %w", err)
+	}
+
+	manifests, err := filepath.Glob(filepath.Join(dir, "*", "*", "release.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, manifest := range manifests {
+		data, err := os.ReadFile(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", manifest, err)
+		}
+		var r Release
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", manifest, err)
+		}
+		s.releases[releaseKey(r.Version, r.OS, r.Arch)] = r
+	}
+
+	return s, nil
+}
+
+func (s *fileReleaseStore) AddRelease(version, osName, arch string, binary []byte) (*Release, error) {
+	for _, field := range []string{version, osName, arch} {
+		if !releaseFieldPattern.MatchString(field) {
+			return nil, fmt.Errorf("invalid release field %q", field)
+		}
+	}
+	if len(binary) == 0 {
+		return nil, fmt.Errorf("release binary is empty")
+	}
+
+	sum := sha256.Sum256(binary)
+	digest := hex.EncodeToString(sum[:])
+	release := Release{
+		Version:    version,
+		OS:         osName,
+		Arch:       arch,
+		Size:       int64(len(binary)),
+		SHA256:     digest,
+		Signature:  base64.StdEncoding.EncodeToString(ed25519.Sign(s.signingKey, []byte(digest))),
+		UploadedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.releases[releaseKey(version, osName, arch)]; exists {
+		return nil, fmt.Errorf("release %s %s/%s already exists", version, osName, arch)
+	}
+
+	dir := filepath.Join(s.dir, version, osName+"-"+arch)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create release directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "agent"), binary, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write release binary: %w", err)
+	}
+	manifest, err := json.MarshalIndent(release, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "release.json"), manifest, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write release manifest: %w", err)
+	}
+
+	s.releases[releaseKey(version, osName, arch)] = release
+	return &release, nil
+}
+
+func (s *fileReleaseStore) GetRelease(version, osName, arch string) (*Release, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	release, exists := s.releases[releaseKey(version, osName, arch)]
+	if !exists {
+		return nil, fmt.Errorf("release %s %s/%s not found", version, osName, arch)
+	}
+	return &release, nil
+}
+
+func (s *fileReleaseStore) ListReleases() []Release {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	releases := make([]Release, 0, len(s.releases))
+	for _, r := range s.releases {
+		releases = append(releases, r)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releaseKey(releases[i].Version, releases[i].OS, releases[i].Arch) <
+			releaseKey(releases[j].Version, releases[j].OS, releases[j].Arch)
+	})
+	return releases
+}
+
+func (s *fileReleaseStore) ArtifactPath(version, osName, arch string) (string, error) {
+	if _, err := s.GetRelease(version, osName, arch); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, version, osName+"-"+arch, "agent"), nil
+}
+
+func (s *fileReleaseStore) PublicKey() ed25519.PublicKey {
+	return s.signingKey.Public().(ed25519.PublicKey)
+}
+
+func releaseKey(version, osName, arch string) string {
+	return version + "/" + osName + "/" + arch
+}
@@ -0,0 +1,421 @@
+// backend/internal/agentupdate/rollout.go
+package agentupdate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+// Job commands handled by the agent's self-update code.
+const (
+	SelfUpdateCommand = "agent.self-update"
+	RollbackCommand   = "agent.rollback"
+)
+
+type RolloutService interface {
+	StartRollout(req RolloutRequest) (*Rollout, error)
+	GetRollout(id string) (*Rollout, error)
+	ListRollouts() []Rollout
+	AbortRollout(id string) error
+}
+
+type RolloutRequest struct {
+	Version       string         `json:"version" binding:"required"`
+	Selector      agent.Selector `json:"selector"`
+	WavePercents  []int          `json:"wave_percents"`  // cumulative, e.g. [10, 50, 100]
+	HealthTimeout time.Duration  `json:"health_timeout"` // time for an agent to heartbeat on the new version
+	MaxFailures   int            `json:"max_failures"`   // failures tolerated before rolling back
+}
+
+type Rollout struct {
+	ID            string                   `json:"id"`
+	Version       string                   `json:"version"`
+	Selector      agent.Selector           `json:"selector"`
+	HealthTimeout time.Duration            `json:"health_timeout"`
+	MaxFailures   int                      `json:"max_failures"`
+	Status        string                   `json:"status"` // running, completed, rolled-back, aborted
+	Waves         []Wave                   `json:"waves"`
+	Agents        map[string]*AgentRollout `json:"agents"`
+	Failures      int                      `json:"failures"`
+	CreatedAt     time.Time                `json:"created_at"`
+	CompletedAt   time.Time                `json:"completed_at,omitempty"`
+}
+
+type Wave struct {
+	Number      int       `json:"number"`
+	AgentIDs    []string  `json:"agent_ids"`
+	Status      string    `json:"status"` // pending, running, completed, failed
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+type AgentRollout struct {
+	PreviousVersion string `json:"previous_version"`
+	Status          string `json:"status"` // pending, updating, updated, failed, rolling-back, rolled-back
+	Error           string `json:"error,omitempty"`
+}
+
+var defaultWavePercents = []int{10, 50, 100}
+
+type rolloutService struct {
+	agentManager *agent.Manager
+	releases     ReleaseStore
+	baseURL      string
+	rollouts     map[string]*Rollout
+	cancelFuncs  map[string]context.CancelFunc // rolloutID -> cancelFunc
+	mu           sync.RWMutex
+}
+
+// NewRolloutService creates the rollout engine. baseURL is the externally
+// reachable API address agents download release binaries from.
+func NewRolloutService(agentManager *agent.Manager, releases ReleaseStore, baseURL string) RolloutService {
+	return &rolloutService{
+		agentManager: agentManager,
+		releases:     releases,
+		baseURL:      baseURL,
+		rollouts:     make(map[string]*Rollout),
+		cancelFuncs:  make(map[string]context.CancelFunc),
+	}
+}
+
+func (s *rolloutService) StartRollout(req RolloutRequest) (*Rollout, error) {
+	if len(req.WavePercents) == 0 {
+		req.WavePercents = defaultWavePercents
+	}
+	for i, p := range req.WavePercents {
+		if p <= 0 || p > 100 || (i > 0 && p <= req.WavePercents[i-1]) {
+			return nil, fmt.Errorf("wave percents must be increasing values in 1..100")
+		}
+	}
+	if req.WavePercents[len(req.WavePercents)-1] != 100 {
+		req.WavePercents = append(req.WavePercents, 100)
+	}
+	if req.HealthTimeout <= 0 {
+		req.HealthTimeout = 5 * time.Minute
+	}
+
+	var targets []*agent.Agent
+	for _, a := range s.agentManager.SelectAgents(req.Selector) {
+		if a.Version == req.Version {
+			continue
+		}
+		if _, err := s.releases.GetRelease(req.Version, a.OS, a.Architecture); err != nil {
+			return nil, fmt.Errorf("agent %s: %w", a.ID, err)
+		}
+		targets = append(targets, a)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no agents need updating to %s", req.Version)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+
+	rollout := &Rollout{
+		ID:            fmt.Sprintf("rollout-%d", time.Now().UnixNano()),
+		Version:       req.Version,
+		Selector:      req.Selector,
+		HealthTimeout: req.HealthTimeout,
+		MaxFailures:   req.MaxFailures,
+		Status:        "running",
+		Agents:        make(map[string]*AgentRollout),
+		CreatedAt:     time.Now(),
+	}
+
+	start := 0
+	for _, pct := range req.WavePercents {
+		end := (len(targets)*pct + 99) / 100
+		if end <= start {
+			continue
+		}
+		wave := Wave{Number: len(rollout.Waves) + 1, Status: "pending"}
+		for _, a := range targets[start:end] {
+			wave.AgentIDs = append(wave.AgentIDs, a.ID)
+			rollout.Agents[a.ID] = &AgentRollout{PreviousVersion: a.Version, Status: "pending"}
+		}
+		rollout.Waves = append(rollout.Waves, wave)
+		start = end
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	s.rollouts[rollout.ID] = rollout
+	s.cancelFuncs[rollout.ID] = cancel
+	s.mu.Unlock()
+
+	go s.run(ctx, rollout.ID)
+	return s.GetRollout(rollout.ID)
+}
+
+func (s *rolloutService) GetRollout(id string) (*Rollout, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rollout, exists := s.rollouts[id]
+	if !exists {
+		return nil, fmt.Errorf("rollout not found")
+	}
+	return copyRollout(rollout), nil
+}
+
+func (s *rolloutService) ListRollouts() []Rollout {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rollouts := make([]Rollout, 0, len(s.rollouts))
+	for _, r := range s.rollouts {
+		rollouts = append(rollouts, *copyRollout(r))
+	}
+	sort.Slice(rollouts, func(i, j int) bool { return rollouts[i].CreatedAt.After(rollouts[j].CreatedAt) })
+	return rollouts
+}
+
+// AbortRollout stops scheduling further waves. Agents already updated keep
+// the new version.
+func (s *rolloutService) AbortRollout(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rollout, exists := s.rollouts[id]
+	if !exists {
+		return fmt.Errorf("rollout not found")
+	}
+	if rollout.Status != "running" {
+		return fmt.Errorf("rollout is %s", rollout.Status)
+	}
+
+	s.cancelFuncs[id]()
+	delete(s.cancelFuncs, id)
+	rollout.Status = "aborted"
+	rollout.CompletedAt = time.Now()
+	return nil
+}
+
+func (s *rolloutService) run(ctx context.Context, id string) {
+	s.mu.RLock()
+	waveCount := len(s.rollouts[id].Waves)
+	s.mu.RUnlock()
+
+	for i := 0; i < waveCount; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !s.runWave(ctx, id, i) {
+			if ctx.Err() == nil {
+				s.rollback(id)
+			}
+			return
+		}
+	}
+
+	s.finish(id, "completed")
+}
+
+// runWave updates every agent in the wave and waits for each to heartbeat
+// on the new version. It returns false if the rollout's failure budget was
+// exceeded.
+func (s *rolloutService) runWave(ctx context.Context, id string, index int) bool {
+	s.mu.Lock()
+	rollout := s.rollouts[id]
+	wave := &rollout.Waves[index]
+	wave.Status = "running"
+	wave.StartedAt = time.Now()
+	agentIDs := append([]string(nil), wave.AgentIDs...)
+	for _, agentID := range agentIDs {
+		rollout.Agents[agentID].Status = "updating"
+	}
+	version := rollout.Version
+	timeout := rollout.HealthTimeout
+	startedAt := wave.StartedAt
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, agentID := range agentIDs {
+		wg.Add(1)
+		go func(agentID string) {
+			defer wg.Done()
+			err := s.updateAgent(ctx, agentID, version, startedAt, timeout)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			state := rollout.Agents[agentID]
+			if err != nil {
+				state.Status = "failed"
+				state.Error = err.Error()
+				rollout.Failures++
+			} else {
+				state.Status = "updated"
+			}
+		}(agentID)
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wave.CompletedAt = time.Now()
+	if rollout.Failures > rollout.MaxFailures {
+		wave.Status = "failed"
+		return false
+	}
+	wave.Status = "completed"
+	return true
+}
+
+func (s *rolloutService) updateAgent(ctx context.Context, agentID, version string, since time.Time, timeout time.Duration) error {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return fmt.Errorf("agent not found")
+	}
+
+	release, err := s.releases.GetRelease(version, a.OS, a.Architecture)
+	if err != nil {
+		return err
+	}
+
+	// The agent restarts itself as part of the update, so the job itself may
+	// never be reported complete; success is judged by the next heartbeat
+	url := fmt.Sprintf("%s/api/v1/agent-releases/%s/%s/%s/download", s.baseURL, release.Version, release.OS, release.Arch)
+	_, err = s.agentManager.RunCommandOnAgent(ctx, agentID, agent.AgentCommand{
+		Command: SelfUpdateCommand,
+		Args:    []string{release.Version, url, release.SHA256, release.Signature},
+		Timeout: timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return fmt.Errorf("no heartbeat on version %s within %s", version, timeout)
+		case <-ticker.C:
+			a, exists := s.agentManager.GetAgent(agentID)
+			if exists && a.Version == version && a.LastHeartbeat.After(since) {
+				return nil
+			}
+		}
+	}
+}
+
+// rollback returns every agent touched by the rollout to its previous
+// version. Agents whose new binary never came up roll themselves back
+// locally; the job covers agents that came up but misbehave. An agent only
+// counts as rolled back once the job completes or it heartbeats on the
+// previous version.
+func (s *rolloutService) rollback(id string) {
+	s.mu.Lock()
+	rollout := s.rollouts[id]
+	targets := make(map[string]string)
+	for agentID, state := range rollout.Agents {
+		if state.Status == "updated" || state.Status == "failed" || state.Status == "updating" {
+			targets[agentID] = state.PreviousVersion
+			state.Status = "rolling-back"
+		}
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for agentID, previous := range targets {
+		wg.Add(1)
+		go func(agentID, previous string) {
+			defer wg.Done()
+			err := s.rollbackAgent(agentID, previous, rollbackTimeout)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			state := rollout.Agents[agentID]
+			if err != nil {
+				state.Status = "failed"
+				state.Error = fmt.Sprintf("rollback failed: %v", err)
+			} else {
+				state.Status = "rolled-back"
+			}
+		}(agentID, previous)
+	}
+	wg.Wait()
+
+	s.finish(id, "rolled-back")
+}
+
+const rollbackTimeout = 5 * time.Minute
+
+func (s *rolloutService) rollbackAgent(agentID, previous string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	since := time.Now()
+	jobID, err := s.agentManager.EnqueueCommand(ctx, agentID, agent.AgentCommand{
+		Command: RollbackCommand,
+		Args:    []string{previous},
+		Timeout: timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Like an update, the rollback restarts the agent and may never report
+	// the job, so a heartbeat on the previous version counts too
+	result := make(chan error, 1)
+	go func() {
+		_, err := s.agentManager.WaitForJob(ctx, jobID)
+		result <- err
+	}()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-result:
+			if ctx.Err() != nil {
+				return fmt.Errorf("no heartbeat on version %s within %s", previous, timeout)
+			}
+			return err
+		case <-ticker.C:
+			a, exists := s.agentManager.GetAgent(agentID)
+			if exists && a.Version == previous && a.LastHeartbeat.After(since) {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *rolloutService) finish(id, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rollout := s.rollouts[id]
+	if rollout.Status != "running" {
+		return
+	}
+	rollout.Status = status
+	rollout.CompletedAt = time.Now()
+	if cancel, ok := s.cancelFuncs[id]; ok {
+		cancel()
+		delete(s.cancelFuncs, id)
+	}
+}
+
+func copyRollout(r *Rollout) *Rollout {
+	c := *r
+	c.Waves = append([]Wave(nil), r.Waves...)
+	c.Agents = make(map[string]*AgentRollout, len(r.Agents))
+	for id, state := range r.Agents {
+		s := *state
+		c.Agents[id] = &s
+	}
+	return &c
+}
@@ -25,7 +25,11 @@ func (s *Server) handleRefreshToken(c *gin.Context) {
 
 func (s *Server) listAgents(c *gin.Context) {
 	agents := s.agentManager.ListAgents()
-	c.JSON(http.StatusOK, gin.H{"agents": agents})
+	views := make([]agentView, 0, len(agents))
+	for _, a := range agents {
+		views = append(views, s.viewAgent(a))
+	}
+	c.JSON(http.StatusOK, gin.H{"agents": views})
 }
 
 func (s *Server) registerAgent(c *gin.Context) {
@@ -46,7 +50,7 @@ func (s *Server) getAgent(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"agent": agent})
+	c.JSON(http.StatusOK, gin.H{"agent": s.viewAgent(agent)})
 }
 
 func (s *Server) runCommand(c *gin.Context) {
@@ -56,19 +60,27 @@ func (s *Server) runCommand(c *gin.Context) {
 
 func (s *Server) getAgentStats(c *gin.Context) {
 	agentID := c.Param("id")
-	agent, exists := s.agentManager.GetAgent(agentID)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+	stats, err := s.agentManager.GetStats(agentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	stats, err := agent.CollectStats()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+func (s *Server) reportAgentStats(c *gin.Context) {
+	var stats agent.AgentStats
+	if err := c.ShouldBindJSON(&stats); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"stats": stats})
+	if err := s.agentManager.ReportStats(c.Param("id"), &stats); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "recorded"})
 }
 
 // ... other handler implementations would follow the same pattern
\ No newline at end of file
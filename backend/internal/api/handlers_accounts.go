@@ -0,0 +1,180 @@
+// backend/internal/api/handlers_accounts.go
+package api
+
+import (
+	"net/http"
+
+	"github.com/autosysadmin/backend/internal/accounts"
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listManagedUsers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"users": s.accountService.ListUsers()})
+}
+
+func (s *Server) declareManagedUser(c *gin.Context) {
+	var user accounts.ManagedUser
+	if err := c.ShouldBindJSON(&user); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.accountService.DeclareUser(user)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"user": saved})
+}
+
+func (s *Server) deleteManagedUser(c *gin.Context) {
+	if err := s.accountService.DeleteUser(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listManagedGroups(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"groups": s.accountService.ListGroups()})
+}
+
+func (s *Server) declareManagedGroup(c *gin.Context) {
+	var group accounts.ManagedGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.accountService.DeclareGroup(group)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"group": saved})
+}
+
+func (s *Server) deleteManagedGroup(c *gin.Context) {
+	if err := s.accountService.DeleteGroup(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listSudoRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"sudo_rules": s.accountService.ListSudoRules()})
+}
+
+func (s *Server) declareSudoRule(c *gin.Context) {
+	var rule accounts.SudoRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.accountService.DeclareSudoRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"sudo_rule": saved})
+}
+
+func (s *Server) deleteSudoRule(c *gin.Context) {
+	if err := s.accountService.DeleteSudoRule(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) applyAccounts(c *gin.Context) {
+	var req struct {
+		Selector agent.Selector `json:"selector"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Refuse to act on the whole fleet by accident
+	if req.Selector.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selector is required"})
+		return
+	}
+
+	results := s.accountService.Apply(c, req.Selector)
+	c.JSON(http.StatusAccepted, gin.H{"results": results})
+}
+
+func (s *Server) listAccountDrift(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"drift": s.accountService.ListDrift()})
+}
+
+func (s *Server) offboardUser(c *gin.Context) {
+	var req accounts.OffboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	req.By = c.GetString("userID")
+
+	offboarding, err := s.accountService.Offboard(c, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"offboarding": offboarding})
+}
+
+func (s *Server) getOffboarding(c *gin.Context) {
+	offboarding, err := s.accountService.GetOffboarding(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"offboarding": offboarding})
+}
+
+func (s *Server) getAgentAccountPlan(c *gin.Context) {
+	plan, err := s.accountService.PlanForAgent(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"plan": plan})
+}
+
+func (s *Server) reportAgentAccounts(c *gin.Context) {
+	var report agent.AccountReport
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	drift, err := s.accountService.ReportAccounts(c.Param("id"), report)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"drift": drift})
+}
+
+func (s *Server) scanAgentAccounts(c *gin.Context) {
+	drift, err := s.accountService.Scan(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"drift": drift})
+}
+
+func (s *Server) getAgentAccountDrift(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"drift": s.accountService.GetDrift(c.Param("id"))})
+}
@@ -0,0 +1,132 @@
+// backend/internal/api/handlers_agentupdate.go
+package api
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+
+	"github.com/autosysadmin/backend/internal/agentupdate"
+	"github.com/gin-gonic/gin"
+)
+
+const maxReleaseSize = 200 * 1024 * 1024 // 200MB
+
+func (s *Server) agentHeartbeat(c *gin.Context) {
+	var req struct {
+		Version string `json:"version"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.agentManager.Heartbeat(c.Param("id"), req.Version); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (s *Server) uploadAgentRelease(c *gin.Context) {
+	var req struct {
+		Version string `form:"version" binding:"required"`
+		OS      string `form:"os" binding:"required"`
+		Arch    string `form:"arch" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > maxReleaseSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "release binary too large"})
+		return
+	}
+
+	f, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	binary, err := io.ReadAll(io.LimitReader(f, maxReleaseSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	release, err := s.releaseStore.AddRelease(req.Version, req.OS, req.Arch, binary)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"release": release})
+}
+
+func (s *Server) listAgentReleases(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"releases":   s.releaseStore.ListReleases(),
+		"public_key": base64.StdEncoding.EncodeToString(s.releaseStore.PublicKey()),
+	})
+}
+
+func (s *Server) downloadAgentRelease(c *gin.Context) {
+	release, err := s.releaseStore.GetRelease(c.Param("version"), c.Param("os"), c.Param("arch"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	path, err := s.releaseStore.ArtifactPath(release.Version, release.OS, release.Arch)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("X-Content-SHA256", release.SHA256)
+	c.Header("X-Release-Signature", release.Signature)
+	c.FileAttachment(path, "autosysadmin-agent")
+}
+
+func (s *Server) startRollout(c *gin.Context) {
+	var req agentupdate.RolloutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rollout, err := s.rolloutService.StartRollout(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"rollout": rollout})
+}
+
+func (s *Server) listRollouts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rollouts": s.rolloutService.ListRollouts()})
+}
+
+func (s *Server) getRollout(c *gin.Context) {
+	rollout, err := s.rolloutService.GetRollout(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rollout": rollout})
+}
+
+func (s *Server) abortRollout(c *gin.Context) {
+	if err := s.rolloutService.AbortRollout(c.Param("id")); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "aborted"})
+}
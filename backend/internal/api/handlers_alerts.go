@@ -0,0 +1,209 @@
+// backend/internal/api/handlers_alerts.go
+package api
+
+import (
+	"net/http"
+
+	"github.com/autosysadmin/backend/internal/monitoring"
+	"github.com/gin-gonic/gin"
+)
+
+// listAlertStates shows which (rule, agent) pairs are pending or firing.
+func (s *Server) listAlertStates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"states": s.alertRuleEngine.States(c.Query("rule_id"))})
+}
+
+func (s *Server) listAlertRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rules": s.alertRuleEngine.ListRules()})
+}
+
+func (s *Server) getAlertRule(c *gin.Context) {
+	rule, err := s.alertRuleEngine.GetRule(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+func (s *Server) createAlertRule(c *gin.Context) {
+	var rule monitoring.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rule.ID != "" {
+		if _, err := s.alertRuleEngine.GetRule(rule.ID); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "alert rule already exists"})
+			return
+		}
+	}
+
+	saved, err := s.alertRuleEngine.AddRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"rule": saved})
+}
+
+func (s *Server) updateAlertRule(c *gin.Context) {
+	var rule monitoring.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.ID = c.Param("id")
+
+	if _, err := s.alertRuleEngine.GetRule(rule.ID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	saved, err := s.alertRuleEngine.UpdateRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rule": saved})
+}
+
+func (s *Server) deleteAlertRule(c *gin.Context) {
+	if err := s.alertRuleEngine.RemoveRule(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listAlertGroups(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"groups": s.alertManager.ListGroups()})
+}
+
+func (s *Server) listSilences(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"silences": s.alertManager.ListSilences()})
+}
+
+func (s *Server) createSilence(c *gin.Context) {
+	var silence monitoring.Silence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	silence.CreatedBy = c.GetString("userID")
+
+	saved, err := s.alertManager.AddSilence(silence)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"silence": saved})
+}
+
+// expireSilence ends a silence early; it stays listed as expired.
+func (s *Server) expireSilence(c *gin.Context) {
+	if err := s.alertManager.ExpireSilence(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listInhibitRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rules": s.alertManager.ListInhibitRules()})
+}
+
+func (s *Server) createInhibitRule(c *gin.Context) {
+	var rule monitoring.InhibitRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.alertManager.AddInhibitRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"rule": saved})
+}
+
+func (s *Server) deleteInhibitRule(c *gin.Context) {
+	if err := s.alertManager.RemoveInhibitRule(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listAlertDeliveries(c *gin.Context) {
+	if _, err := s.alertManager.GetAlert(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": s.alertManager.Deliveries(c.Param("id"))})
+}
+
+type notificationTemplate struct {
+	Channel  string `json:"channel"`
+	Template string `json:"template"`
+	Custom   bool   `json:"custom"`
+}
+
+func (s *Server) listNotificationTemplates(c *gin.Context) {
+	templates := make([]notificationTemplate, 0, len(monitoring.Channels))
+	for _, channel := range monitoring.Channels {
+		source, custom, _ := s.notificationTemplates.Template(channel)
+		templates = append(templates, notificationTemplate{Channel: channel, Template: source, Custom: custom})
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+func (s *Server) setNotificationTemplate(c *gin.Context) {
+	var req struct {
+		Template string `json:"template" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.notificationTemplates.SetTemplate(c.Param("channel"), req.Template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"template": notificationTemplate{Channel: c.Param("channel"), Template: req.Template, Custom: true}})
+}
+
+// resetNotificationTemplate goes back to the channel's default template.
+func (s *Server) resetNotificationTemplate(c *gin.Context) {
+	if err := s.notificationTemplates.SetTemplate(c.Param("channel"), ""); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// previewNotificationTemplate renders a template, or the channel's current
+// one, against the given alert or a sample one.
+func (s *Server) previewNotificationTemplate(c *gin.Context) {
+	var req struct {
+		Channel  string            `json:"channel" binding:"required"`
+		Template string            `json:"template"`
+		Alert    *monitoring.Alert `json:"alert"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	alert := monitoring.SampleAlert()
+	if req.Alert != nil {
+		alert = *req.Alert
+	}
+
+	rendered, err := s.notificationTemplates.Preview(req.Channel, req.Template, alert)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"channel": req.Channel, "rendered": rendered})
+}
@@ -0,0 +1,42 @@
+// backend/internal/api/handlers_containers.go
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listContainers(c *gin.Context) {
+	inv, err := s.containerService.Inventory(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"containers":   inv.Containers,
+		"images":       inv.Images,
+		"collected_at": inv.CollectedAt,
+	})
+}
+
+func (s *Server) controlContainer(c *gin.Context) {
+	result, err := s.containerService.Control(c, c.Param("id"), c.Param("container"), c.Param("action"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+func (s *Server) getContainerLogs(c *gin.Context) {
+	tail, _ := strconv.Atoi(c.DefaultQuery("tail", "100"))
+
+	lines, err := s.containerService.Logs(c, c.Param("id"), c.Param("container"), tail)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"logs": lines})
+}
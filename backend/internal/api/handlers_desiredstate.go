@@ -0,0 +1,105 @@
+// backend/internal/api/handlers_desiredstate.go
+package api
+
+import (
+	"net/http"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/desiredstate"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listManagedFiles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"files": s.desiredStateService.ListFiles()})
+}
+
+func (s *Server) declareManagedFile(c *gin.Context) {
+	var file desiredstate.ManagedFile
+	if err := c.ShouldBindJSON(&file); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.desiredStateService.DeclareFile(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"file": saved})
+}
+
+func (s *Server) deleteManagedFile(c *gin.Context) {
+	if err := s.desiredStateService.DeleteFile(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listManagedPackages(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"packages": s.desiredStateService.ListPackages()})
+}
+
+func (s *Server) declareManagedPackage(c *gin.Context) {
+	var pkg desiredstate.ManagedPackage
+	if err := c.ShouldBindJSON(&pkg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.desiredStateService.DeclarePackage(pkg)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"package": saved})
+}
+
+func (s *Server) deleteManagedPackage(c *gin.Context) {
+	if err := s.desiredStateService.DeletePackage(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listFleetDrift(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"drift": s.desiredStateService.ListDrift()})
+}
+
+func (s *Server) getAgentDesiredState(c *gin.Context) {
+	desired, err := s.desiredStateService.DesiredForAgent(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"desired": desired})
+}
+
+func (s *Server) reportAgentState(c *gin.Context) {
+	var report agent.StateReport
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	drift, err := s.desiredStateService.ReportState(c.Param("id"), report)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"drift": drift})
+}
+
+func (s *Server) getAgentDrift(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"drift": s.desiredStateService.GetDrift(c.Param("id"))})
+}
+
+func (s *Server) remediateAgentDrift(c *gin.Context) {
+	results, err := s.desiredStateService.Remediate(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"results": results})
+}
@@ -0,0 +1,137 @@
+// backend/internal/api/handlers_inventory.go
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/autosysadmin/backend/internal/agentupdate"
+	"github.com/autosysadmin/backend/internal/inventory"
+	"github.com/gin-gonic/gin"
+)
+
+const maxInventorySize = 32 << 20
+
+// importInventory takes the inventory file as the raw request body, or as
+// a multipart "file" field. The format comes from ?format=, the file
+// extension, or is detected from the content.
+func (s *Server) importInventory(c *gin.Context) {
+	format := c.Query("format")
+	var body io.Reader = c.Request.Body
+
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+		if format == "" {
+			format = formatFromFilename(file.Filename)
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxInventorySize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(data) > maxInventorySize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "inventory too large"})
+		return
+	}
+
+	result, err := s.inventoryService.Import(format, data, c.Query("dry_run") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusCreated
+	if result.DryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{"import": result})
+}
+
+func formatFromFilename(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml":
+		return inventory.FormatYAML
+	case ".csv":
+		return inventory.FormatCSV
+	case ".ini", ".cfg":
+		return inventory.FormatINI
+	}
+	return ""
+}
+
+func (s *Server) listInventoryImports(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"imports": s.inventoryService.ListImports()})
+}
+
+func (s *Server) getInventoryImport(c *gin.Context) {
+	result, err := s.inventoryService.GetImport(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"import": result})
+}
+
+func (s *Server) getAgentInventory(c *gin.Context) {
+	host, err := s.inventoryService.GetHost(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"host": host})
+}
+
+// enrollAgent is called by the installer on a host imported from an
+// inventory; it authenticates with the enrollment token, not a user login.
+func (s *Server) enrollAgent(c *gin.Context) {
+	var req inventory.EnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrolled, err := s.inventoryService.Enroll(req)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"agent": enrolled.Agent, "credential": enrolled.Credential}
+	if release := s.latestAgentRelease(req.OS, req.Architecture); release != nil {
+		response["release"] = release
+		response["download_path"] = fmt.Sprintf("/api/v1/agent-releases/%s/%s/%s/download", release.Version, release.OS, release.Arch)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// latestAgentRelease is the most recently uploaded release for a platform,
+// which a freshly enrolled host installs.
+func (s *Server) latestAgentRelease(osName, arch string) *agentupdate.Release {
+	var latest *agentupdate.Release
+	for _, r := range s.releaseStore.ListReleases() {
+		if r.OS != osName || r.Arch != arch {
+			continue
+		}
+		if latest == nil || r.UploadedAt.After(latest.UploadedAt) {
+			r := r
+			latest = &r
+		}
+	}
+	return latest
+}
+
+// installScript serves the installer the enrollment command pipes to sh.
+func (s *Server) installScript(c *gin.Context) {
+	c.Data(http.StatusOK, "text/x-shellscript; charset=utf-8", []byte(inventory.InstallScript))
+}
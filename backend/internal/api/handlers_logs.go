@@ -0,0 +1,185 @@
+// backend/internal/api/handlers_logs.go
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/logs"
+	"github.com/autosysadmin/backend/internal/monitoring"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) ingestLogs(c *gin.Context) {
+	var req struct {
+		Lines []logs.LogLine `json:"lines" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.logService.Ingest(c.Param("id"), req.Lines); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"accepted": len(req.Lines)})
+}
+
+func (s *Server) getLogConfig(c *gin.Context) {
+	cfg, err := s.logService.ConfigForAgent(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"config": cfg})
+}
+
+func (s *Server) searchLogs(c *gin.Context) {
+	q, err := parseLogQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines, err := s.logService.Search(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"lines": lines})
+}
+
+// tailLogs streams matching lines as server-sent events until the client
+// disconnects.
+func (s *Server) tailLogs(c *gin.Context) {
+	q, err := parseLogQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines, cancel, err := s.logService.Subscribe(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer cancel()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case line, ok := <-lines:
+			if !ok {
+				return false
+			}
+			c.SSEvent("log", line)
+		case <-keepalive.C:
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		return true
+	})
+}
+
+func (s *Server) listLogConfigs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"configs": s.logService.ListConfigs()})
+}
+
+func (s *Server) createLogConfig(c *gin.Context) {
+	var cfg logs.CollectionConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.logService.SetConfig(cfg)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"config": saved})
+}
+
+func (s *Server) deleteLogConfig(c *gin.Context) {
+	if err := s.logService.DeleteConfig(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseLogQuery(c *gin.Context) (logs.Query, error) {
+	q := logs.Query{
+		Contains: c.Query("q"),
+		Regex:    c.Query("regex"),
+		Unit:     c.Query("unit"),
+		Path:     c.Query("path"),
+		Selector: parseSelector(c),
+	}
+
+	var err error
+	if v := c.Query("start"); v != "" {
+		if q.Start, err = time.Parse(time.RFC3339, v); err != nil {
+			return q, err
+		}
+	}
+	if v := c.Query("end"); v != "" {
+		if q.End, err = time.Parse(time.RFC3339, v); err != nil {
+			return q, err
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// parseSelector reads an agent selector from comma-separated agent_ids and
+// tags query parameters.
+func parseSelector(c *gin.Context) agent.Selector {
+	var sel agent.Selector
+	if v := c.Query("agent_ids"); v != "" {
+		sel.AgentIDs = strings.Split(v, ",")
+	}
+	if v := c.Query("tags"); v != "" {
+		sel.Tags = strings.Split(v, ",")
+	}
+	return sel
+}
+
+func (s *Server) listLogAlertRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rules": s.logRuleEvaluator.ListRules()})
+}
+
+func (s *Server) createLogAlertRule(c *gin.Context) {
+	var rule monitoring.LogAlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.logRuleEvaluator.AddRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"rule": saved})
+}
+
+func (s *Server) deleteLogAlertRule(c *gin.Context) {
+	if err := s.logRuleEvaluator.RemoveRule(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,123 @@
+// backend/internal/api/handlers_maintenance.go
+package api
+
+import (
+	"net/http"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/maintenance"
+	"github.com/autosysadmin/backend/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// agentView is an agent as shown in listings, with its current
+// maintenance window when it has one.
+type agentView struct {
+	*agent.Agent
+	Maintenance *maintenance.Window `json:"maintenance,omitempty"`
+}
+
+func (s *Server) viewAgent(a *agent.Agent) agentView {
+	return agentView{Agent: a, Maintenance: s.maintenanceService.ActiveWindow(a.ID)}
+}
+
+func (s *Server) listMaintenanceWindows(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"windows": s.maintenanceService.ListWindows()})
+}
+
+func (s *Server) createMaintenanceWindow(c *gin.Context) {
+	var w maintenance.Window
+	if err := c.ShouldBindJSON(&w); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if w.Owner == "" {
+		w.Owner = c.GetString("userID")
+	}
+
+	created, err := s.maintenanceService.CreateWindow(w)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"window": created})
+}
+
+func (s *Server) getMaintenanceWindow(c *gin.Context) {
+	w, err := s.maintenanceService.GetWindow(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"window": w})
+}
+
+func (s *Server) endMaintenanceWindow(c *gin.Context) {
+	w, err := s.maintenanceService.EndWindow(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"window": w})
+}
+
+func (s *Server) deleteMaintenanceWindow(c *gin.Context) {
+	if err := s.maintenanceService.DeleteWindow(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listRecurringJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": s.schedulerService.ListJobs()})
+}
+
+func (s *Server) createRecurringJob(c *gin.Context) {
+	var job scheduler.RecurringJob
+	if err := c.ShouldBindJSON(&job); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := s.schedulerService.CreateJob(job)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"job": created})
+}
+
+func (s *Server) getRecurringJob(c *gin.Context) {
+	job, err := s.schedulerService.GetJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+func (s *Server) enableRecurringJob(c *gin.Context) {
+	s.setRecurringJobEnabled(c, true)
+}
+
+func (s *Server) disableRecurringJob(c *gin.Context) {
+	s.setRecurringJobEnabled(c, false)
+}
+
+func (s *Server) setRecurringJobEnabled(c *gin.Context, enabled bool) {
+	job, err := s.schedulerService.SetEnabled(c.Param("id"), enabled)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+func (s *Server) deleteRecurringJob(c *gin.Context) {
+	if err := s.schedulerService.DeleteJob(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
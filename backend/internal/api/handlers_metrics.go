@@ -0,0 +1,123 @@
+// backend/internal/api/handlers_metrics.go
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/tsdb"
+	"github.com/gin-gonic/gin"
+)
+
+// Points per series when a query doesn't give a step.
+const defaultQueryPoints = 120
+
+// getMetrics evaluates a metrics query, e.g.
+// ?query=percentile(95, cpu) by (env)&range=7d&step=1d
+func (s *Server) getMetrics(c *gin.Context) {
+	query := c.Query("query")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required"})
+		return
+	}
+	start, end, err := parseMetricsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	step := end.Sub(start) / defaultQueryPoints
+	if v := c.Query("step"); v != "" {
+		if step, err = tsdb.ParseDuration(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if step < time.Second {
+		step = time.Second
+	}
+
+	result, err := s.metricsDB.Exec(query, start, end, step.Truncate(time.Second))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// getAgentMetrics returns an agent's series, optionally one metric's, over
+// the range: raw samples while retained, rollups for older ranges.
+func (s *Server) getAgentMetrics(c *gin.Context) {
+	start, end, err := parseMetricsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	match := tsdb.Labels{"agent_id": c.Param("agent_id")}
+	if name := c.Query("name"); name != "" {
+		match[tsdb.MetricName] = name
+	}
+	res := s.metricsDB.ResolutionFor(start)
+	series, err := s.metricsDB.Query(match, start, end, res)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"series": series, "resolution": res.String()})
+}
+
+// listMetricSeries lists the label sets of the series matching a selector
+// such as ?match=cpu{env="prod"}.
+func (s *Server) listMetricSeries(c *gin.Context) {
+	match := c.Query("match")
+	if match == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match is required"})
+		return
+	}
+	matchers, err := tsdb.ParseSelector(match)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"series": s.metricsDB.SeriesLabels(matchers)})
+}
+
+// parseMetricsRange reads start and end (RFC3339 or unix seconds), with
+// end defaulting to now and start to end minus range, by default 1h.
+func parseMetricsRange(c *gin.Context) (time.Time, time.Time, error) {
+	end := time.Now()
+	if v := c.Query("end"); v != "" {
+		t, err := parseMetricsTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end: %w", err)
+		}
+		end = t
+	}
+
+	if v := c.Query("start"); v != "" {
+		start, err := parseMetricsTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start: %w", err)
+		}
+		return start, end, nil
+	}
+	window := time.Hour
+	if v := c.Query("range"); v != "" {
+		d, err := tsdb.ParseDuration(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid range: %w", err)
+		}
+		window = d
+	}
+	return end.Add(-window), end, nil
+}
+
+func parseMetricsTime(v string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.UnixMilli(int64(secs * 1000)), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
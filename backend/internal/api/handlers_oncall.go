@@ -0,0 +1,205 @@
+// backend/internal/api/handlers_oncall.go
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/oncall"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listSchedules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"schedules": s.oncallService.ListSchedules()})
+}
+
+func (s *Server) createSchedule(c *gin.Context) {
+	var schedule oncall.Schedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.oncallService.CreateSchedule(schedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"schedule": saved})
+}
+
+func (s *Server) getSchedule(c *gin.Context) {
+	schedule, err := s.oncallService.GetSchedule(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+func (s *Server) updateSchedule(c *gin.Context) {
+	var schedule oncall.Schedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	schedule.ID = c.Param("id")
+
+	if _, err := s.oncallService.GetSchedule(schedule.ID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	saved, err := s.oncallService.UpdateSchedule(schedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedule": saved})
+}
+
+func (s *Server) deleteSchedule(c *gin.Context) {
+	if _, err := s.oncallService.GetSchedule(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.oncallService.DeleteSchedule(c.Param("id")); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// getOnCall answers who is on call now, or at the RFC 3339 time in "at".
+func (s *Server) getOnCall(c *gin.Context) {
+	at := time.Now()
+	if raw := c.Query("at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 time"})
+			return
+		}
+		at = parsed
+	}
+
+	user, err := s.oncallService.OnCall(c.Param("id"), at)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": user, "at": at})
+}
+
+func (s *Server) addOverride(c *gin.Context) {
+	var override oncall.Override
+	if err := c.ShouldBindJSON(&override); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := s.oncallService.GetSchedule(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.oncallService.AddOverride(c.Param("id"), override)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"override": saved})
+}
+
+func (s *Server) removeOverride(c *gin.Context) {
+	if err := s.oncallService.RemoveOverride(c.Param("id"), c.Param("override_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listEscalationPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"policies": s.oncallService.ListPolicies()})
+}
+
+// createEscalationPolicy returns the receiver name alert rules use to
+// route to the new policy.
+func (s *Server) createEscalationPolicy(c *gin.Context) {
+	var policy oncall.EscalationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.oncallService.CreatePolicy(policy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"policy": saved, "receiver": oncall.ReceiverName(saved.ID)})
+}
+
+func (s *Server) getEscalationPolicy(c *gin.Context) {
+	policy, err := s.oncallService.GetPolicy(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policy": policy, "receiver": oncall.ReceiverName(policy.ID)})
+}
+
+func (s *Server) updateEscalationPolicy(c *gin.Context) {
+	var policy oncall.EscalationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy.ID = c.Param("id")
+
+	if _, err := s.oncallService.GetPolicy(policy.ID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	saved, err := s.oncallService.UpdatePolicy(policy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policy": saved})
+}
+
+func (s *Server) deleteEscalationPolicy(c *gin.Context) {
+	if err := s.oncallService.DeletePolicy(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listEscalations(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+	c.JSON(http.StatusOK, gin.H{"escalations": s.oncallService.ListEscalations(activeOnly)})
+}
+
+func (s *Server) getEscalation(c *gin.Context) {
+	escalation, err := s.oncallService.GetEscalation(c.Param("alert_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"escalation": escalation})
+}
+
+// acknowledgeAlert acknowledges an alert as the current user, halting its
+// escalation.
+func (s *Server) acknowledgeAlert(c *gin.Context) {
+	if _, err := s.alertManager.GetAlert(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	alert, err := s.oncallService.Acknowledge(c.Param("id"), c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"alert": alert})
+}
@@ -0,0 +1,116 @@
+// backend/internal/api/handlers_process.go
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/autosysadmin/backend/internal/monitoring"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listProcesses(c *gin.Context) {
+	live := c.Query("live") == "true"
+
+	procs, err := s.processService.ListProcesses(c, c.Param("id"), live)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"processes": procs})
+}
+
+func (s *Server) signalProcess(c *gin.Context) {
+	pid, err := strconv.Atoi(c.Param("pid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pid"})
+		return
+	}
+	var req struct {
+		Signal string `json:"signal" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := s.processService.Signal(c, c.Param("id"), pid, req.Signal)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+func (s *Server) killProcessTree(c *gin.Context) {
+	pid, err := strconv.Atoi(c.Param("pid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pid"})
+		return
+	}
+	var req struct {
+		Signal string `json:"signal"` // defaults to TERM
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := s.processService.KillTree(c, c.Param("id"), pid, req.Signal)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+func (s *Server) reniceProcess(c *gin.Context) {
+	pid, err := strconv.Atoi(c.Param("pid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pid"})
+		return
+	}
+	var req struct {
+		Nice *int `json:"nice" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := s.processService.Renice(c, c.Param("id"), pid, *req.Nice)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+func (s *Server) listProcessWatchRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rules": s.processRuleEvaluator.ListRules()})
+}
+
+func (s *Server) createProcessWatchRule(c *gin.Context) {
+	var rule monitoring.ProcessWatchRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.processRuleEvaluator.AddRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"rule": saved})
+}
+
+func (s *Server) deleteProcessWatchRule(c *gin.Context) {
+	if err := s.processRuleEvaluator.RemoveRule(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
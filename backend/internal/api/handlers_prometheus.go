@@ -0,0 +1,428 @@
+// backend/internal/api/handlers_prometheus.go
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/api/middleware"
+	"github.com/autosysadmin/backend/internal/jobqueue"
+	"github.com/autosysadmin/backend/internal/tsdb"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	prometheusTextType  = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsTextType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Agent samples older than this are stale and not exported, so a gone
+// agent's series disappear from Prometheus.
+const agentMetricStaleness = 5 * time.Minute
+
+// Agent statuses exported as one series each, whatever the current one.
+var agentStatuses = []string{"online", "offline", "degraded"}
+
+type agentMetricMeta struct {
+	name string // exported name, without the autosysadmin_agent_ prefix
+	help string
+	typ  string
+}
+
+// Exported names and help for the metrics the monitor records; others are
+// exported as gauges under their own name.
+var agentMetrics = map[string]agentMetricMeta{
+	"cpu":                         {"cpu_usage_percent", "CPU usage in percent.", "gauge"},
+	"memory":                      {"memory_usage_percent", "Memory usage in percent.", "gauge"},
+	"disk":                        {"disk_usage_percent", "Disk usage in percent.", "gauge"},
+	"network_in":                  {"network_in", "Inbound network throughput as reported by the agent.", "gauge"},
+	"network_out":                 {"network_out", "Outbound network throughput as reported by the agent.", "gauge"},
+	"up":                          {"up", "Whether the agent's stats could be collected.", "gauge"},
+	"filesystem_usage":            {"filesystem_usage_percent", "Filesystem usage in percent, per mount.", "gauge"},
+	"network_interface_in_bytes":  {"network_interface_in_bytes_total", "Bytes received, per interface.", "counter"},
+	"network_interface_out_bytes": {"network_interface_out_bytes_total", "Bytes sent, per interface.", "counter"},
+}
+
+type promSample struct {
+	suffix string // _bucket, _sum and _count for histograms
+	labels map[string]string
+	value  float64
+	ts     time.Time // zero for "now"
+}
+
+type promFamily struct {
+	name    string // counters end in _total
+	help    string
+	typ     string // gauge, counter, histogram
+	samples []promSample
+}
+
+// prometheusMetrics serves fleet metrics in the Prometheus text format, or
+// in OpenMetrics when the scraper asks for it. As with federation, match[]
+// selectors such as {agent_id="a1"} or autosysadmin_agent_up limit the
+// output to matching series. When a metrics token is configured scrapes
+// must send it as a bearer token.
+func (s *Server) prometheusMetrics(c *gin.Context) {
+	if s.metricsToken != "" {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.metricsToken)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid metrics token"})
+			return
+		}
+	}
+
+	var selectors [][]*tsdb.Matcher
+	for _, match := range c.QueryArray("match[]") {
+		matchers, err := tsdb.ParseSelector(match)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		selectors = append(selectors, matchers)
+	}
+
+	openMetrics := strings.Contains(c.GetHeader("Accept"), "application/openmetrics-text")
+	var buf bytes.Buffer
+	writeExposition(&buf, s.collectMetrics(c.Request.Context()), selectors, openMetrics)
+	contentType := prometheusTextType
+	if openMetrics {
+		contentType = openMetricsTextType
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+func (s *Server) collectMetrics(ctx context.Context) []promFamily {
+	families := s.agentFamilies()
+	families = append(families, s.agentMetricFamilies()...)
+	families = append(families, s.jobQueueFamilies(ctx)...)
+	families = append(families, s.alertFamilies()...)
+	return append(families, s.requestFamilies()...)
+}
+
+func (s *Server) agentFamilies() []promFamily {
+	info := promFamily{name: "autosysadmin_agent_info", help: "Agent metadata; always 1.", typ: "gauge"}
+	status := promFamily{name: "autosysadmin_agent_status", help: "1 for the agent's current status, 0 for the others.", typ: "gauge"}
+	heartbeat := promFamily{name: "autosysadmin_agent_last_heartbeat_timestamp_seconds", help: "Unix time of the agent's last heartbeat.", typ: "gauge"}
+	counts := promFamily{name: "autosysadmin_agents", help: "Registered agents by status.", typ: "gauge"}
+
+	byStatus := make(map[string]int)
+	for _, st := range agentStatuses {
+		byStatus[st] = 0
+	}
+	for _, a := range s.agentManager.ListAgents() {
+		info.samples = append(info.samples, promSample{labels: map[string]string{
+			"agent_id": a.ID,
+			"hostname": a.Hostname,
+			"os":       a.OS,
+			"arch":     a.Architecture,
+			"version":  a.Version,
+		}, value: 1})
+
+		statuses := agentStatuses
+		if _, known := byStatus[a.Status]; !known {
+			statuses = append(append([]string(nil), agentStatuses...), a.Status)
+		}
+		for _, st := range statuses {
+			status.samples = append(status.samples, promSample{
+				labels: map[string]string{"agent_id": a.ID, "status": st},
+				value:  boolValue(a.Status == st),
+			})
+		}
+		byStatus[a.Status]++
+
+		if !a.LastHeartbeat.IsZero() {
+			heartbeat.samples = append(heartbeat.samples, promSample{
+				labels: map[string]string{"agent_id": a.ID},
+				value:  float64(a.LastHeartbeat.UnixMilli()) / 1000,
+			})
+		}
+	}
+	for st, n := range byStatus {
+		counts.samples = append(counts.samples, promSample{labels: map[string]string{"status": st}, value: float64(n)})
+	}
+	return []promFamily{counts, info, status, heartbeat}
+}
+
+// agentMetricFamilies exports the newest sample of every agent series in
+// the metrics store, with its labels and timestamp.
+func (s *Server) agentMetricFamilies() []promFamily {
+	byName := make(map[string]*promFamily)
+	var names []string
+	for _, sample := range s.metricsDB.Latest(nil, time.Now().Add(-agentMetricStaleness)) {
+		metric := sample.Labels[tsdb.MetricName]
+		meta, ok := agentMetrics[metric]
+		if !ok {
+			meta = agentMetricMeta{name: metric, help: fmt.Sprintf("Latest %s sample.", metric), typ: "gauge"}
+		}
+		name := "autosysadmin_agent_" + sanitizeMetricName(meta.name)
+
+		f, ok := byName[name]
+		if !ok {
+			f = &promFamily{name: name, help: meta.help, typ: meta.typ}
+			byName[name] = f
+			names = append(names, name)
+		}
+		labels := make(map[string]string, len(sample.Labels))
+		for k, v := range sample.Labels {
+			if k != tsdb.MetricName {
+				labels[k] = v
+			}
+		}
+		f.samples = append(f.samples, promSample{labels: labels, value: sample.Value, ts: sample.Time})
+	}
+
+	sort.Strings(names)
+	families := make([]promFamily, 0, len(names))
+	for _, name := range names {
+		families = append(families, *byName[name])
+	}
+	return families
+}
+
+func (s *Server) jobQueueFamilies(ctx context.Context) []promFamily {
+	queue, ok := s.jobQueue.(jobqueue.DepthReporter)
+	if !ok {
+		return nil
+	}
+	depths, err := queue.Depths(ctx)
+	if err != nil {
+		return nil
+	}
+	f := promFamily{
+		name: "autosysadmin_job_queue_depth",
+		help: "Unfinished agent jobs: queued ones waiting for an agent, running ones not yet reported back.",
+		typ:  "gauge",
+	}
+	for state, n := range depths {
+		f.samples = append(f.samples, promSample{labels: map[string]string{"state": state}, value: float64(n)})
+	}
+	return []promFamily{f}
+}
+
+func (s *Server) alertFamilies() []promFamily {
+	alerts := promFamily{name: "autosysadmin_alerts", help: "Active alerts by severity and state.", typ: "gauge"}
+	counts := make(map[[2]string]int)
+	for _, alert := range s.alertManager.GetActiveAlerts() {
+		state := "firing"
+		switch {
+		case alert.Suppressed:
+			state = "suppressed"
+		case alert.AcknowledgedAt != nil:
+			state = "acknowledged"
+		}
+		severity := alert.Severity
+		if severity == "" {
+			severity = "none"
+		}
+		counts[[2]string{severity, state}]++
+	}
+	for key, n := range counts {
+		alerts.samples = append(alerts.samples, promSample{
+			labels: map[string]string{"severity": key[0], "state": key[1]},
+			value:  float64(n),
+		})
+	}
+
+	now := time.Now()
+	silences := promFamily{name: "autosysadmin_alert_silences", help: "Silences in effect now.", typ: "gauge"}
+	active := 0
+	for _, silence := range s.alertManager.ListSilences() {
+		if silence.Active(now) {
+			active++
+		}
+	}
+	silences.samples = []promSample{{value: float64(active)}}
+
+	rules := promFamily{
+		name:    "autosysadmin_alert_rules",
+		help:    "Configured alert rules.",
+		typ:     "gauge",
+		samples: []promSample{{value: float64(len(s.alertRuleEngine.ListRules()))}},
+	}
+	return []promFamily{alerts, silences, rules}
+}
+
+func (s *Server) requestFamilies() []promFamily {
+	counts, durations := s.requestStats.Snapshot()
+
+	requests := promFamily{name: "autosysadmin_http_requests_total", help: "API requests by method, route and status code.", typ: "counter"}
+	for key, n := range counts {
+		requests.samples = append(requests.samples, promSample{
+			labels: map[string]string{"method": key.Method, "route": key.Route, "code": strconv.Itoa(key.Status)},
+			value:  float64(n),
+		})
+	}
+
+	latency := promFamily{name: "autosysadmin_http_request_duration_seconds", help: "API request latency by method and route.", typ: "histogram"}
+	for key, h := range durations {
+		var cumulative uint64
+		for i, bound := range middleware.DurationBuckets {
+			cumulative += h.Buckets[i]
+			latency.samples = append(latency.samples, promSample{
+				suffix: "_bucket",
+				labels: map[string]string{"method": key.Method, "route": key.Route, "le": formatValue(bound)},
+				value:  float64(cumulative),
+			})
+		}
+		labels := map[string]string{"method": key.Method, "route": key.Route}
+		latency.samples = append(latency.samples,
+			promSample{suffix: "_bucket", labels: map[string]string{"method": key.Method, "route": key.Route, "le": "+Inf"}, value: float64(h.Count)},
+			promSample{suffix: "_sum", labels: labels, value: h.Sum},
+			promSample{suffix: "_count", labels: labels, value: float64(h.Count)},
+		)
+	}
+	return []promFamily{requests, latency}
+}
+
+// writeExposition writes families with at least one sample matching any of
+// the selectors, or all of them without selectors.
+func writeExposition(buf *bytes.Buffer, families []promFamily, selectors [][]*tsdb.Matcher, openMetrics bool) {
+	for _, f := range families {
+		var samples []promSample
+		for _, sample := range f.samples {
+			if selected(f.name+sample.suffix, sample.labels, selectors) {
+				samples = append(samples, sample)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		sortSamples(samples)
+
+		// OpenMetrics names a counter family without _total and its
+		// samples with it; the Prometheus format uses _total throughout
+		family := f.name
+		if openMetrics && f.typ == "counter" {
+			family = strings.TrimSuffix(family, "_total")
+		}
+		fmt.Fprintf(buf, "# HELP %s %s\n", family, escapeHelp(f.help))
+		fmt.Fprintf(buf, "# TYPE %s %s\n", family, f.typ)
+		for _, sample := range samples {
+			buf.WriteString(f.name + sample.suffix)
+			writeLabels(buf, sample.labels)
+			buf.WriteByte(' ')
+			buf.WriteString(formatValue(sample.value))
+			if !sample.ts.IsZero() {
+				if openMetrics {
+					fmt.Fprintf(buf, " %s", strconv.FormatFloat(float64(sample.ts.UnixMilli())/1000, 'f', -1, 64))
+				} else {
+					fmt.Fprintf(buf, " %d", sample.ts.UnixMilli())
+				}
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+}
+
+func selected(name string, labels map[string]string, selectors [][]*tsdb.Matcher) bool {
+	if len(selectors) == 0 {
+		return true
+	}
+	for _, matchers := range selectors {
+		matches := true
+		for _, m := range matchers {
+			value := labels[m.Name]
+			if m.Name == tsdb.MetricName {
+				value = name
+			}
+			if !m.Matches(value) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func sortSamples(samples []promSample) {
+	keys := make([]string, len(samples))
+	for i, sample := range samples {
+		var b bytes.Buffer
+		b.WriteString(sample.suffix)
+		writeLabels(&b, sample.labels)
+		keys[i] = b.String()
+	}
+	sort.Sort(samplesByKey{samples, keys})
+}
+
+type samplesByKey struct {
+	samples []promSample
+	keys    []string
+}
+
+func (s samplesByKey) Len() int           { return len(s.samples) }
+func (s samplesByKey) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s samplesByKey) Swap(i, j int) {
+	s.samples[i], s.samples[j] = s.samples[j], s.samples[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func writeLabels(buf *bytes.Buffer, labels map[string]string) {
+	names := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, "%s=\"%s\"", sanitizeMetricName(name), labelValueEscaper.Replace(labels[name]))
+	}
+	buf.WriteByte('}')
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func sanitizeMetricName(name string) string {
+	return invalidNameChars.ReplaceAllString(name, "_")
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
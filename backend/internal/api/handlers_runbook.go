@@ -0,0 +1,123 @@
+// backend/internal/api/handlers_runbook.go
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/autosysadmin/backend/internal/runbook"
+	"github.com/gin-gonic/gin"
+)
+
+const maxRunbookSize = 1 << 20
+
+func (s *Server) listRunbooks(c *gin.Context) {
+	runbooks, err := s.runbookService.ListRunbooks(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runbooks": runbooks})
+}
+
+// createRunbook takes the runbook YAML as the raw request body.
+func (s *Server) createRunbook(c *gin.Context) {
+	source, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRunbookSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(source) > maxRunbookSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "runbook too large"})
+		return
+	}
+
+	rb, err := s.runbookService.CreateRunbook(c, source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"runbook": rb})
+}
+
+func (s *Server) getRunbook(c *gin.Context) {
+	rb, err := s.runbookService.GetRunbook(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runbook": rb})
+}
+
+func (s *Server) deleteRunbook(c *gin.Context) {
+	if err := s.runbookService.DeleteRunbook(c, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) executeRunbook(c *gin.Context) {
+	var req runbook.ExecuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Refuse to act on the whole fleet by accident
+	if req.Selector.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selector is required"})
+		return
+	}
+
+	exec, err := s.runbookService.Execute(c, c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"execution": exec})
+}
+
+func (s *Server) listRunbookExecutions(c *gin.Context) {
+	executions, err := s.runbookService.ListExecutions(c, c.Query("runbook_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"executions": executions})
+}
+
+func (s *Server) getRunbookExecution(c *gin.Context) {
+	exec, err := s.runbookService.GetExecution(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"execution": exec})
+}
+
+func (s *Server) approveRunbookStep(c *gin.Context) {
+	var req struct {
+		AgentID  string `json:"agent_id" binding:"required"`
+		Step     string `json:"step" binding:"required"`
+		Approved bool   `json:"approved"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.runbookService.Approve(c.Param("id"), req.AgentID, req.Step, c.GetString("userID"), req.Approved); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "recorded"})
+}
+
+func (s *Server) cancelRunbookExecution(c *gin.Context) {
+	if err := s.runbookService.Cancel(c.Param("id")); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"status": "cancelling"})
+}
@@ -0,0 +1,94 @@
+// backend/internal/api/handlers_scripts.go
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/autosysadmin/backend/internal/scripts"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listScripts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"scripts": s.scriptService.ListScripts()})
+}
+
+func (s *Server) createScript(c *gin.Context) {
+	var def scripts.ScriptDefinition
+	if err := c.ShouldBindJSON(&def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	script, err := s.scriptService.CreateScript(def)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"script": script})
+}
+
+// getScript returns the latest version unless ?version= is given.
+func (s *Server) getScript(c *gin.Context) {
+	version, _ := strconv.Atoi(c.Query("version"))
+	script, err := s.scriptService.GetScript(c.Param("id"), version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"script": script})
+}
+
+func (s *Server) deleteScript(c *gin.Context) {
+	if err := s.scriptService.DeleteScript(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) listScriptVersions(c *gin.Context) {
+	versions, err := s.scriptService.ListVersions(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+func (s *Server) addScriptVersion(c *gin.Context) {
+	var def scripts.ScriptDefinition
+	if err := c.ShouldBindJSON(&def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	script, err := s.scriptService.AddVersion(c.Param("id"), def)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"script": script})
+}
+
+func (s *Server) runScript(c *gin.Context) {
+	var req scripts.RunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.ScriptID = c.Param("id")
+
+	// Refuse to act on the whole fleet by accident
+	if req.Selector.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selector is required"})
+		return
+	}
+
+	results, err := s.scriptService.Run(c, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"results": results})
+}
@@ -0,0 +1,60 @@
+// backend/internal/api/handlers_systemd.go
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listServices(c *gin.Context) {
+	units, err := s.systemdService.ListUnits(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"services": units})
+}
+
+func (s *Server) controlService(c *gin.Context) {
+	result, err := s.systemdService.ControlUnit(c, c.Param("id"), c.Param("unit"), c.Param("action"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+func (s *Server) getServiceJournal(c *gin.Context) {
+	lines, _ := strconv.Atoi(c.DefaultQuery("lines", "100"))
+
+	entries, err := s.systemdService.GetJournal(c, c.Param("id"), c.Param("unit"), lines)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func (s *Server) controlFleetService(c *gin.Context) {
+	var req struct {
+		Selector agent.Selector `json:"selector"`
+		Unit     string         `json:"unit" binding:"required"`
+		Action   string         `json:"action" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Refuse to act on the whole fleet by accident
+	if req.Selector.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selector is required"})
+		return
+	}
+
+	results := s.systemdService.ControlFleet(c, req.Selector, req.Unit, req.Action)
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
@@ -0,0 +1,59 @@
+// backend/internal/api/handlers_topology.go
+package api
+
+import (
+	"net/http"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/topology"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) reportAgentConnections(c *gin.Context) {
+	var report agent.ConnectionReport
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.topologyService.Report(c.Param("id"), &report); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "recorded"})
+}
+
+func (s *Server) getAgentConnections(c *gin.Context) {
+	live := c.Query("live") == "true"
+
+	report, err := s.topologyService.Connections(c, c.Param("id"), live)
+	if err != nil {
+		status := http.StatusNotFound
+		if live {
+			status = http.StatusBadGateway
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"connections": report})
+}
+
+func (s *Server) getTopologyGraph(c *gin.Context) {
+	level := c.DefaultQuery("level", topology.LevelProcess)
+
+	graph, err := s.topologyService.Graph(parseSelector(c), level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"graph": graph})
+}
+
+func (s *Server) getTopologyImpact(c *gin.Context) {
+	impact, err := s.topologyService.Impact(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"impact": impact})
+}
@@ -0,0 +1,114 @@
+// backend/internal/api/handlers_transfer.go
+package api
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/autosysadmin/backend/internal/transfer"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) uploadFile(c *gin.Context) {
+	agentID := c.Param("id")
+
+	var req transfer.UploadRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	maxSize := s.transferService.MaxFileSize()
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+		return
+	}
+
+	f, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t, err := s.transferService.Upload(c, agentID, req, content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"transfer": t})
+}
+
+func (s *Server) downloadFile(c *gin.Context) {
+	agentID := c.Param("id")
+
+	var req struct {
+		Path string `json:"path" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t, err := s.transferService.Download(c, agentID, req.Path)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"transfer": t})
+}
+
+func (s *Server) listTransfers(c *gin.Context) {
+	agentID := c.Param("id")
+	c.JSON(http.StatusOK, gin.H{"transfers": s.transferService.ListTransfers(agentID)})
+}
+
+func (s *Server) getTransfer(c *gin.Context) {
+	t, err := s.transferService.GetTransfer(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"transfer": t})
+}
+
+func (s *Server) resumeTransfer(c *gin.Context) {
+	t, err := s.transferService.Resume(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"transfer": t})
+}
+
+func (s *Server) getTransferContent(c *gin.Context) {
+	t, err := s.transferService.GetTransfer(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, err := s.transferService.GetContent(t.ID)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+filepath.Base(t.Path)+"\"")
+	c.Header("X-Content-SHA256", t.SHA256)
+	c.Data(http.StatusOK, "application/octet-stream", content)
+}
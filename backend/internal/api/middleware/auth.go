@@ -33,4 +33,42 @@ func AuthMiddleware(authService auth.AuthService) gin.HandlerFunc {
 		c.Set("roles", claims.Roles)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// AgentAuthenticator resolves a credential issued to an agent at
+// enrollment to the agent's ID.
+type AgentAuthenticator interface {
+	VerifyAgentCredential(credential string) (string, bool)
+}
+
+// AgentOrUserAuth accepts a user access token, like AuthMiddleware, or an
+// agent credential. Agents may only call the routes listed in agentRoutes,
+// given as "METHOD /full/path", and only for their own :id.
+func AgentOrUserAuth(authService auth.AuthService, agents AgentAuthenticator, agentRoutes ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(agentRoutes))
+	for _, route := range agentRoutes {
+		allowed[route] = true
+	}
+	userAuth := AuthMiddleware(authService)
+
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		agentID, ok := agents.VerifyAgentCredential(tokenString)
+		if !ok {
+			userAuth(c)
+			return
+		}
+
+		if !allowed[c.Request.Method+" "+c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Route not available to agents"})
+			return
+		}
+		if id := c.Param("id"); id != "" && id != agentID {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Agents may only act for themselves"})
+			return
+		}
+
+		c.Set("agentID", agentID)
+		c.Next()
+	}
+}
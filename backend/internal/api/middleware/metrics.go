@@ -0,0 +1,111 @@
+// backend/internal/api/middleware/metrics.go
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DurationBuckets are the upper bounds, in seconds, of the request
+// duration histogram.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type RouteKey struct {
+	Method string
+	Route  string // the route pattern, e.g. /api/v1/agents/:id
+}
+
+type RequestKey struct {
+	RouteKey
+	Status int
+}
+
+// DurationHistogram counts requests per bucket in DurationBuckets, not
+// cumulatively; Count includes requests slower than the last bucket.
+type DurationHistogram struct {
+	Buckets []uint64
+	Count   uint64
+	Sum     float64 // seconds
+}
+
+// RequestStats counts API requests by method, route and status, and
+// their durations by method and route.
+type RequestStats struct {
+	counts    map[RequestKey]uint64
+	durations map[RouteKey]*DurationHistogram
+	mu        sync.Mutex
+}
+
+func NewRequestStats() *RequestStats {
+	return &RequestStats{
+		counts:    make(map[RequestKey]uint64),
+		durations: make(map[RouteKey]*DurationHistogram),
+	}
+}
+
+// RequestMetrics records every request in stats. Requests matching no
+// route share one "unmatched" route so scanners can't add a series per
+// path.
+func RequestMetrics(stats *RequestStats) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		stats.observe(requestMethod(c.Request.Method), route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+func requestMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+func (s *RequestStats) observe(method, route string, status int, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := RouteKey{Method: method, Route: route}
+	s.counts[RequestKey{RouteKey: key, Status: status}]++
+
+	h, ok := s.durations[key]
+	if !ok {
+		h = &DurationHistogram{Buckets: make([]uint64, len(DurationBuckets))}
+		s.durations[key] = h
+	}
+	seconds := d.Seconds()
+	for i, bound := range DurationBuckets {
+		if seconds <= bound {
+			h.Buckets[i]++
+			break
+		}
+	}
+	h.Count++
+	h.Sum += seconds
+}
+
+// Snapshot copies the current counts and histograms.
+func (s *RequestStats) Snapshot() (map[RequestKey]uint64, map[RouteKey]DurationHistogram) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[RequestKey]uint64, len(s.counts))
+	for k, v := range s.counts {
+		counts[k] = v
+	}
+	durations := make(map[RouteKey]DurationHistogram, len(s.durations))
+	for k, h := range s.durations {
+		durations[k] = DurationHistogram{Buckets: append([]uint64(nil), h.Buckets...), Count: h.Count, Sum: h.Sum}
+	}
+	return counts, durations
+}
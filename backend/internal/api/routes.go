@@ -5,7 +5,25 @@ import (
 	"github.com/autosysadmin/backend/internal/api/middleware"
 )
 
+// Routes an enrolled agent may call with its own credential, for its own
+// :id; everything else needs a user login.
+var agentRoutes = []string{
+	"POST /api/v1/agents/:id/heartbeat",
+	"POST /api/v1/agents/:id/stats",
+	"POST /api/v1/agents/:id/logs",
+	"GET /api/v1/agents/:id/logs/config",
+	"GET /api/v1/agents/:id/desired-state",
+	"POST /api/v1/agents/:id/state",
+	"POST /api/v1/agents/:id/connections",
+	"GET /api/v1/agents/:id/accounts",
+	"POST /api/v1/agents/:id/accounts/report",
+	"GET /api/v1/agent-releases/:version/:os/:arch/download",
+}
+
 func (s *Server) setupRoutes() {
+	// Request counts and latencies for /metrics, by route template
+	s.router.Use(middleware.RequestMetrics(s.requestStats))
+
 	// Public routes
 	public := s.router.Group("/api/v1")
 	{
@@ -15,11 +33,14 @@ func (s *Server) setupRoutes() {
 			authGroup.POST("/register", s.handleRegister)
 			authGroup.POST("/refresh", s.handleRefreshToken)
 		}
+
+		// Agents imported from an inventory enroll with a one-time token
+		public.POST("/enroll", s.enrollAgent)
 	}
 
 	// Protected routes (require authentication)
 	protected := s.router.Group("/api/v1")
-	protected.Use(middleware.AuthMiddleware(s.authService))
+	protected.Use(middleware.AgentOrUserAuth(s.authService, s.inventoryService, agentRoutes...))
 	{
 		// Agent routes
 		agentGroup := protected.Group("/agents")
@@ -29,8 +50,185 @@ func (s *Server) setupRoutes() {
 			agentGroup.GET("/:id", s.getAgent)
 			agentGroup.POST("/:id/command", s.runCommand)
 			agentGroup.GET("/:id/stats", s.getAgentStats)
+			agentGroup.POST("/:id/stats", s.reportAgentStats)
 			agentGroup.GET("/:id/updates", s.listAvailableUpdates)
 			agentGroup.POST("/:id/updates", s.applyUpdates)
+			agentGroup.POST("/:id/files/upload", s.uploadFile)
+			agentGroup.POST("/:id/files/download", s.downloadFile)
+			agentGroup.GET("/:id/files/transfers", s.listTransfers)
+			agentGroup.GET("/:id/services", s.listServices)
+			agentGroup.POST("/:id/services/:unit/:action", s.controlService)
+			agentGroup.GET("/:id/services/:unit/journal", s.getServiceJournal)
+			agentGroup.POST("/:id/logs", s.ingestLogs)
+			agentGroup.GET("/:id/logs/config", s.getLogConfig)
+			agentGroup.POST("/:id/heartbeat", s.agentHeartbeat)
+			agentGroup.GET("/:id/desired-state", s.getAgentDesiredState)
+			agentGroup.POST("/:id/state", s.reportAgentState)
+			agentGroup.GET("/:id/drift", s.getAgentDrift)
+			agentGroup.POST("/:id/drift/remediate", s.remediateAgentDrift)
+			agentGroup.GET("/:id/inventory", s.getAgentInventory)
+			agentGroup.GET("/:id/processes", s.listProcesses)
+			agentGroup.POST("/:id/processes/:pid/signal", s.signalProcess)
+			agentGroup.POST("/:id/processes/:pid/kill-tree", s.killProcessTree)
+			agentGroup.POST("/:id/processes/:pid/renice", s.reniceProcess)
+			agentGroup.GET("/:id/containers", s.listContainers)
+			agentGroup.POST("/:id/containers/:container/:action", s.controlContainer)
+			agentGroup.GET("/:id/containers/:container/logs", s.getContainerLogs)
+			agentGroup.POST("/:id/connections", s.reportAgentConnections)
+			agentGroup.GET("/:id/connections", s.getAgentConnections)
+			agentGroup.GET("/:id/accounts", s.getAgentAccountPlan)
+			agentGroup.POST("/:id/accounts/report", s.reportAgentAccounts)
+			agentGroup.POST("/:id/accounts/scan", s.scanAgentAccounts)
+			agentGroup.GET("/:id/accounts/drift", s.getAgentAccountDrift)
+		}
+
+		// Maintenance window and recurring job routes
+		maintenanceGroup := protected.Group("/maintenance/windows")
+		{
+			maintenanceGroup.GET("", s.listMaintenanceWindows)
+			maintenanceGroup.POST("", s.createMaintenanceWindow)
+			maintenanceGroup.GET("/:id", s.getMaintenanceWindow)
+			maintenanceGroup.POST("/:id/end", s.endMaintenanceWindow)
+			maintenanceGroup.DELETE("/:id", s.deleteMaintenanceWindow)
+		}
+		recurringGroup := protected.Group("/recurring-jobs")
+		{
+			recurringGroup.GET("", s.listRecurringJobs)
+			recurringGroup.POST("", s.createRecurringJob)
+			recurringGroup.GET("/:id", s.getRecurringJob)
+			recurringGroup.POST("/:id/enable", s.enableRecurringJob)
+			recurringGroup.POST("/:id/disable", s.disableRecurringJob)
+			recurringGroup.DELETE("/:id", s.deleteRecurringJob)
+		}
+
+		// Inventory import routes
+		inventoryGroup := protected.Group("/inventory")
+		{
+			inventoryGroup.POST("/import", s.importInventory)
+			inventoryGroup.GET("/imports", s.listInventoryImports)
+			inventoryGroup.GET("/imports/:id", s.getInventoryImport)
+		}
+
+		// Desired-state declarations
+		desiredGroup := protected.Group("/desired-state")
+		{
+			desiredGroup.GET("/files", s.listManagedFiles)
+			desiredGroup.POST("/files", s.declareManagedFile)
+			desiredGroup.DELETE("/files/:id", s.deleteManagedFile)
+			desiredGroup.GET("/packages", s.listManagedPackages)
+			desiredGroup.POST("/packages", s.declareManagedPackage)
+			desiredGroup.DELETE("/packages/:id", s.deleteManagedPackage)
+			desiredGroup.GET("/drift", s.listFleetDrift)
+		}
+
+		// Host account management routes
+		accountGroup := protected.Group("/accounts")
+		{
+			accountGroup.GET("/users", s.listManagedUsers)
+			accountGroup.POST("/users", s.declareManagedUser)
+			accountGroup.DELETE("/users/:id", s.deleteManagedUser)
+			accountGroup.GET("/groups", s.listManagedGroups)
+			accountGroup.POST("/groups", s.declareManagedGroup)
+			accountGroup.DELETE("/groups/:id", s.deleteManagedGroup)
+			accountGroup.GET("/sudo-rules", s.listSudoRules)
+			accountGroup.POST("/sudo-rules", s.declareSudoRule)
+			accountGroup.DELETE("/sudo-rules/:id", s.deleteSudoRule)
+			accountGroup.POST("/apply", s.applyAccounts)
+			accountGroup.GET("/drift", s.listAccountDrift)
+			accountGroup.POST("/offboard", s.offboardUser)
+			accountGroup.GET("/offboardings/:id", s.getOffboarding)
+		}
+
+		// On-call schedule and escalation routes
+		oncallGroup := protected.Group("/oncall")
+		{
+			oncallGroup.GET("/schedules", s.listSchedules)
+			oncallGroup.POST("/schedules", s.createSchedule)
+			oncallGroup.GET("/schedules/:id", s.getSchedule)
+			oncallGroup.PUT("/schedules/:id", s.updateSchedule)
+			oncallGroup.DELETE("/schedules/:id", s.deleteSchedule)
+			oncallGroup.GET("/schedules/:id/oncall", s.getOnCall)
+			oncallGroup.POST("/schedules/:id/overrides", s.addOverride)
+			oncallGroup.DELETE("/schedules/:id/overrides/:override_id", s.removeOverride)
+			oncallGroup.GET("/policies", s.listEscalationPolicies)
+			oncallGroup.POST("/policies", s.createEscalationPolicy)
+			oncallGroup.GET("/policies/:id", s.getEscalationPolicy)
+			oncallGroup.PUT("/policies/:id", s.updateEscalationPolicy)
+			oncallGroup.DELETE("/policies/:id", s.deleteEscalationPolicy)
+			oncallGroup.GET("/escalations", s.listEscalations)
+			oncallGroup.GET("/escalations/:alert_id", s.getEscalation)
+		}
+
+		// Agent release and rollout routes
+		releaseGroup := protected.Group("/agent-releases")
+		{
+			releaseGroup.GET("", s.listAgentReleases)
+			releaseGroup.POST("", s.uploadAgentRelease)
+			releaseGroup.GET("/:version/:os/:arch/download", s.downloadAgentRelease)
+		}
+		rolloutGroup := protected.Group("/rollouts")
+		{
+			rolloutGroup.GET("", s.listRollouts)
+			rolloutGroup.POST("", s.startRollout)
+			rolloutGroup.GET("/:id", s.getRollout)
+			rolloutGroup.POST("/:id/abort", s.abortRollout)
+		}
+
+		// Runbook routes
+		runbookGroup := protected.Group("/runbooks")
+		{
+			runbookGroup.GET("", s.listRunbooks)
+			runbookGroup.POST("", s.createRunbook)
+			runbookGroup.GET("/:id", s.getRunbook)
+			runbookGroup.DELETE("/:id", s.deleteRunbook)
+			runbookGroup.POST("/:id/execute", s.executeRunbook)
+		}
+		executionGroup := protected.Group("/runbook-executions")
+		{
+			executionGroup.GET("", s.listRunbookExecutions)
+			executionGroup.GET("/:id", s.getRunbookExecution)
+			executionGroup.POST("/:id/approve", s.approveRunbookStep)
+			executionGroup.POST("/:id/cancel", s.cancelRunbookExecution)
+		}
+
+		// Script library routes
+		scriptGroup := protected.Group("/scripts")
+		{
+			scriptGroup.GET("", s.listScripts)
+			scriptGroup.POST("", s.createScript)
+			scriptGroup.GET("/:id", s.getScript)
+			scriptGroup.DELETE("/:id", s.deleteScript)
+			scriptGroup.GET("/:id/versions", s.listScriptVersions)
+			scriptGroup.POST("/:id/versions", s.addScriptVersion)
+			scriptGroup.POST("/:id/run", s.runScript)
+		}
+
+		// Network topology routes
+		topologyGroup := protected.Group("/topology")
+		{
+			topologyGroup.GET("/graph", s.getTopologyGraph)
+			topologyGroup.GET("/impact/:id", s.getTopologyImpact)
+		}
+
+		// Fleet-wide service management
+		protected.POST("/services/fleet", s.controlFleetService)
+
+		// Log search and collection routes
+		logGroup := protected.Group("/logs")
+		{
+			logGroup.GET("", s.searchLogs)
+			logGroup.GET("/tail", s.tailLogs)
+			logGroup.GET("/configs", s.listLogConfigs)
+			logGroup.POST("/configs", s.createLogConfig)
+			logGroup.DELETE("/configs/:id", s.deleteLogConfig)
+		}
+
+		// File transfer routes
+		transferGroup := protected.Group("/transfers")
+		{
+			transferGroup.GET("/:id", s.getTransfer)
+			transferGroup.POST("/:id/resume", s.resumeTransfer)
+			transferGroup.GET("/:id/content", s.getTransferContent)
 		}
 
 		// Monitoring routes
@@ -39,8 +237,34 @@ func (s *Server) setupRoutes() {
 			monitorGroup.GET("/", s.getMonitoringDashboard)
 			monitorGroup.GET("/alerts", s.listAlerts)
 			monitorGroup.POST("/alerts", s.createAlert)
+			monitorGroup.POST("/alerts/:id/ack", s.acknowledgeAlert)
+			monitorGroup.GET("/alerts/:id/deliveries", s.listAlertDeliveries)
 			monitorGroup.GET("/metrics", s.getMetrics)
 			monitorGroup.GET("/metrics/:agent_id", s.getAgentMetrics)
+			monitorGroup.GET("/series", s.listMetricSeries)
+			monitorGroup.GET("/log-rules", s.listLogAlertRules)
+			monitorGroup.POST("/log-rules", s.createLogAlertRule)
+			monitorGroup.DELETE("/log-rules/:id", s.deleteLogAlertRule)
+			monitorGroup.GET("/alert-states", s.listAlertStates)
+			monitorGroup.GET("/alert-rules", s.listAlertRules)
+			monitorGroup.POST("/alert-rules", s.createAlertRule)
+			monitorGroup.GET("/alert-rules/:id", s.getAlertRule)
+			monitorGroup.PUT("/alert-rules/:id", s.updateAlertRule)
+			monitorGroup.DELETE("/alert-rules/:id", s.deleteAlertRule)
+			monitorGroup.GET("/alert-groups", s.listAlertGroups)
+			monitorGroup.GET("/notification-templates", s.listNotificationTemplates)
+			monitorGroup.POST("/notification-templates/preview", s.previewNotificationTemplate)
+			monitorGroup.PUT("/notification-templates/:channel", s.setNotificationTemplate)
+			monitorGroup.DELETE("/notification-templates/:channel", s.resetNotificationTemplate)
+			monitorGroup.GET("/silences", s.listSilences)
+			monitorGroup.POST("/silences", s.createSilence)
+			monitorGroup.DELETE("/silences/:id", s.expireSilence)
+			monitorGroup.GET("/inhibit-rules", s.listInhibitRules)
+			monitorGroup.POST("/inhibit-rules", s.createInhibitRule)
+			monitorGroup.DELETE("/inhibit-rules/:id", s.deleteInhibitRule)
+			monitorGroup.GET("/process-rules", s.listProcessWatchRules)
+			monitorGroup.POST("/process-rules", s.createProcessWatchRule)
+			monitorGroup.DELETE("/process-rules/:id", s.deleteProcessWatchRule)
 		}
 
 		// Security routes
@@ -78,6 +302,12 @@ func (s *Server) setupRoutes() {
 
 	// Health check route
 	s.router.GET("/health", s.healthCheck)
+
+	// Installer the inventory enrollment command pipes to sh
+	s.router.GET("/install.sh", s.installScript)
+
+	// Prometheus scrape endpoint
+	s.router.GET("/metrics", s.prometheusMetrics)
 }
 
 func (s *Server) healthCheck(c *gin.Context) {
@@ -7,29 +7,72 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/autosysadmin/backend/internal/accounts"
 	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/agentupdate"
+	"github.com/autosysadmin/backend/internal/api/middleware"
 	"github.com/autosysadmin/backend/internal/auth"
 	"github.com/autosysadmin/backend/internal/billing"
+	"github.com/autosysadmin/backend/internal/containers"
+	"github.com/autosysadmin/backend/internal/desiredstate"
+	"github.com/autosysadmin/backend/internal/inventory"
+	"github.com/autosysadmin/backend/internal/jobqueue"
+	"github.com/autosysadmin/backend/internal/logs"
+	"github.com/autosysadmin/backend/internal/maintenance"
 	"github.com/autosysadmin/backend/internal/monitoring"
+	"github.com/autosysadmin/backend/internal/oncall"
 	"github.com/autosysadmin/backend/internal/patching"
+	"github.com/autosysadmin/backend/internal/process"
+	"github.com/autosysadmin/backend/internal/runbook"
+	"github.com/autosysadmin/backend/internal/scheduler"
+	"github.com/autosysadmin/backend/internal/scripts"
 	"github.com/autosysadmin/backend/internal/security"
 	"github.com/autosysadmin/backend/internal/subscriptions"
+	"github.com/autosysadmin/backend/internal/systemd"
+	"github.com/autosysadmin/backend/internal/topology"
+	"github.com/autosysadmin/backend/internal/transfer"
+	"github.com/autosysadmin/backend/internal/tsdb"
 	"github.com/autosysadmin/backend/internal/usage"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/sync/errgroup"
 )
 
 type Server struct {
-	router            *gin.Engine
-	httpServer        *http.Server
-	authService       auth.AuthService
-	agentManager      *agent.Manager
-	monitoringService monitoring.Monitor
-	patchingService   patching.PatchManager
-	securityScanner   security.VulnerabilityScanner
-	billingService    billing.BillingService
-	subscriptionService subscriptions.Service
-	usageTracker      usage.Tracker
+	router                *gin.Engine
+	httpServer            *http.Server
+	authService           auth.AuthService
+	agentManager          *agent.Manager
+	monitoringService     monitoring.Monitor
+	patchingService       patching.PatchManager
+	securityScanner       security.VulnerabilityScanner
+	billingService        billing.BillingService
+	subscriptionService   subscriptions.Service
+	usageTracker          usage.Tracker
+	transferService       transfer.Service
+	systemdService        systemd.Service
+	logService            logs.Service
+	logRuleEvaluator      *monitoring.LogRuleEvaluator
+	releaseStore          agentupdate.ReleaseStore
+	rolloutService        agentupdate.RolloutService
+	desiredStateService   desiredstate.Service
+	runbookService        runbook.Service
+	scriptService         scripts.Service
+	inventoryService      inventory.Service
+	maintenanceService    maintenance.Service
+	schedulerService      scheduler.Service
+	processService        process.Service
+	processRuleEvaluator  *monitoring.ProcessRuleEvaluator
+	containerService      containers.Service
+	topologyService       topology.Service
+	accountService        accounts.Service
+	alertRuleEngine       *monitoring.RuleEngine
+	alertManager          *monitoring.AlertManager
+	oncallService         oncall.Service
+	notificationTemplates *monitoring.TemplateRenderer
+	metricsDB             *tsdb.DB
+	jobQueue              jobqueue.JobQueue
+	metricsToken          string
+	requestStats          *middleware.RequestStats
 }
 
 func NewServer(
@@ -41,18 +84,67 @@ func NewServer(
 	billingService billing.BillingService,
 	subscriptionService subscriptions.Service,
 	usageTracker usage.Tracker,
+	transferService transfer.Service,
+	systemdService systemd.Service,
+	logService logs.Service,
+	logRuleEvaluator *monitoring.LogRuleEvaluator,
+	releaseStore agentupdate.ReleaseStore,
+	rolloutService agentupdate.RolloutService,
+	desiredStateService desiredstate.Service,
+	runbookService runbook.Service,
+	scriptService scripts.Service,
+	inventoryService inventory.Service,
+	maintenanceService maintenance.Service,
+	schedulerService scheduler.Service,
+	processService process.Service,
+	processRuleEvaluator *monitoring.ProcessRuleEvaluator,
+	containerService containers.Service,
+	topologyService topology.Service,
+	accountService accounts.Service,
+	alertRuleEngine *monitoring.RuleEngine,
+	alertManager *monitoring.AlertManager,
+	oncallService oncall.Service,
+	notificationTemplates *monitoring.TemplateRenderer,
+	metricsDB *tsdb.DB,
+	jobQueue jobqueue.JobQueue,
+	metricsToken string,
 ) *Server {
 	router := gin.Default()
 	server := &Server{
-		router:            router,
-		authService:       authService,
-		agentManager:      agentManager,
-		monitoringService: monitoringService,
-		patchingService:   patchingService,
-		securityScanner:   securityScanner,
-		billingService:    billingService,
-		subscriptionService: subscriptionService,
-		usageTracker:      usageTracker,
+		router:                router,
+		authService:           authService,
+		agentManager:          agentManager,
+		monitoringService:     monitoringService,
+		patchingService:       patchingService,
+		securityScanner:       securityScanner,
+		billingService:        billingService,
+		subscriptionService:   subscriptionService,
+		usageTracker:          usageTracker,
+		transferService:       transferService,
+		systemdService:        systemdService,
+		logService:            logService,
+		logRuleEvaluator:      logRuleEvaluator,
+		releaseStore:          releaseStore,
+		rolloutService:        rolloutService,
+		desiredStateService:   desiredStateService,
+		runbookService:        runbookService,
+		scriptService:         scriptService,
+		inventoryService:      inventoryService,
+		maintenanceService:    maintenanceService,
+		schedulerService:      schedulerService,
+		processService:        processService,
+		processRuleEvaluator:  processRuleEvaluator,
+		containerService:      containerService,
+		topologyService:       topologyService,
+		accountService:        accountService,
+		alertRuleEngine:       alertRuleEngine,
+		alertManager:          alertManager,
+		oncallService:         oncallService,
+		notificationTemplates: notificationTemplates,
+		metricsDB:             metricsDB,
+		jobQueue:              jobQueue,
+		metricsToken:          metricsToken,
+		requestStats:          middleware.NewRequestStats(),
 	}
 
 	server.setupRoutes()
@@ -147,4 +239,4 @@ func (s *Server) Stop() {
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
-}
\ No newline at end of file
+}
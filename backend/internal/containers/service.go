@@ -0,0 +1,111 @@
+// backend/internal/containers/service.go
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type Service interface {
+	// Inventory asks the agent for its containers, images and per-container
+	// stats from the local Docker Engine.
+	Inventory(ctx context.Context, agentID string) (*agent.DockerInventory, error)
+	Control(ctx context.Context, agentID, container, action string) (*ActionResult, error)
+	Logs(ctx context.Context, agentID, container string, tail int) ([]agent.ContainerLogLine, error)
+}
+
+type ActionResult struct {
+	AgentID   string `json:"agent_id"`
+	Container string `json:"container"`
+	Action    string `json:"action"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+const (
+	defaultLogLines = 100
+	maxLogLines     = 10000
+)
+
+type service struct {
+	agentManager *agent.Manager
+	timeout      time.Duration
+}
+
+func NewService(agentManager *agent.Manager) Service {
+	return &service{
+		agentManager: agentManager,
+		timeout:      2 * time.Minute,
+	}
+}
+
+func (s *service) Inventory(ctx context.Context, agentID string) (*agent.DockerInventory, error) {
+	output, err := s.run(ctx, agentID, agent.DockerInventoryCommand)
+	if err != nil {
+		return nil, err
+	}
+	var inv agent.DockerInventory
+	if err := json.Unmarshal([]byte(output), &inv); err != nil {
+		return nil, fmt.Errorf("failed to parse container inventory: %w", err)
+	}
+	return &inv, nil
+}
+
+func (s *service) Control(ctx context.Context, agentID, container, action string) (*ActionResult, error) {
+	if !agent.DockerContainerActions[action] {
+		return nil, fmt.Errorf("unsupported action %q", action)
+	}
+	if !agent.ValidContainerRef(container) {
+		return nil, fmt.Errorf("invalid container %q", container)
+	}
+
+	result := &ActionResult{AgentID: agentID, Container: container, Action: action}
+	if _, err := s.run(ctx, agentID, agent.DockerContainerCommand, action, container); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+	}
+	return result, nil
+}
+
+func (s *service) Logs(ctx context.Context, agentID, container string, tail int) ([]agent.ContainerLogLine, error) {
+	if !agent.ValidContainerRef(container) {
+		return nil, fmt.Errorf("invalid container %q", container)
+	}
+	if tail <= 0 {
+		tail = defaultLogLines
+	}
+	if tail > maxLogLines {
+		tail = maxLogLines
+	}
+
+	output, err := s.run(ctx, agentID, agent.DockerLogsCommand, container, strconv.Itoa(tail))
+	if err != nil {
+		return nil, err
+	}
+	var lines []agent.ContainerLogLine
+	if err := json.Unmarshal([]byte(output), &lines); err != nil {
+		return nil, fmt.Errorf("failed to parse container logs: %w", err)
+	}
+	return lines, nil
+}
+
+func (s *service) run(ctx context.Context, agentID, command string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	job, err := s.agentManager.RunCommandAndWait(ctx, agentID, agent.AgentCommand{
+		Command: command,
+		Args:    args,
+		Timeout: s.timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	return job.Result, nil
+}
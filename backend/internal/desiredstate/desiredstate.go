@@ -0,0 +1,74 @@
+// backend/internal/desiredstate/desiredstate.go
+package desiredstate
+
+import (
+	"context"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+// PackageEnsureCommand is the job an agent runs to install, upgrade or
+// remove a package. Args: name, version (may be empty), state.
+const PackageEnsureCommand = "pkg.ensure"
+
+type Service interface {
+	DeclareFile(file ManagedFile) (*ManagedFile, error)
+	DeclarePackage(pkg ManagedPackage) (*ManagedPackage, error)
+	DeleteFile(id string) error
+	DeletePackage(id string) error
+	ListFiles() []ManagedFile
+	ListPackages() []ManagedPackage
+	DesiredForAgent(agentID string) (*AgentDesiredState, error)
+	ReportState(agentID string, report agent.StateReport) ([]Drift, error)
+	GetDrift(agentID string) []Drift
+	ListDrift() map[string][]Drift
+	Remediate(ctx context.Context, agentID string) ([]RemediationResult, error)
+}
+
+// ManagedFile declares that Path on every matching agent must have exactly
+// this content, mode and ownership.
+type ManagedFile struct {
+	ID       string         `json:"id"`
+	Selector agent.Selector `json:"selector"`
+	Path     string         `json:"path"`
+	Content  string         `json:"content"`
+	Mode     string         `json:"mode"` // octal, e.g. 0644
+	Owner    string         `json:"owner,omitempty"`
+	Group    string         `json:"group,omitempty"`
+	SHA256   string         `json:"sha256"`
+}
+
+type ManagedPackage struct {
+	ID       string         `json:"id"`
+	Selector agent.Selector `json:"selector"`
+	Name     string         `json:"name"`
+	Version  string         `json:"version,omitempty"` // empty means any version
+	State    string         `json:"state"`             // present, absent
+}
+
+// AgentDesiredState is what an agent should report on in its next state
+// report.
+type AgentDesiredState struct {
+	Files    []string `json:"files"`
+	Packages []string `json:"packages"`
+}
+
+type Drift struct {
+	AgentID    string    `json:"agent_id"`
+	Kind       string    `json:"kind"`     // file, package
+	Resource   string    `json:"resource"` // path or package name
+	DeclaredBy string    `json:"declared_by"`
+	Field      string    `json:"field"` // content, mode, owner, group, exists, version, installed
+	Expected   string    `json:"expected"`
+	Actual     string    `json:"actual"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+type RemediationResult struct {
+	Kind     string `json:"kind"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Ref      string `json:"ref,omitempty"` // transfer or job ID
+	Error    string `json:"error,omitempty"`
+}
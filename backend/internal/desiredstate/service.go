@@ -0,0 +1,403 @@
+// backend/internal/desiredstate/service.go
+package desiredstate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/monitoring"
+	"github.com/autosysadmin/backend/internal/transfer"
+)
+
+type service struct {
+	agentManager    *agent.Manager
+	transferService transfer.Service
+	alertManager    *monitoring.AlertManager
+	files           map[string]ManagedFile    // declarationID -> file
+	packages        map[string]ManagedPackage // declarationID -> package
+	drift           map[string][]Drift        // agentID -> drift from the last report
+	alertIDs        map[string]string         // agentID|kind|resource|field -> alertID
+	mu              sync.RWMutex
+}
+
+func NewService(agentManager *agent.Manager, transferService transfer.Service, alertManager *monitoring.AlertManager) Service {
+	return &service{
+		agentManager:    agentManager,
+		transferService: transferService,
+		alertManager:    alertManager,
+		files:           make(map[string]ManagedFile),
+		packages:        make(map[string]ManagedPackage),
+		drift:           make(map[string][]Drift),
+		alertIDs:        make(map[string]string),
+	}
+}
+
+func (s *service) DeclareFile(file ManagedFile) (*ManagedFile, error) {
+	if !filepath.IsAbs(file.Path) {
+		return nil, fmt.Errorf("path must be absolute")
+	}
+	if file.Mode == "" {
+		file.Mode = "0644"
+	}
+	mode, err := strconv.ParseUint(file.Mode, 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mode %q", file.Mode)
+	}
+	if maxSize := s.transferService.MaxFileSize(); int64(len(file.Content)) > maxSize {
+		return nil, fmt.Errorf("content exceeds maximum size of %d bytes", maxSize)
+	}
+
+	// Normalise so it compares equal to what agents report
+	file.Mode = fmt.Sprintf("%04o", mode)
+	file.Path = filepath.Clean(file.Path)
+	sum := sha256.Sum256([]byte(file.Content))
+	file.SHA256 = hex.EncodeToString(sum[:])
+	if file.ID == "" {
+		file.ID = fmt.Sprintf("file-%d", time.Now().UnixNano())
+	}
+
+	s.mu.Lock()
+	s.files[file.ID] = file
+	s.mu.Unlock()
+
+	return &file, nil
+}
+
+func (s *service) DeclarePackage(pkg ManagedPackage) (*ManagedPackage, error) {
+	if pkg.Name == "" {
+		return nil, fmt.Errorf("package name is required")
+	}
+	if pkg.State == "" {
+		pkg.State = "present"
+	}
+	if pkg.State != "present" && pkg.State != "absent" {
+		return nil, fmt.Errorf("state must be present or absent")
+	}
+	if pkg.ID == "" {
+		pkg.ID = fmt.Sprintf("pkg-%d", time.Now().UnixNano())
+	}
+
+	s.mu.Lock()
+	s.packages[pkg.ID] = pkg
+	s.mu.Unlock()
+
+	return &pkg, nil
+}
+
+func (s *service) DeleteFile(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.files[id]; !exists {
+		return fmt.Errorf("managed file not found")
+	}
+	delete(s.files, id)
+	return nil
+}
+
+func (s *service) DeletePackage(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.packages[id]; !exists {
+		return fmt.Errorf("managed package not found")
+	}
+	delete(s.packages, id)
+	return nil
+}
+
+func (s *service) ListFiles() []ManagedFile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files := make([]ManagedFile, 0, len(s.files))
+	for _, f := range s.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ID < files[j].ID })
+	return files
+}
+
+func (s *service) ListPackages() []ManagedPackage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	packages := make([]ManagedPackage, 0, len(s.packages))
+	for _, p := range s.packages {
+		packages = append(packages, p)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].ID < packages[j].ID })
+	return packages
+}
+
+func (s *service) DesiredForAgent(agentID string) (*AgentDesiredState, error) {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+
+	files, packages := s.declarationsFor(a)
+	desired := &AgentDesiredState{Files: []string{}, Packages: []string{}}
+	for path := range files {
+		desired.Files = append(desired.Files, path)
+	}
+	for name := range packages {
+		desired.Packages = append(desired.Packages, name)
+	}
+	sort.Strings(desired.Files)
+	sort.Strings(desired.Packages)
+	return desired, nil
+}
+
+// declarationsFor returns the declarations applying to an agent keyed by
+// path and package name. When several declarations match the same path or
+// package, the one with the lowest ID wins so the outcome is stable.
+func (s *service) declarationsFor(a *agent.Agent) (map[string]ManagedFile, map[string]ManagedPackage) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files := make(map[string]ManagedFile)
+	for _, f := range s.files {
+		if !f.Selector.Matches(a) {
+			continue
+		}
+		if existing, ok := files[f.Path]; !ok || f.ID < existing.ID {
+			files[f.Path] = f
+		}
+	}
+
+	packages := make(map[string]ManagedPackage)
+	for _, p := range s.packages {
+		if !p.Selector.Matches(a) {
+			continue
+		}
+		if existing, ok := packages[p.Name]; !ok || p.ID < existing.ID {
+			packages[p.Name] = p
+		}
+	}
+	return files, packages
+}
+
+func (s *service) ReportState(agentID string, report agent.StateReport) ([]Drift, error) {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+
+	files, packages := s.declarationsFor(a)
+	now := time.Now()
+	var drift []Drift
+
+	reportedFiles := make(map[string]agent.FileState)
+	for _, f := range report.Files {
+		reportedFiles[filepath.Clean(f.Path)] = f
+	}
+	for path, desired := range files {
+		actual, reported := reportedFiles[path]
+		if !reported {
+			// Agent hasn't picked up this declaration yet; no verdict
+			continue
+		}
+		add := func(field, expected, got string) {
+			drift = append(drift, Drift{
+				AgentID: agentID, Kind: "file", Resource: path, DeclaredBy: desired.ID,
+				Field: field, Expected: expected, Actual: got, DetectedAt: now,
+			})
+		}
+		if !actual.Exists {
+			add("exists", "true", "false")
+			continue
+		}
+		if actual.SHA256 != desired.SHA256 {
+			add("content", desired.SHA256, actual.SHA256)
+		}
+		if actual.Mode != desired.Mode {
+			add("mode", desired.Mode, actual.Mode)
+		}
+		if desired.Owner != "" && actual.Owner != desired.Owner {
+			add("owner", desired.Owner, actual.Owner)
+		}
+		if desired.Group != "" && actual.Group != desired.Group {
+			add("group", desired.Group, actual.Group)
+		}
+	}
+
+	reportedPackages := make(map[string]agent.PackageState)
+	for _, p := range report.Packages {
+		reportedPackages[p.Name] = p
+	}
+	for name, desired := range packages {
+		actual, reported := reportedPackages[name]
+		if !reported {
+			continue
+		}
+		add := func(field, expected, got string) {
+			drift = append(drift, Drift{
+				AgentID: agentID, Kind: "package", Resource: name, DeclaredBy: desired.ID,
+				Field: field, Expected: expected, Actual: got, DetectedAt: now,
+			})
+		}
+		switch {
+		case desired.State == "present" && !actual.Installed:
+			add("installed", "true", "false")
+		case desired.State == "absent" && actual.Installed:
+			add("installed", "false", "true")
+		case desired.State == "present" && desired.Version != "" && actual.Version != desired.Version:
+			add("version", desired.Version, actual.Version)
+		}
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		if drift[i].Resource != drift[j].Resource {
+			return drift[i].Resource < drift[j].Resource
+		}
+		return drift[i].Field < drift[j].Field
+	})
+
+	s.updateAlerts(agentID, drift)
+
+	s.mu.Lock()
+	s.drift[agentID] = drift
+	s.mu.Unlock()
+
+	return drift, nil
+}
+
+// updateAlerts raises an alert for each newly detected drift and resolves
+// alerts for drift that is gone.
+func (s *service) updateAlerts(agentID string, drift []Drift) {
+	current := make(map[string]Drift)
+	for _, d := range drift {
+		current[driftKey(d)] = d
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.drift[agentID] {
+		key := driftKey(d)
+		if _, still := current[key]; still {
+			continue
+		}
+		if alertID, ok := s.alertIDs[key]; ok {
+			s.alertManager.ResolveAlert(alertID)
+			delete(s.alertIDs, key)
+		}
+	}
+
+	for key, d := range current {
+		if _, alerted := s.alertIDs[key]; alerted {
+			continue
+		}
+		alert := monitoring.Alert{
+			ID:        fmt.Sprintf("drift-%s-%d", agentID, time.Now().UnixNano()),
+			AgentID:   agentID,
+			Metric:    fmt.Sprintf("drift:%s:%s", d.Kind, d.Field),
+			Message:   fmt.Sprintf("%s %s drifted: %s expected %q, found %q", d.Kind, d.Resource, d.Field, d.Expected, d.Actual),
+			Timestamp: d.DetectedAt,
+			Status:    "active",
+		}
+		s.alertIDs[key] = alert.ID
+		s.alertManager.AddAlert(alert)
+	}
+}
+
+func (s *service) GetDrift(agentID string) []Drift {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Drift(nil), s.drift[agentID]...)
+}
+
+func (s *service) ListDrift() map[string][]Drift {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string][]Drift)
+	for agentID, drift := range s.drift {
+		if len(drift) > 0 {
+			result[agentID] = append([]Drift(nil), drift...)
+		}
+	}
+	return result
+}
+
+// Remediate reapplies the desired state for everything that drifted in the
+// agent's last report. Files are pushed through the transfer service and
+// packages are fixed with pkg.ensure jobs; the next state report shows
+// whether it worked.
+func (s *service) Remediate(ctx context.Context, agentID string) ([]RemediationResult, error) {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+
+	files, packages := s.declarationsFor(a)
+	drift := s.GetDrift(agentID)
+
+	done := make(map[string]bool)
+	var results []RemediationResult
+	for _, d := range drift {
+		if done[d.Kind+"|"+d.Resource] {
+			continue
+		}
+		done[d.Kind+"|"+d.Resource] = true
+
+		switch d.Kind {
+		case "file":
+			desired, ok := files[d.Resource]
+			if !ok {
+				continue
+			}
+			result := RemediationResult{Kind: "file", Resource: d.Resource, Action: "push"}
+			t, err := s.transferService.Upload(ctx, agentID, transfer.UploadRequest{
+				Path:  desired.Path,
+				Mode:  desired.Mode,
+				Owner: desired.Owner,
+				Group: desired.Group,
+			}, []byte(desired.Content))
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Ref = t.ID
+			}
+			results = append(results, result)
+
+		case "package":
+			desired, ok := packages[d.Resource]
+			if !ok {
+				continue
+			}
+			result := RemediationResult{Kind: "package", Resource: d.Resource, Action: desired.State}
+			out, err := s.agentManager.RunCommandOnAgent(ctx, agentID, agent.AgentCommand{
+				Command: PackageEnsureCommand,
+				Args:    []string{desired.Name, desired.Version, desired.State},
+				Timeout: 30 * time.Minute,
+			})
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				var queued struct {
+					JobID string `json:"job_id"`
+				}
+				json.Unmarshal(out, &queued)
+				result.Ref = queued.JobID
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+func driftKey(d Drift) string {
+	return d.AgentID + "|" + d.Kind + "|" + d.Resource + "|" + d.Field
+}
@@ -0,0 +1,100 @@
+// backend/internal/inventory/install.go
+package inventory
+
+// InstallScript is served at /install.sh for the enrollment command. It
+// enrolls the host with its one-time token, stores the agent credential it
+// gets back, installs the newest agent release for the platform and starts
+// it under systemd where available.
+const InstallScript = `#!/bin/sh
+# autosysadmin agent installer
+set -eu
+
+SERVER=""
+AGENT_ID=""
+TOKEN=""
+CONFIG_DIR=/etc/autosysadmin
+BINARY=/usr/local/bin/autosysadmin-agent
+
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--server) SERVER="$2"; shift 2 ;;
+	--agent-id) AGENT_ID="$2"; shift 2 ;;
+	--token) TOKEN="$2"; shift 2 ;;
+	*) echo "unknown option: $1" >&2; exit 2 ;;
+	esac
+done
+if [ -z "$SERVER" ] || [ -z "$AGENT_ID" ] || [ -z "$TOKEN" ]; then
+	echo "usage: install.sh --server URL --agent-id ID --token TOKEN" >&2
+	exit 2
+fi
+
+OS=$(uname -s | tr '[:upper:]' '[:lower:]')
+case "$(uname -m)" in
+	x86_64|amd64) ARCH=amd64 ;;
+	aarch64|arm64) ARCH=arm64 ;;
+	armv7l|armv6l) ARCH=arm ;;
+	i386|i686) ARCH=386 ;;
+	*) ARCH=$(uname -m) ;;
+esac
+HOSTNAME=$(hostname)
+
+# json_field NAME prints the first string value of NAME in the response
+json_field() {
+	sed -n "s/.*\"$1\":\"\([^\"]*\)\".*/\1/p" "$RESPONSE" | head -n 1
+}
+
+umask 077
+mkdir -p "$CONFIG_DIR"
+RESPONSE="$CONFIG_DIR/enrollment.json"
+curl -fsS -X POST "$SERVER/api/v1/enroll" \
+	-H "Content-Type: application/json" \
+	-d "{\"agent_id\":\"$AGENT_ID\",\"token\":\"$TOKEN\",\"hostname\":\"$HOSTNAME\",\"os\":\"$OS\",\"architecture\":\"$ARCH\"}" \
+	-o "$RESPONSE"
+
+CREDENTIAL=$(json_field credential)
+if [ -z "$CREDENTIAL" ]; then
+	echo "enrollment failed: no credential in response" >&2
+	exit 1
+fi
+cat > "$CONFIG_DIR/agent.env" <<EOF
+AUTOSYSADMIN_URL=$SERVER
+AUTOSYSADMIN_AGENT_ID=$AGENT_ID
+AUTOSYSADMIN_CREDENTIAL=$CREDENTIAL
+EOF
+
+DOWNLOAD_PATH=$(json_field download_path)
+if [ -z "$DOWNLOAD_PATH" ]; then
+	echo "enrolled as $AGENT_ID; no agent release for $OS/$ARCH yet, install the agent binary manually"
+	exit 0
+fi
+SHA256=$(json_field sha256)
+curl -fsS -H "Authorization: Bearer $CREDENTIAL" -o "$BINARY.new" "$SERVER$DOWNLOAD_PATH"
+if [ "$(sha256sum "$BINARY.new" | cut -d' ' -f1)" != "$SHA256" ]; then
+	rm -f "$BINARY.new"
+	echo "agent download failed checksum verification" >&2
+	exit 1
+fi
+chmod 755 "$BINARY.new"
+mv "$BINARY.new" "$BINARY"
+
+if command -v systemctl >/dev/null 2>&1; then
+	cat > /etc/systemd/system/autosysadmin-agent.service <<EOF
+[Unit]
+Description=autosysadmin agent
+After=network-online.target
+
+[Service]
+EnvironmentFile=$CONFIG_DIR/agent.env
+ExecStart=$BINARY
+Restart=always
+
+[Install]
+WantedBy=multi-user.target
+EOF
+	systemctl daemon-reload
+	systemctl enable --now autosysadmin-agent
+	echo "enrolled as $AGENT_ID; agent running under systemd"
+else
+	echo "enrolled as $AGENT_ID; start $BINARY with the settings in $CONFIG_DIR/agent.env"
+fi
+`
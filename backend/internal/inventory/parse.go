@@ -0,0 +1,498 @@
+// backend/internal/inventory/parse.go
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported inventory formats.
+const (
+	FormatINI  = "ini"
+	FormatYAML = "yaml"
+	FormatCSV  = "csv"
+)
+
+// Host is one host read from an inventory, with group membership expanded
+// through parent groups and group vars merged under its own vars.
+type Host struct {
+	Name    string            `json:"name"`
+	Address string            `json:"address,omitempty"` // ansible_host, when set
+	Groups  []string          `json:"groups"`
+	Vars    map[string]string `json:"vars"`
+}
+
+// Parse reads an inventory in the given format. An empty format is
+// detected from the content.
+func Parse(format string, data []byte) ([]Host, error) {
+	if format == "" {
+		format = DetectFormat(data)
+	}
+	switch format {
+	case FormatINI:
+		return ParseINI(data)
+	case FormatYAML, "yml":
+		return ParseYAML(data)
+	case FormatCSV:
+		return ParseCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported inventory format %q", format)
+	}
+}
+
+// DetectFormat guesses the format from the first meaningful line: INI
+// inventories start with a [group] header or a bare host, YAML with a
+// "key:" mapping, and CSV with a comma separated header row.
+func DetectFormat(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || line == "---" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "["):
+			return FormatINI
+		case strings.HasSuffix(line, ":") || strings.Contains(line, ": "):
+			return FormatYAML
+		case strings.Contains(line, ","):
+			return FormatCSV
+		default:
+			return FormatINI
+		}
+	}
+	return FormatINI
+}
+
+// inventory accumulates groups while parsing, then resolves each host's
+// effective groups and vars.
+type inventory struct {
+	hosts     map[string]map[string]string // host -> own vars
+	hostOrder []string
+	members   map[string][]string          // group -> direct hosts
+	children  map[string][]string          // group -> child groups
+	groupVars map[string]map[string]string // group -> vars
+}
+
+func newInventory() *inventory {
+	return &inventory{
+		hosts:     make(map[string]map[string]string),
+		members:   make(map[string][]string),
+		children:  make(map[string][]string),
+		groupVars: make(map[string]map[string]string),
+	}
+}
+
+func (inv *inventory) addHost(group, host string, vars map[string]string) {
+	own, exists := inv.hosts[host]
+	if !exists {
+		own = make(map[string]string)
+		inv.hosts[host] = own
+		inv.hostOrder = append(inv.hostOrder, host)
+	}
+	for k, v := range vars {
+		own[k] = v
+	}
+	if group != "" {
+		inv.members[group] = append(inv.members[group], host)
+	}
+}
+
+func (inv *inventory) setGroupVar(group, key, value string) {
+	if inv.groupVars[group] == nil {
+		inv.groupVars[group] = make(map[string]string)
+	}
+	inv.groupVars[group][key] = value
+}
+
+// resolve walks the group tree from "all" and each top-level group so that
+// a host inherits every ancestor group's name and vars; deeper groups and
+// the host's own vars win.
+func (inv *inventory) resolve() ([]Host, error) {
+	paths := make(map[string][][]string) // host -> group paths, outermost first
+
+	var walk func(group string, path []string, depth int) error
+	walk = func(group string, path []string, depth int) error {
+		if depth > 32 {
+			return fmt.Errorf("group nesting too deep or cyclic at %q", group)
+		}
+		path = append(append([]string(nil), path...), group)
+		for _, host := range inv.members[group] {
+			paths[host] = append(paths[host], path)
+		}
+		for _, child := range inv.children[group] {
+			if err := walk(child, path, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	isChild := make(map[string]bool)
+	for _, kids := range inv.children {
+		for _, k := range kids {
+			isChild[k] = true
+		}
+	}
+	var roots []string
+	for group := range inv.members {
+		if !isChild[group] {
+			roots = append(roots, group)
+		}
+	}
+	for group := range inv.children {
+		if !isChild[group] && len(inv.members[group]) == 0 {
+			roots = append(roots, group)
+		}
+	}
+	sort.Strings(roots)
+	for _, group := range roots {
+		if err := walk(group, nil, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	hosts := make([]Host, 0, len(inv.hostOrder))
+	for _, name := range inv.hostOrder {
+		vars := make(map[string]string)
+		for k, v := range inv.groupVars["all"] {
+			vars[k] = v
+		}
+		seen := make(map[string]bool)
+		var groups []string
+		for _, path := range paths[name] {
+			for _, group := range path {
+				for k, v := range inv.groupVars[group] {
+					vars[k] = v
+				}
+				if !seen[group] && group != "all" && group != "ungrouped" {
+					seen[group] = true
+					groups = append(groups, group)
+				}
+			}
+		}
+		for k, v := range inv.hosts[name] {
+			vars[k] = v
+		}
+		sort.Strings(groups)
+		if groups == nil {
+			groups = []string{}
+		}
+		hosts = append(hosts, Host{Name: name, Address: vars["ansible_host"], Groups: groups, Vars: vars})
+	}
+	return hosts, nil
+}
+
+var hostRangePattern = regexp.MustCompile(`\[([0-9a-z]+):([0-9a-z]+)\]`)
+
+// expandHostPattern expands Ansible ranges such as web[01:03] or db-[a:c]
+// into individual host names. Only the first range in a name is expanded
+// per pass; nested calls handle the rest.
+func expandHostPattern(pattern string) ([]string, error) {
+	loc := hostRangePattern.FindStringSubmatchIndex(pattern)
+	if loc == nil {
+		return []string{pattern}, nil
+	}
+	prefix, suffix := pattern[:loc[0]], pattern[loc[1]:]
+	start, end := pattern[loc[2]:loc[3]], pattern[loc[4]:loc[5]]
+
+	var items []string
+	if a, errA := strconv.Atoi(start); errA == nil {
+		b, err := strconv.Atoi(end)
+		if err != nil || b < a {
+			return nil, fmt.Errorf("invalid host range in %q", pattern)
+		}
+		if b-a > 10000 {
+			return nil, fmt.Errorf("host range in %q is too large", pattern)
+		}
+		width := 0
+		if len(start) > 1 && start[0] == '0' {
+			width = len(start)
+		}
+		for i := a; i <= b; i++ {
+			items = append(items, fmt.Sprintf("%0*d", width, i))
+		}
+	} else if len(start) == 1 && len(end) == 1 && start[0] <= end[0] {
+		for ch := start[0]; ch <= end[0]; ch++ {
+			items = append(items, string(ch))
+		}
+	} else {
+		return nil, fmt.Errorf("invalid host range in %q", pattern)
+	}
+
+	var names []string
+	for _, item := range items {
+		rest, err := expandHostPattern(prefix + item + suffix)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, rest...)
+	}
+	return names, nil
+}
+
+// splitINIFields splits a host line on whitespace while keeping quoted
+// values such as motd="hello world" together.
+func splitINIFields(line string) []string {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// ParseINI reads an Ansible INI inventory with [group], [group:vars] and
+// [group:children] sections.
+func ParseINI(data []byte) ([]Host, error) {
+	inv := newInventory()
+	section, kind := "ungrouped", "hosts"
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			header := strings.TrimSpace(line[1 : len(line)-1])
+			section, kind = header, "hosts"
+			if name, suffix, ok := strings.Cut(header, ":"); ok {
+				if suffix != "vars" && suffix != "children" {
+					return nil, fmt.Errorf("line %d: unknown section type %q", lineNo, suffix)
+				}
+				section, kind = name, suffix
+			}
+			if section == "" {
+				return nil, fmt.Errorf("line %d: empty group name", lineNo)
+			}
+			if _, exists := inv.members[section]; !exists && kind == "hosts" {
+				inv.members[section] = nil
+			}
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value in [%s:vars]", lineNo, section)
+			}
+			inv.setGroupVar(section, strings.TrimSpace(key), strings.Trim(strings.TrimSpace(value), `"'`))
+
+		case "children":
+			inv.children[section] = append(inv.children[section], strings.Fields(line)[0])
+
+		default:
+			fields := splitINIFields(line)
+			vars := make(map[string]string)
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					return nil, fmt.Errorf("line %d: expected key=value after host name, got %q", lineNo, field)
+				}
+				vars[key] = value
+			}
+			names, err := expandHostPattern(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			for _, name := range names {
+				inv.addHost(section, name, vars)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return inv.resolve()
+}
+
+type yamlGroup struct {
+	Hosts    map[string]map[string]interface{} `yaml:"hosts"`
+	Vars     map[string]interface{}            `yaml:"vars"`
+	Children map[string]*yamlGroup             `yaml:"children"`
+}
+
+// ParseYAML reads an Ansible YAML inventory: a mapping of group names to
+// hosts, vars and children.
+func ParseYAML(data []byte) ([]Host, error) {
+	var root map[string]*yamlGroup
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid YAML inventory: %w", err)
+	}
+
+	inv := newInventory()
+	var load func(name string, group *yamlGroup)
+	load = func(name string, group *yamlGroup) {
+		if _, exists := inv.members[name]; !exists {
+			inv.members[name] = nil
+		}
+		if group == nil {
+			return
+		}
+		hostNames := make([]string, 0, len(group.Hosts))
+		for host := range group.Hosts {
+			hostNames = append(hostNames, host)
+		}
+		sort.Strings(hostNames)
+		for _, host := range hostNames {
+			vars := make(map[string]string)
+			for k, v := range group.Hosts[host] {
+				vars[k] = scalarString(v)
+			}
+			names, err := expandHostPattern(host)
+			if err != nil {
+				names = []string{host}
+			}
+			for _, n := range names {
+				inv.addHost(name, n, vars)
+			}
+		}
+		for k, v := range group.Vars {
+			inv.setGroupVar(name, k, scalarString(v))
+		}
+		childNames := make([]string, 0, len(group.Children))
+		for child := range group.Children {
+			childNames = append(childNames, child)
+		}
+		sort.Strings(childNames)
+		for _, child := range childNames {
+			inv.children[name] = append(inv.children[name], child)
+			load(child, group.Children[child])
+		}
+	}
+
+	names := make([]string, 0, len(root))
+	for name := range root {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		load(name, root[name])
+	}
+	return inv.resolve()
+}
+
+func scalarString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case map[string]interface{}, []interface{}:
+		out, _ := yaml.Marshal(val)
+		return strings.TrimSpace(string(out))
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// ParseCSV reads a CSV file with a header row. The host column is named
+// hostname, host or name; address, ip or ansible_host gives the address;
+// groups or tags lists groups separated by spaces or semicolons. Every
+// other column becomes a host var.
+func ParseCSV(data []byte) ([]Host, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	hostCol, addrCol, groupCol := -1, -1, -1
+	for i, col := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(col))
+		switch header[i] {
+		case "hostname", "host", "name":
+			if hostCol == -1 {
+				hostCol = i
+			}
+		case "address", "ip", "ansible_host":
+			if addrCol == -1 {
+				addrCol = i
+			}
+		case "groups", "tags":
+			if groupCol == -1 {
+				groupCol = i
+			}
+		}
+	}
+	if hostCol == -1 {
+		return nil, fmt.Errorf("CSV needs a hostname, host or name column")
+	}
+
+	inv := newInventory()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		name := strings.TrimSpace(record[hostCol])
+		if name == "" {
+			continue
+		}
+
+		vars := make(map[string]string)
+		for i, value := range record {
+			if i == hostCol || i == groupCol || value == "" {
+				continue
+			}
+			key := header[i]
+			if i == addrCol {
+				key = "ansible_host"
+			}
+			vars[key] = value
+		}
+
+		var groups []string
+		if groupCol != -1 {
+			groups = strings.FieldsFunc(record[groupCol], func(r rune) bool {
+				return r == ';' || r == ' ' || r == '|'
+			})
+		}
+		if len(groups) == 0 {
+			inv.addHost("ungrouped", name, vars)
+		}
+		for _, group := range groups {
+			inv.addHost(group, name, vars)
+		}
+	}
+	return inv.resolve()
+}
@@ -0,0 +1,372 @@
+// backend/internal/inventory/service.go
+package inventory
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+const (
+	maxHostsPerImport     = 50000
+	enrollmentTokenTTL    = 7 * 24 * time.Hour
+	pendingStatus         = "pending"
+	enrollmentInstallPath = "/install.sh"
+	credentialPrefix      = "agt_"
+)
+
+type Service interface {
+	Import(format string, data []byte, dryRun bool) (*ImportResult, error)
+	GetImport(id string) (*ImportResult, error)
+	ListImports() []ImportResult
+	GetHost(agentID string) (*ImportedHost, error)
+	Enroll(req EnrollRequest) (*Enrollment, error)
+	VerifyAgentCredential(credential string) (string, bool)
+}
+
+// ImportedHost is a host that became a pending agent record.
+type ImportedHost struct {
+	AgentID           string            `json:"agent_id"`
+	Name              string            `json:"name"`
+	Address           string            `json:"address,omitempty"`
+	Groups            []string          `json:"groups"`
+	Vars              map[string]string `json:"vars"`
+	EnrollmentCommand string            `json:"enrollment_command,omitempty"`
+	TokenExpiresAt    time.Time         `json:"token_expires_at,omitempty"`
+	EnrolledAt        time.Time         `json:"enrolled_at,omitempty"`
+}
+
+// Conflict is a host that matches an existing agent and was not imported.
+type Conflict struct {
+	Host            string `json:"host"`
+	ExistingAgentID string `json:"existing_agent_id"`
+	Field           string `json:"field"` // id, hostname, address
+	Value           string `json:"value"`
+}
+
+type ImportResult struct {
+	ID         string         `json:"id"`
+	Format     string         `json:"format"`
+	DryRun     bool           `json:"dry_run"`
+	Hosts      []ImportedHost `json:"hosts"`
+	Conflicts  []Conflict     `json:"conflicts"`
+	ImportedAt time.Time      `json:"imported_at"`
+}
+
+type EnrollRequest struct {
+	AgentID      string `json:"agent_id" binding:"required"`
+	Token        string `json:"token" binding:"required"`
+	Hostname     string `json:"hostname"`
+	IPAddress    string `json:"ip_address"`
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Version      string `json:"version"`
+}
+
+// Enrollment is what an enrolled agent gets back: its record and the
+// credential it authenticates to the agent API with from then on.
+type Enrollment struct {
+	Agent      *agent.Agent `json:"agent"`
+	Credential string       `json:"credential"`
+}
+
+type enrollment struct {
+	token     string
+	expiresAt time.Time
+}
+
+type service struct {
+	agentManager *agent.Manager
+	publicURL    string
+	imports      map[string]ImportResult
+	hosts        map[string]ImportedHost // agentID -> host
+	tokens       map[string]enrollment   // agentID -> pending enrollment
+	hostImports  map[string]string       // agentID -> ID of the import that created it
+	credentials  map[string]string       // credential SHA256 -> agentID
+	agentCreds   map[string]string       // agentID -> credential SHA256
+	mu           sync.RWMutex
+}
+
+func NewService(agentManager *agent.Manager, publicURL string) Service {
+	return &service{
+		agentManager: agentManager,
+		publicURL:    strings.TrimRight(publicURL, "/"),
+		imports:      make(map[string]ImportResult),
+		hosts:        make(map[string]ImportedHost),
+		tokens:       make(map[string]enrollment),
+		hostImports:  make(map[string]string),
+		credentials:  make(map[string]string),
+		agentCreds:   make(map[string]string),
+	}
+}
+
+// Import parses an inventory and creates a pending agent, tagged with its
+// groups, for every host that doesn't clash with an existing agent. With
+// dryRun nothing is created and no tokens are issued.
+func (s *service) Import(format string, data []byte, dryRun bool) (*ImportResult, error) {
+	if format == "" {
+		format = DetectFormat(data)
+	}
+	hosts, err := Parse(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("inventory contains no hosts")
+	}
+	if len(hosts) > maxHostsPerImport {
+		return nil, fmt.Errorf("inventory has %d hosts, the limit is %d", len(hosts), maxHostsPerImport)
+	}
+
+	result := &ImportResult{
+		ID:         fmt.Sprintf("import-%d", time.Now().UnixNano()),
+		Format:     format,
+		DryRun:     dryRun,
+		Hosts:      []ImportedHost{},
+		Conflicts:  []Conflict{},
+		ImportedAt: time.Now(),
+	}
+
+	existing := s.agentManager.ListAgents()
+	byID := make(map[string]*agent.Agent)
+	byHostname := make(map[string]*agent.Agent)
+	byAddress := make(map[string]*agent.Agent)
+	for _, a := range existing {
+		byID[a.ID] = a
+		if a.Hostname != "" {
+			byHostname[strings.ToLower(a.Hostname)] = a
+		}
+		if a.IPAddress != "" {
+			byAddress[a.IPAddress] = a
+		}
+	}
+
+	for _, h := range hosts {
+		agentID := agentIDFor(h.Name)
+
+		var conflict *Conflict
+		switch {
+		case byID[agentID] != nil:
+			conflict = &Conflict{Host: h.Name, ExistingAgentID: agentID, Field: "id", Value: agentID}
+		case byHostname[strings.ToLower(h.Name)] != nil:
+			conflict = &Conflict{Host: h.Name, ExistingAgentID: byHostname[strings.ToLower(h.Name)].ID, Field: "hostname", Value: h.Name}
+		case h.Address != "" && byAddress[h.Address] != nil:
+			conflict = &Conflict{Host: h.Name, ExistingAgentID: byAddress[h.Address].ID, Field: "address", Value: h.Address}
+		}
+		if conflict != nil {
+			result.Conflicts = append(result.Conflicts, *conflict)
+			continue
+		}
+
+		imported := ImportedHost{
+			AgentID: agentID,
+			Name:    h.Name,
+			Address: h.Address,
+			Groups:  h.Groups,
+			Vars:    h.Vars,
+		}
+
+		// Later hosts in the same file must not reuse an ID or address
+		pending := &agent.Agent{
+			ID:        agentID,
+			Name:      h.Name,
+			Hostname:  h.Name,
+			IPAddress: h.Address,
+			Status:    pendingStatus,
+			Tags:      append([]string(nil), h.Groups...),
+		}
+		byID[agentID] = pending
+		byHostname[strings.ToLower(h.Name)] = pending
+		if h.Address != "" {
+			byAddress[h.Address] = pending
+		}
+
+		if !dryRun {
+			token, err := newToken()
+			if err != nil {
+				return nil, err
+			}
+			imported.TokenExpiresAt = time.Now().Add(enrollmentTokenTTL)
+			imported.EnrollmentCommand = s.enrollmentCommand(agentID, token)
+
+			s.agentManager.RegisterAgent(pending)
+			s.mu.Lock()
+			s.hosts[agentID] = imported
+			s.tokens[agentID] = enrollment{token: token, expiresAt: imported.TokenExpiresAt}
+			s.hostImports[agentID] = result.ID
+			s.mu.Unlock()
+		}
+		result.Hosts = append(result.Hosts, imported)
+	}
+
+	if !dryRun {
+		s.mu.Lock()
+		s.imports[result.ID] = *result
+		s.mu.Unlock()
+	}
+	return result, nil
+}
+
+func (s *service) enrollmentCommand(agentID, token string) string {
+	return fmt.Sprintf("curl -fsSL %s%s | sudo sh -s -- --server %s --agent-id %s --token %s",
+		s.publicURL, enrollmentInstallPath, s.publicURL, agentID, token)
+}
+
+func (s *service) GetImport(id string) (*ImportResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, exists := s.imports[id]
+	if !exists {
+		return nil, fmt.Errorf("import not found")
+	}
+	return &result, nil
+}
+
+func (s *service) ListImports() []ImportResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	imports := make([]ImportResult, 0, len(s.imports))
+	for _, result := range s.imports {
+		imports = append(imports, result)
+	}
+	sort.Slice(imports, func(i, j int) bool { return imports[i].ImportedAt.After(imports[j].ImportedAt) })
+	return imports
+}
+
+func (s *service) GetHost(agentID string) (*ImportedHost, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	host, exists := s.hosts[agentID]
+	if !exists {
+		return nil, fmt.Errorf("no imported host for agent %s", agentID)
+	}
+	return &host, nil
+}
+
+// Enroll is called by the agent installer on the host. The token is single
+// use; on success the pending record is filled in and marked online, and
+// the agent gets a credential for the agent API.
+func (s *service) Enroll(req EnrollRequest) (*Enrollment, error) {
+	s.mu.Lock()
+	pending, exists := s.tokens[req.AgentID]
+	if !exists || subtle.ConstantTimeCompare([]byte(pending.token), []byte(req.Token)) != 1 {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("invalid enrollment token")
+	}
+	if time.Now().After(pending.expiresAt) {
+		delete(s.tokens, req.AgentID)
+		s.clearEnrollmentCommandLocked(req.AgentID)
+		s.mu.Unlock()
+		return nil, fmt.Errorf("enrollment token expired")
+	}
+	delete(s.tokens, req.AgentID)
+	s.clearEnrollmentCommandLocked(req.AgentID)
+	host := s.hosts[req.AgentID]
+	host.EnrolledAt = time.Now()
+	s.hosts[req.AgentID] = host
+	s.mu.Unlock()
+
+	err := s.agentManager.UpdateAgent(req.AgentID, func(a *agent.Agent) {
+		if req.Hostname != "" {
+			a.Hostname = req.Hostname
+		}
+		if req.IPAddress != "" {
+			a.IPAddress = req.IPAddress
+		}
+		a.OS = req.OS
+		a.Architecture = req.Architecture
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.agentManager.Heartbeat(req.AgentID, req.Version); err != nil {
+		return nil, err
+	}
+
+	secret, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	credential := credentialPrefix + secret
+	hash := credentialHash(credential)
+
+	s.mu.Lock()
+	// Only the newest credential is valid, so re-enrolling revokes the old
+	if old, ok := s.agentCreds[req.AgentID]; ok {
+		delete(s.credentials, old)
+	}
+	s.credentials[hash] = req.AgentID
+	s.agentCreds[req.AgentID] = hash
+	s.mu.Unlock()
+
+	a, _ := s.agentManager.GetAgent(req.AgentID)
+	return &Enrollment{Agent: a, Credential: credential}, nil
+}
+
+// VerifyAgentCredential returns the agent a credential was issued to.
+func (s *service) VerifyAgentCredential(credential string) (string, bool) {
+	if !strings.HasPrefix(credential, credentialPrefix) {
+		return "", false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	agentID, ok := s.credentials[credentialHash(credential)]
+	return agentID, ok
+}
+
+// clearEnrollmentCommandLocked removes a used or expired token from the
+// host and from the import that created it, so neither lists it again.
+func (s *service) clearEnrollmentCommandLocked(agentID string) {
+	if host, ok := s.hosts[agentID]; ok {
+		host.EnrollmentCommand = ""
+		s.hosts[agentID] = host
+	}
+	result, ok := s.imports[s.hostImports[agentID]]
+	if !ok {
+		return
+	}
+	// The stored result shares its Hosts array with copies already handed
+	// out, so replace it rather than edit in place
+	hosts := append([]ImportedHost(nil), result.Hosts...)
+	for i := range hosts {
+		if hosts[i].AgentID == agentID {
+			hosts[i].EnrollmentCommand = ""
+		}
+	}
+	result.Hosts = hosts
+	s.imports[result.ID] = result
+	delete(s.hostImports, agentID)
+}
+
+// Credentials are stored hashed, so a leak of the map doesn't leak them;
+// they are random enough that a plain SHA256 suffices.
+func credentialHash(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(sum[:])
+}
+
+var agentIDUnsafe = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+func agentIDFor(hostname string) string {
+	return "agent-" + strings.Trim(agentIDUnsafe.ReplaceAllString(strings.ToLower(hostname), "-"), "-")
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate enrollment token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
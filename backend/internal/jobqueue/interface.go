@@ -10,4 +10,11 @@ type JobQueue interface {
 	FailJob(ctx context.Context, jobID string, errorMsg string) error
 	GetJob(ctx context.Context, jobID string) (*Job, error)
 	Close() error
+}
+
+// DepthReporter is implemented by queues that can count their unfinished
+// jobs by status: "queued" jobs wait to be dequeued, "running" ones have
+// been handed to an agent that hasn't reported back.
+type DepthReporter interface {
+	Depths(ctx context.Context) (map[string]int64, error)
 }
\ No newline at end of file
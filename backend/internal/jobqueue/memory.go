@@ -0,0 +1,132 @@
+// backend/internal/jobqueue/memory.go
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// FinishedJobTTL is how long the in-memory queue keeps completed and failed
+// jobs for GetJob before evicting them.
+const FinishedJobTTL = 10 * time.Minute
+
+type finishedJob struct {
+	id string
+	at time.Time
+}
+
+// InMemoryJobQueue is a process-local JobQueue for tests and tools such as
+// the agent simulator that shouldn't depend on Redis.
+type InMemoryJobQueue struct {
+	jobs     map[string]*Job
+	pending  []string      // job IDs, oldest first
+	finished []finishedJob // oldest first
+	mu       sync.Mutex
+}
+
+func NewInMemoryJobQueue() *InMemoryJobQueue {
+	return &InMemoryJobQueue{
+		jobs: make(map[string]*Job),
+	}
+}
+
+func (q *InMemoryJobQueue) Enqueue(ctx context.Context, job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.evictLocked(time.Now())
+	job.Status = "queued"
+	q.jobs[job.ID] = &job
+	q.pending = append(q.pending, job.ID)
+	return nil
+}
+
+func (q *InMemoryJobQueue) Dequeue(ctx context.Context) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		return nil, nil // No jobs available
+	}
+	jobID := q.pending[0]
+	q.pending = q.pending[1:]
+
+	job := q.jobs[jobID]
+	job.Status = "running"
+	c := *job
+	return &c, nil
+}
+
+func (q *InMemoryJobQueue) CompleteJob(ctx context.Context, jobID string, result string) error {
+	return q.finish(jobID, "completed", result)
+}
+
+func (q *InMemoryJobQueue) FailJob(ctx context.Context, jobID string, errorMsg string) error {
+	return q.finish(jobID, "failed", errorMsg)
+}
+
+func (q *InMemoryJobQueue) finish(jobID, status, result string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, exists := q.jobs[jobID]
+	if !exists {
+		return errors.New("job not found")
+	}
+	job.Status = status
+	job.Result = result
+
+	now := time.Now()
+	q.finished = append(q.finished, finishedJob{id: jobID, at: now})
+	q.evictLocked(now)
+	return nil
+}
+
+// evictLocked drops jobs that finished more than FinishedJobTTL ago, so a
+// long load test doesn't keep every job it ever ran.
+func (q *InMemoryJobQueue) evictLocked(now time.Time) {
+	i := 0
+	for i < len(q.finished) && now.Sub(q.finished[i].at) > FinishedJobTTL {
+		delete(q.jobs, q.finished[i].id)
+		i++
+	}
+	q.finished = q.finished[i:]
+}
+
+func (q *InMemoryJobQueue) GetJob(ctx context.Context, jobID string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, exists := q.jobs[jobID]
+	if !exists {
+		return nil, errors.New("job not found")
+	}
+	c := *job
+	return &c, nil
+}
+
+// Len returns the number of jobs waiting to be dequeued.
+func (q *InMemoryJobQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+func (q *InMemoryJobQueue) Depths(ctx context.Context) (map[string]int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	depths := map[string]int64{"queued": int64(len(q.pending)), "running": 0}
+	for _, job := range q.jobs {
+		if job.Status == "running" {
+			depths["running"]++
+		}
+	}
+	return depths, nil
+}
+
+func (q *InMemoryJobQueue) Close() error {
+	return nil
+}
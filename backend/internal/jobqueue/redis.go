@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -25,6 +26,10 @@ type Job struct {
 	CreatedAt time.Time     `json:"created_at"`
 	Status    string        `json:"status"` // queued, running, completed, failed
 	Result    string        `json:"result"`
+	// Script is the exact script body a script.run job executes, kept on
+	// the job so what ran can be audited after the library changes
+	Script    string `json:"script,omitempty"`
+	ScriptRef string `json:"script_ref,omitempty"` // scriptID@version
 }
 
 func NewRedisJobQueue(redisAddr string, prefix string) *RedisJobQueue {
@@ -87,7 +92,10 @@ func (q *RedisJobQueue) Dequeue(ctx context.Context) (*Job, error) {
 		return nil, fmt.Errorf("failed to marshal updated job: %w", err)
 	}
 
-	if err := q.client.HSet(ctx, jobKey, "data", jobData).Err(); err != nil {
+	pipe := q.client.TxPipeline()
+	pipe.HSet(ctx, jobKey, "data", jobData)
+	pipe.ZAdd(ctx, q.runningKey(), redis.Z{Score: float64(runningDeadline(job).Unix()), Member: job.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
 		return nil, fmt.Errorf("failed to update job status: %w", err)
 	}
 
@@ -113,7 +121,7 @@ func (q *RedisJobQueue) CompleteJob(ctx context.Context, jobID string, result st
 		return fmt.Errorf("failed to marshal completed job: %w", err)
 	}
 
-	return q.client.HSet(ctx, jobKey, "data", jobData).Err()
+	return q.finish(ctx, jobKey, jobID, jobData)
 }
 
 func (q *RedisJobQueue) FailJob(ctx context.Context, jobID string, errorMsg string) error {
@@ -135,7 +143,7 @@ func (q *RedisJobQueue) FailJob(ctx context.Context, jobID string, errorMsg stri
 		return fmt.Errorf("failed to marshal failed job: %w", err)
 	}
 
-	return q.client.HSet(ctx, jobKey, "data", jobData).Err()
+	return q.finish(ctx, jobKey, jobID, jobData)
 }
 
 func (q *RedisJobQueue) GetJob(ctx context.Context, jobID string) (*Job, error) {
@@ -153,6 +161,45 @@ func (q *RedisJobQueue) GetJob(ctx context.Context, jobID string) (*Job, error)
 	return &job, nil
 }
 
+// finish stores a completed or failed job and drops it from the running set.
+func (q *RedisJobQueue) finish(ctx context.Context, jobKey, jobID string, jobData []byte) error {
+	pipe := q.client.TxPipeline()
+	pipe.HSet(ctx, jobKey, "data", jobData)
+	pipe.ZRem(ctx, q.runningKey(), jobID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// runningKey is a sorted set of running job IDs scored by the time after
+// which the job counts as abandoned, so jobs of a worker that died without
+// finishing them don't stay "running" forever.
+func (q *RedisJobQueue) runningKey() string {
+	return fmt.Sprintf("%s:running", q.prefix)
+}
+
+// Jobs without a timeout of their own count as running for this long
+const defaultRunningTTL = time.Hour
+
+func runningDeadline(job Job) time.Time {
+	ttl := defaultRunningTTL
+	if job.Timeout > 0 {
+		// Allow for the result to be reported after the command times out
+		ttl = job.Timeout + time.Minute
+	}
+	return time.Now().Add(ttl)
+}
+
+func (q *RedisJobQueue) Depths(ctx context.Context) (map[string]int64, error) {
+	pipe := q.client.TxPipeline()
+	queued := pipe.LLen(ctx, fmt.Sprintf("%s:queue", q.prefix))
+	pipe.ZRemRangeByScore(ctx, q.runningKey(), "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+	running := pipe.ZCard(ctx, q.runningKey())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get queue depths: %w", err)
+	}
+	return map[string]int64{"queued": queued.Val(), "running": running.Val()}, nil
+}
+
 func (q *RedisJobQueue) Close() error {
 	return q.client.Close()
 }
\ No newline at end of file
@@ -0,0 +1,83 @@
+// backend/internal/logs/logs.go
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type LogLine struct {
+	AgentID   string            `json:"agent_id"`
+	Hostname  string            `json:"hostname"`
+	Source    string            `json:"source"` // file, journald
+	Path      string            `json:"path,omitempty"`
+	Unit      string            `json:"unit,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Message   string            `json:"message"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// CollectionConfig tells matching agents which files and journald units to
+// tail and ship to the backend.
+type CollectionConfig struct {
+	ID       string         `json:"id"`
+	Selector agent.Selector `json:"selector"`
+	Files    []string       `json:"files"`
+	Units    []string       `json:"units"`
+}
+
+type Query struct {
+	Start    time.Time      `json:"start"`
+	End      time.Time      `json:"end"`
+	Selector agent.Selector `json:"selector"`
+	Contains string         `json:"contains,omitempty"` // case-insensitive substring
+	Regex    string         `json:"regex,omitempty"`
+	Unit     string         `json:"unit,omitempty"`
+	Path     string         `json:"path,omitempty"`
+	Limit    int            `json:"limit,omitempty"`
+}
+
+// Matcher is a compiled form of the line filters in a Query. Agent and time
+// range filtering is done by the store.
+type Matcher struct {
+	contains string
+	regex    *regexp.Regexp
+	unit     string
+	path     string
+}
+
+func (q Query) Compile() (*Matcher, error) {
+	m := &Matcher{
+		contains: strings.ToLower(q.Contains),
+		unit:     q.Unit,
+		path:     q.Path,
+	}
+	if q.Regex != "" {
+		re, err := regexp.Compile(q.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		m.regex = re
+	}
+	return m, nil
+}
+
+func (m *Matcher) Match(line LogLine) bool {
+	if m.unit != "" && line.Unit != m.unit {
+		return false
+	}
+	if m.path != "" && line.Path != m.path {
+		return false
+	}
+	if m.contains != "" && !strings.Contains(strings.ToLower(line.Message), m.contains) {
+		return false
+	}
+	if m.regex != nil && !m.regex.MatchString(line.Message) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,273 @@
+// backend/internal/logs/service.go
+package logs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+const (
+	DefaultRetention = 7 * 24 * time.Hour
+	MaxBatchSize     = 5000
+	defaultLimit     = 1000
+	pruneInterval    = time.Minute
+	subscriberBuffer = 256
+)
+
+type Service interface {
+	Ingest(agentID string, lines []LogLine) error
+	Search(q Query) ([]LogLine, error)
+	Subscribe(q Query) (<-chan LogLine, func(), error)
+	OnIngest(hook IngestHook)
+	SetConfig(cfg CollectionConfig) (*CollectionConfig, error)
+	DeleteConfig(id string) error
+	ListConfigs() []CollectionConfig
+	ConfigForAgent(agentID string) (*CollectionConfig, error)
+}
+
+// IngestHook sees every stored batch, synchronously and in order, so unlike
+// a subscription it never misses lines. It must not block.
+type IngestHook func(lines []LogLine)
+
+type subscriber struct {
+	selector agent.Selector
+	match    *Matcher
+	ch       chan LogLine
+}
+
+type service struct {
+	agentManager *agent.Manager
+	store        Store
+	retention    time.Duration
+	lastPrune    time.Time
+	configs      map[string]CollectionConfig // configID -> config
+	subscribers  map[int]*subscriber
+	nextSubID    int
+	hooks        []IngestHook
+	mu           sync.RWMutex
+}
+
+func NewService(agentManager *agent.Manager, store Store, retention time.Duration) Service {
+	return &service{
+		agentManager: agentManager,
+		store:        store,
+		retention:    retention,
+		lastPrune:    time.Now(),
+		configs:      make(map[string]CollectionConfig),
+		subscribers:  make(map[int]*subscriber),
+	}
+}
+
+func (s *service) Ingest(agentID string, lines []LogLine) error {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return fmt.Errorf("agent not found")
+	}
+	if len(lines) > MaxBatchSize {
+		return fmt.Errorf("batch of %d lines exceeds maximum of %d", len(lines), MaxBatchSize)
+	}
+
+	// Never trust the agent to label its own lines with another agent's ID
+	for i := range lines {
+		lines[i].AgentID = a.ID
+		lines[i].Hostname = a.Hostname
+		if lines[i].Timestamp.IsZero() {
+			lines[i].Timestamp = time.Now()
+		}
+	}
+
+	if err := s.store.Append(lines); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	hooks := s.hooks
+	s.mu.RUnlock()
+	for _, hook := range hooks {
+		hook(lines)
+	}
+
+	s.publish(a, lines)
+	s.maybePrune()
+	return nil
+}
+
+func (s *service) Search(q Query) ([]LogLine, error) {
+	match, err := q.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End.Add(-time.Hour)
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+
+	var agentIDs []string
+	if !q.Selector.IsEmpty() {
+		agentIDs = []string{}
+		for _, a := range s.agentManager.SelectAgents(q.Selector) {
+			agentIDs = append(agentIDs, a.ID)
+		}
+	}
+
+	return s.store.Query(agentIDs, q.Start, q.End, match, q.Limit)
+}
+
+// Subscribe streams newly ingested lines matching the query until the
+// returned cancel func is called. Slow subscribers miss lines rather than
+// blocking ingestion.
+func (s *service) Subscribe(q Query) (<-chan LogLine, func(), error) {
+	match, err := q.Compile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub := &subscriber{
+		selector: q.Selector,
+		match:    match,
+		ch:       make(chan LogLine, subscriberBuffer),
+	}
+
+	s.mu.Lock()
+	id := s.nextSubID
+	s.nextSubID++
+	s.subscribers[id] = sub
+	s.mu.Unlock()
+
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[id]; ok {
+			delete(s.subscribers, id)
+			close(sub.ch)
+		}
+	}
+	return sub.ch, cancel, nil
+}
+
+// OnIngest registers a hook called with every batch once it is stored.
+func (s *service) OnIngest(hook IngestHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+func (s *service) SetConfig(cfg CollectionConfig) (*CollectionConfig, error) {
+	if len(cfg.Files) == 0 && len(cfg.Units) == 0 {
+		return nil, fmt.Errorf("at least one file or unit is required")
+	}
+	if cfg.ID == "" {
+		cfg.ID = fmt.Sprintf("logcfg-%d", time.Now().UnixNano())
+	}
+
+	s.mu.Lock()
+	s.configs[cfg.ID] = cfg
+	s.mu.Unlock()
+
+	return &cfg, nil
+}
+
+func (s *service) DeleteConfig(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.configs[id]; !exists {
+		return fmt.Errorf("log collection config not found")
+	}
+	delete(s.configs, id)
+	return nil
+}
+
+func (s *service) ListConfigs() []CollectionConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	configs := make([]CollectionConfig, 0, len(s.configs))
+	for _, cfg := range s.configs {
+		configs = append(configs, cfg)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].ID < configs[j].ID })
+	return configs
+}
+
+// ConfigForAgent merges every config whose selector matches the agent into
+// the single list of files and units the agent should tail.
+func (s *service) ConfigForAgent(agentID string) (*CollectionConfig, error) {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+
+	merged := &CollectionConfig{
+		ID:       agentID,
+		Selector: agent.Selector{AgentIDs: []string{agentID}},
+	}
+	files := make(map[string]bool)
+	units := make(map[string]bool)
+
+	s.mu.RLock()
+	for _, cfg := range s.configs {
+		if !cfg.Selector.Matches(a) {
+			continue
+		}
+		for _, f := range cfg.Files {
+			if !files[f] {
+				files[f] = true
+				merged.Files = append(merged.Files, f)
+			}
+		}
+		for _, u := range cfg.Units {
+			if !units[u] {
+				units[u] = true
+				merged.Units = append(merged.Units, u)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(merged.Files)
+	sort.Strings(merged.Units)
+	return merged, nil
+}
+
+func (s *service) publish(a *agent.Agent, lines []LogLine) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, sub := range s.subscribers {
+		if !sub.selector.Matches(a) {
+			continue
+		}
+		for _, line := range lines {
+			if !sub.match.Match(line) {
+				continue
+			}
+			select {
+			case sub.ch <- line:
+			default:
+			}
+		}
+	}
+}
+
+func (s *service) maybePrune() {
+	s.mu.Lock()
+	if time.Since(s.lastPrune) < pruneInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = time.Now()
+	s.mu.Unlock()
+
+	s.store.Prune(time.Now().Add(-s.retention))
+}
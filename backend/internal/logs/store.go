@@ -0,0 +1,110 @@
+// backend/internal/logs/store.go
+package logs
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	Append(lines []LogLine) error
+	Query(agentIDs []string, start, end time.Time, match *Matcher, limit int) ([]LogLine, error)
+	Prune(before time.Time) int
+}
+
+type inMemoryStore struct {
+	lines map[string][]LogLine // agentID -> lines ordered by timestamp
+	mu    sync.RWMutex
+}
+
+func NewInMemoryStore() Store {
+	return &inMemoryStore{
+		lines: make(map[string][]LogLine),
+	}
+}
+
+func (s *inMemoryStore) Append(lines []LogLine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	touched := make(map[string]bool)
+	for _, line := range lines {
+		agentLines := s.lines[line.AgentID]
+		if n := len(agentLines); n > 0 && line.Timestamp.Before(agentLines[n-1].Timestamp) {
+			touched[line.AgentID] = true
+		}
+		s.lines[line.AgentID] = append(agentLines, line)
+	}
+
+	// Batches from different sources on one host can interleave; keep each
+	// agent's lines ordered so range queries can binary search
+	for agentID := range touched {
+		agentLines := s.lines[agentID]
+		sort.SliceStable(agentLines, func(i, j int) bool {
+			return agentLines[i].Timestamp.Before(agentLines[j].Timestamp)
+		})
+	}
+	return nil
+}
+
+// Query returns matching lines in the time range, newest first. A nil
+// agentIDs slice means all agents.
+func (s *inMemoryStore) Query(agentIDs []string, start, end time.Time, match *Matcher, limit int) ([]LogLine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if agentIDs == nil {
+		for agentID := range s.lines {
+			agentIDs = append(agentIDs, agentID)
+		}
+	}
+
+	var result []LogLine
+	for _, agentID := range agentIDs {
+		agentLines := s.lines[agentID]
+		from := sort.Search(len(agentLines), func(i int) bool {
+			return !agentLines[i].Timestamp.Before(start)
+		})
+		to := sort.Search(len(agentLines), func(i int) bool {
+			return agentLines[i].Timestamp.After(end)
+		})
+
+		for i := to - 1; i >= from; i-- {
+			if match == nil || match.Match(agentLines[i]) {
+				result = append(result, agentLines[i])
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.After(result[j].Timestamp)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (s *inMemoryStore) Prune(before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for agentID, agentLines := range s.lines {
+		idx := sort.Search(len(agentLines), func(i int) bool {
+			return !agentLines[i].Timestamp.Before(before)
+		})
+		if idx == 0 {
+			continue
+		}
+		pruned += idx
+		if idx == len(agentLines) {
+			delete(s.lines, agentID)
+			continue
+		}
+		// Copy so the pruned prefix can be garbage collected
+		s.lines[agentID] = append([]LogLine(nil), agentLines[idx:]...)
+	}
+	return pruned
+}
@@ -0,0 +1,192 @@
+// backend/internal/logs/tailer.go
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPending bounds how many lines the tailer buffers while the backend is
+// unreachable; the oldest lines are dropped beyond this.
+const maxPending = 50000
+
+// Tailer runs on the agent host. It follows the configured files and
+// journald units and ships new lines in batches.
+type Tailer struct {
+	Files        []string
+	Units        []string
+	BatchSize    int
+	PollInterval time.Duration
+	Ship         func(lines []LogLine) error
+
+	files   map[string]*fileState
+	cursors map[string]string // unit -> last journald cursor
+	pending []LogLine
+}
+
+type fileState struct {
+	info   os.FileInfo
+	offset int64
+}
+
+func (t *Tailer) Run(ctx context.Context) error {
+	if t.BatchSize <= 0 {
+		t.BatchSize = 500
+	}
+	if t.PollInterval <= 0 {
+		t.PollInterval = 2 * time.Second
+	}
+	t.files = make(map[string]*fileState)
+	t.cursors = make(map[string]string)
+
+	// Start at the current end of every source; history is not re-shipped
+	for _, path := range t.Files {
+		if info, err := os.Stat(path); err == nil {
+			t.files[path] = &fileState{info: info, offset: info.Size()}
+		}
+	}
+	for _, unit := range t.Units {
+		t.cursors[unit] = t.latestCursor(ctx, unit)
+	}
+
+	ticker := time.NewTicker(t.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			for _, path := range t.Files {
+				t.pending = append(t.pending, t.readFile(path)...)
+			}
+			for _, unit := range t.Units {
+				t.pending = append(t.pending, t.readJournal(ctx, unit)...)
+			}
+			if len(t.pending) > maxPending {
+				t.pending = t.pending[len(t.pending)-maxPending:]
+			}
+			t.flush()
+		}
+	}
+}
+
+func (t *Tailer) flush() {
+	for len(t.pending) > 0 {
+		n := t.BatchSize
+		if n > len(t.pending) {
+			n = len(t.pending)
+		}
+		if err := t.Ship(t.pending[:n]); err != nil {
+			// Keep the lines and retry on the next tick
+			return
+		}
+		t.pending = t.pending[n:]
+	}
+	t.pending = nil
+}
+
+func (t *Tailer) readFile(path string) []LogLine {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	state, known := t.files[path]
+	if !known || !os.SameFile(state.info, info) || info.Size() < state.offset {
+		// New file, rotated by rename, or truncated in place: read from the start
+		state = &fileState{}
+		t.files[path] = state
+	}
+	state.info = info
+	if info.Size() == state.offset {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(state.offset, io.SeekStart); err != nil {
+		return nil
+	}
+
+	var lines []LogLine
+	reader := bufio.NewReader(f)
+	for {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			// Partial line at EOF; pick it up once it is terminated
+			break
+		}
+		state.offset += int64(len(text))
+		lines = append(lines, LogLine{
+			Source:    "file",
+			Path:      path,
+			Timestamp: time.Now(),
+			Message:   strings.TrimRight(text, "\r\n"),
+		})
+	}
+	return lines
+}
+
+func (t *Tailer) latestCursor(ctx context.Context, unit string) string {
+	out, err := exec.CommandContext(ctx, "journalctl", "-u", unit, "-n", "1", "-o", "json", "--no-pager").Output()
+	if err != nil {
+		return ""
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out, &entry); err != nil {
+		return ""
+	}
+	cursor, _ := entry["__CURSOR"].(string)
+	return cursor
+}
+
+func (t *Tailer) readJournal(ctx context.Context, unit string) []LogLine {
+	args := []string{"-u", unit, "-o", "json", "--no-pager"}
+	if cursor := t.cursors[unit]; cursor != "" {
+		args = append(args, "--after-cursor="+cursor)
+	} else {
+		args = append(args, "--since=-"+strconv.Itoa(int(t.PollInterval.Seconds())+1)+"s")
+	}
+
+	out, err := exec.CommandContext(ctx, "journalctl", args...).Output()
+	if err != nil {
+		return nil
+	}
+
+	var lines []LogLine
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+
+		line := LogLine{Source: "journald", Unit: unit, Timestamp: time.Now()}
+		if msg, ok := entry["MESSAGE"].(string); ok {
+			line.Message = msg
+		}
+		if ts, ok := entry["__REALTIME_TIMESTAMP"].(string); ok {
+			if usec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				line.Timestamp = time.UnixMicro(usec)
+			}
+		}
+		if cursor, ok := entry["__CURSOR"].(string); ok {
+			t.cursors[unit] = cursor
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
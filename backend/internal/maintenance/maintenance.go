@@ -0,0 +1,110 @@
+// backend/internal/maintenance/maintenance.go
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type Service interface {
+	CreateWindow(w Window) (*Window, error)
+	GetWindow(id string) (*Window, error)
+	ListWindows() []Window
+	EndWindow(id string) (*Window, error)
+	DeleteWindow(id string) error
+	// ActiveWindow returns the window currently covering the agent, or nil.
+	ActiveWindow(agentID string) *Window
+	InMaintenance(agentID string) bool
+}
+
+// Recurrence values for Window.Recurrence.
+const (
+	RecurNone   = ""
+	RecurDaily  = "daily"
+	RecurWeekly = "weekly"
+)
+
+// Window puts the matching agents into maintenance from Start to End. A
+// recurring window repeats the same span every day or week, in Start's
+// time zone, until RecurUntil (forever when zero).
+type Window struct {
+	ID         string         `json:"id"`
+	Selector   agent.Selector `json:"selector"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Reason     string         `json:"reason"`
+	Owner      string         `json:"owner"`
+	Recurrence string         `json:"recurrence,omitempty"` // "", daily, weekly
+	RecurUntil time.Time      `json:"recur_until,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+func (w *Window) validate() error {
+	if !w.End.After(w.Start) {
+		return fmt.Errorf("end must be after start")
+	}
+	if w.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	if w.Selector.IsEmpty() {
+		// An empty selector matches every agent; require it to be explicit
+		return fmt.Errorf("selector must name agents or tags")
+	}
+	switch w.Recurrence {
+	case RecurNone:
+	case RecurDaily, RecurWeekly:
+		if w.End.Sub(w.Start) >= w.period() {
+			return fmt.Errorf("a %s window must be shorter than its period", w.Recurrence)
+		}
+	default:
+		return fmt.Errorf("recurrence must be daily or weekly")
+	}
+	return nil
+}
+
+func (w *Window) period() time.Duration {
+	if w.Recurrence == RecurWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// occurrence returns the start of the k-th occurrence, stepping by
+// calendar days so the window keeps its wall-clock time across DST changes.
+func (w *Window) occurrence(k int) time.Time {
+	days := 1
+	if w.Recurrence == RecurWeekly {
+		days = 7
+	}
+	return w.Start.AddDate(0, 0, k*days)
+}
+
+// ActiveAt reports whether the window covers the given instant.
+func (w *Window) ActiveAt(t time.Time) bool {
+	if t.Before(w.Start) {
+		return false
+	}
+	length := w.End.Sub(w.Start)
+	if w.Recurrence == RecurNone {
+		return t.Before(w.End)
+	}
+
+	// Estimate the occurrence by fixed periods, then check it and the one
+	// before to absorb DST shifts
+	k := int(t.Sub(w.Start) / w.period())
+	for _, n := range []int{k, k - 1} {
+		if n < 0 {
+			continue
+		}
+		start := w.occurrence(n)
+		if !w.RecurUntil.IsZero() && start.After(w.RecurUntil) {
+			continue
+		}
+		if !t.Before(start) && t.Before(start.Add(length)) {
+			return true
+		}
+	}
+	return false
+}
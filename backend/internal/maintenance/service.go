@@ -0,0 +1,127 @@
+// backend/internal/maintenance/service.go
+package maintenance
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type service struct {
+	agentManager *agent.Manager
+	windows      map[string]Window // windowID -> window
+	mu           sync.RWMutex
+}
+
+func NewService(agentManager *agent.Manager) Service {
+	return &service{
+		agentManager: agentManager,
+		windows:      make(map[string]Window),
+	}
+}
+
+func (s *service) CreateWindow(w Window) (*Window, error) {
+	if err := w.validate(); err != nil {
+		return nil, err
+	}
+	w.ID = fmt.Sprintf("mw-%d", time.Now().UnixNano())
+	w.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	s.windows[w.ID] = w
+	s.mu.Unlock()
+
+	return &w, nil
+}
+
+func (s *service) GetWindow(id string) (*Window, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, exists := s.windows[id]
+	if !exists {
+		return nil, fmt.Errorf("maintenance window not found")
+	}
+	return &w, nil
+}
+
+func (s *service) ListWindows() []Window {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	windows := make([]Window, 0, len(s.windows))
+	for _, w := range s.windows {
+		windows = append(windows, w)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows
+}
+
+// EndWindow finishes maintenance early. A one-off window ends now; a
+// recurring one stops recurring after the current occurrence.
+func (s *service) EndWindow(id string) (*Window, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, exists := s.windows[id]
+	if !exists {
+		return nil, fmt.Errorf("maintenance window not found")
+	}
+
+	now := time.Now()
+	if w.Recurrence == RecurNone {
+		if now.Before(w.End) {
+			w.End = now
+			if w.End.Before(w.Start) {
+				w.Start = now
+			}
+		}
+	} else {
+		// No occurrence starting from now on
+		w.RecurUntil = now.Add(-time.Second)
+	}
+	s.windows[id] = w
+	return &w, nil
+}
+
+func (s *service) DeleteWindow(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.windows[id]; !exists {
+		return fmt.Errorf("maintenance window not found")
+	}
+	delete(s.windows, id)
+	return nil
+}
+
+func (s *service) ActiveWindow(agentID string) *Window {
+	a, exists := s.agentManager.GetAgent(agentID)
+	if !exists {
+		return nil
+	}
+
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var active *Window
+	for _, w := range s.windows {
+		if !w.ActiveAt(now) || !w.Selector.Matches(a) {
+			continue
+		}
+		// Lowest ID wins when several overlap so the answer is stable
+		if active == nil || w.ID < active.ID {
+			w := w
+			active = &w
+		}
+	}
+	return active
+}
+
+func (s *service) InMaintenance(agentID string) bool {
+	return s.ActiveWindow(agentID) != nil
+}
@@ -2,44 +2,143 @@
 package monitoring
 
 import (
-	"context"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/autosysadmin/backend/internal/maintenance"
 )
 
 type AlertNotifier interface {
 	Notify(alert Alert) error
 }
 
+// AcknowledgeNotifier is implemented by notifiers that track alert state on
+// their side, such as paging services, and should hear about acks.
+type AcknowledgeNotifier interface {
+	NotifyAcknowledged(alert Alert) error
+}
+
 type AlertManager struct {
-	notifiers []AlertNotifier
-	alerts    map[string]Alert // alertID -> alert
-	mu        sync.RWMutex
+	notifiers   []AlertNotifier          // default notifiers
+	receivers   map[string]AlertNotifier // name -> notifier, for rule routing
+	alerts      map[string]Alert         // alertID -> alert
+	maintenance maintenance.Service
+
+	silences     map[string]Silence
+	inhibitRules map[string]InhibitRule
+	notified     map[string]bool // alertID -> firing was notified, when not grouping
+	grouping     *GroupConfig    // nil notifies every alert on its own
+	groups       map[string]*alertGroup
+	retry        RetryPolicy
+	deliveries   map[string][]Delivery     // alertID -> delivery attempts, oldest first
+	queues       map[string]*deliveryQueue // deliveryKey -> notifications not yet sent
+
+	mu sync.RWMutex
 }
 
 func NewAlertManager(notifiers ...AlertNotifier) *AlertManager {
 	return &AlertManager{
-		notifiers: notifiers,
-		alerts:    make(map[string]Alert),
+		notifiers:    notifiers,
+		receivers:    make(map[string]AlertNotifier),
+		alerts:       make(map[string]Alert),
+		silences:     make(map[string]Silence),
+		inhibitRules: make(map[string]InhibitRule),
+		notified:     make(map[string]bool),
+		groups:       make(map[string]*alertGroup),
+		retry:        DefaultRetryPolicy,
+		deliveries:   make(map[string][]Delivery),
+		queues:       make(map[string]*deliveryQueue),
 	}
 }
 
+// SetMaintenance makes the manager record, but not notify, alerts for
+// agents in a maintenance window.
+func (m *AlertManager) SetMaintenance(svc maintenance.Service) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maintenance = svc
+}
+
+// AddReceiver registers a named notifier that alert rules can route to.
+func (m *AlertManager) AddReceiver(name string, notifier AlertNotifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.receivers[name] = notifier
+}
+
+func (m *AlertManager) RemoveReceiver(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.receivers, name)
+}
+
+func (m *AlertManager) HasReceiver(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, exists := m.receivers[name]
+	return exists
+}
+
+// ListReceivers returns the registered receiver names, sorted.
+func (m *AlertManager) ListReceivers() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.receivers))
+	for name := range m.receivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddAlert records an alert and notifies about it. Re-adding an alert that
+// is already active updates it without notifying again, unless it was
+// suppressed by a maintenance window that has since ended: an alert still
+// firing after the window is notified then.
 func (m *AlertManager) AddAlert(alert Alert) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if existing, exists := m.alerts[alert.ID]; exists && existing.Status == "active" {
+		alert.Suppressed = existing.Suppressed && m.inMaintenanceLocked(alert)
+		alert.AcknowledgedBy, alert.AcknowledgedAt = existing.AcknowledgedBy, existing.AcknowledgedAt
+		m.alerts[alert.ID] = alert
+		if existing.Suppressed && !alert.Suppressed {
+			m.dispatchLocked(alert)
+		}
+		return
+	}
+
+	alert.Suppressed = m.inMaintenanceLocked(alert)
 	m.alerts[alert.ID] = alert
+	if !alert.Suppressed {
+		m.dispatchLocked(alert)
+	}
+}
+
+func (m *AlertManager) inMaintenanceLocked(alert Alert) bool {
+	return m.maintenance != nil && alert.AgentID != "" && m.maintenance.InMaintenance(alert.AgentID)
+}
 
-	// Notify all notifiers
-	for _, notifier := range m.notifiers {
-		go func(n AlertNotifier) {
-			if err := n.Notify(alert); err != nil {
-				fmt.Printf("Failed to send alert notification: %v\n", err)
-			}
-		}(notifier)
+// unsuppressLocked notifies active alerts whose maintenance window has
+// ended. Rules raise an alert once per firing, so this, run from Run, is
+// what gets a long-firing alert notified after the window.
+func (m *AlertManager) unsuppressLocked() {
+	for id, alert := range m.alerts {
+		if alert.Status != "active" || !alert.Suppressed || m.inMaintenanceLocked(alert) {
+			continue
+		}
+		alert.Suppressed = false
+		m.alerts[id] = alert
+		m.dispatchLocked(alert)
 	}
 }
 
+// ResolveAlert marks an alert resolved and notifies about the resolution.
+// Resolving an alert that is already resolved does nothing.
 func (m *AlertManager) ResolveAlert(alertID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -48,62 +147,166 @@ func (m *AlertManager) ResolveAlert(alertID string) error {
 	if !exists {
 		return fmt.Errorf("alert not found")
 	}
+	if alert.Status == "resolved" {
+		return nil
+	}
 
+	now := time.Now()
 	alert.Status = "resolved"
+	alert.ResolvedAt = &now
 	m.alerts[alertID] = alert
+	if !alert.Suppressed {
+		m.dispatchLocked(alert)
+	}
 	return nil
 }
 
-func (m *AlertManager) GetActiveAlerts() []Alert {
+// AcknowledgeAlert records that someone is working on an active alert,
+// which halts its escalation. Acknowledging again keeps the first ack.
+func (m *AlertManager) AcknowledgeAlert(alertID, userID string) (*Alert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alert, exists := m.alerts[alertID]
+	if !exists {
+		return nil, fmt.Errorf("alert not found")
+	}
+	if alert.Status != "active" {
+		return nil, fmt.Errorf("alert is already resolved")
+	}
+	if alert.AcknowledgedAt == nil {
+		now := time.Now()
+		alert.AcknowledgedBy = userID
+		alert.AcknowledgedAt = &now
+		m.alerts[alertID] = alert
+		if !alert.Suppressed {
+			m.notifyAcknowledgedLocked(alert)
+		}
+	}
+	return &alert, nil
+}
+
+func (m *AlertManager) GetAlert(alertID string) (*Alert, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var activeAlerts []Alert
-	for _, alert := range m.alerts {
-		if alert.Status == "active" {
-			activeAlerts = append(activeAlerts, alert)
+	alert, exists := m.alerts[alertID]
+	if !exists {
+		return nil, fmt.Errorf("alert not found")
+	}
+	return &alert, nil
+}
+
+// dispatchLocked hands a fired or resolved alert to its group, or notifies
+// it directly unless it is silenced or inhibited. A resolution is only
+// notified if the firing was.
+func (m *AlertManager) dispatchLocked(alert Alert) {
+	if m.grouping != nil {
+		m.groupLocked(alert)
+		return
+	}
+
+	if alert.Status != "active" {
+		if m.notified[alert.ID] {
+			delete(m.notified, alert.ID)
+			m.notifyLocked(alert)
 		}
+		return
 	}
-	return activeAlerts
+	if silenced, inhibited := m.mutedLocked(alert, time.Now()); len(silenced) > 0 || len(inhibited) > 0 {
+		return
+	}
+	m.notified[alert.ID] = true
+	m.notifyLocked(alert)
 }
 
-// EmailNotifier implements AlertNotifier for email notifications
-type EmailNotifier struct {
-	SMTPConfig SMTPConfig
+type namedNotifier struct {
+	name     string
+	notifier AlertNotifier
 }
 
-type SMTPConfig struct {
-	Server   string
-	Port     int
-	Username string
-	Password string
-	From     string
+// notifiersLocked resolves receiver names to notifiers, falling back to the
+// default notifiers if none of them are registered.
+func (m *AlertManager) notifiersLocked(receivers []string) []namedNotifier {
+	var notifiers []namedNotifier
+	for _, name := range receivers {
+		if n, ok := m.receivers[name]; ok {
+			notifiers = append(notifiers, namedNotifier{name, n})
+		}
+	}
+	if len(notifiers) == 0 {
+		for _, n := range m.notifiers {
+			notifiers = append(notifiers, namedNotifier{"default:" + notifierType(n), n})
+		}
+	}
+	return notifiers
 }
 
-func (n *EmailNotifier) Notify(alert Alert) error {
-	// In a real implementation, this would send an email
-	fmt.Printf("Sending email alert for %s: %s\n", alert.AgentID, alert.Message)
-	return nil
+func (m *AlertManager) notifyGroupLocked(group AlertGroup) {
+	for _, nn := range m.notifiersLocked(group.Receivers) {
+		nn := nn
+		if gn, ok := nn.notifier.(GroupNotifier); ok {
+			m.enqueueLocked(deliveryKey("group:"+group.Key, nn.name), func() {
+				m.deliver(nn.name, group.Alerts, "", func() error { return gn.NotifyGroup(group) })
+			})
+			continue
+		}
+		for _, alert := range group.Alerts {
+			alert := alert
+			m.enqueueLocked(deliveryKey(alert.ID, nn.name), func() {
+				m.deliver(nn.name, []Alert{alert}, eventFor(alert), func() error { return nn.notifier.Notify(alert) })
+			})
+		}
+	}
 }
 
-// SlackNotifier implements AlertNotifier for Slack notifications
-type SlackNotifier struct {
-	WebhookURL string
+func (m *AlertManager) notifyLocked(alert Alert) {
+	for _, nn := range m.notifiersLocked(alert.Receivers) {
+		nn := nn
+		m.enqueueLocked(deliveryKey(alert.ID, nn.name), func() {
+			m.deliver(nn.name, []Alert{alert}, eventFor(alert), func() error { return nn.notifier.Notify(alert) })
+		})
+	}
 }
 
-func (n *SlackNotifier) Notify(alert Alert) error {
-	// In a real implementation, this would send a Slack message
-	fmt.Printf("Sending Slack alert for %s: %s\n", alert.AgentID, alert.Message)
-	return nil
+func (m *AlertManager) notifyAcknowledgedLocked(alert Alert) {
+	for _, nn := range m.notifiersLocked(alert.Receivers) {
+		an, ok := nn.notifier.(AcknowledgeNotifier)
+		if !ok {
+			continue
+		}
+		name := nn.name
+		m.enqueueLocked(deliveryKey(alert.ID, name), func() {
+			m.deliver(name, []Alert{alert}, EventAcknowledged, func() error { return an.NotifyAcknowledged(alert) })
+		})
+	}
 }
 
-// WebhookNotifier implements AlertNotifier for generic webhook notifications
-type WebhookNotifier struct {
-	URL string
+// GetAgentAlerts returns the agent's alerts, active and resolved, oldest
+// first.
+func (m *AlertManager) GetAgentAlerts(agentID string) []Alert {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var alerts []Alert
+	for _, alert := range m.alerts {
+		if alert.AgentID == agentID {
+			alerts = append(alerts, alert)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Timestamp.Before(alerts[j].Timestamp) })
+	return alerts
 }
 
-func (n *WebhookNotifier) Notify(alert Alert) error {
-	// In a real implementation, this would POST to a webhook
-	fmt.Printf("Sending webhook alert for %s: %s\n", alert.AgentID, alert.Message)
-	return nil
-}
\ No newline at end of file
+func (m *AlertManager) GetActiveAlerts() []Alert {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var activeAlerts []Alert
+	for _, alert := range m.alerts {
+		if alert.Status == "active" {
+			activeAlerts = append(activeAlerts, alert)
+		}
+	}
+	return activeAlerts
+}
@@ -0,0 +1,200 @@
+// backend/internal/monitoring/delivery.go
+package monitoring
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RetryPolicy is how often and how patiently a failed notification is
+// retried. The wait doubles after every attempt, up to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int           `json:"max_attempts"`
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+// Keep the delivery log bounded for alerts that flap or repeat for days
+const maxDeliveriesPerAlert = 100
+
+// Delivery statuses.
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is one attempt to deliver a notification about an alert.
+type Delivery struct {
+	AlertID  string        `json:"alert_id"`
+	Receiver string        `json:"receiver"`
+	Event    string        `json:"event"` // firing, resolved, acknowledged
+	Attempt  int           `json:"attempt"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	At       time.Time     `json:"at"`
+	Duration time.Duration `json:"duration"`
+}
+
+// permanentError marks a notification failure that retrying won't fix,
+// such as a 4xx response to a malformed payload.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the AlertManager doesn't retry the notification.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func (m *AlertManager) SetRetryPolicy(policy RetryPolicy) error {
+	if policy.MaxAttempts < 1 {
+		return fmt.Errorf("max_attempts must be at least 1")
+	}
+	if policy.InitialBackoff < 0 || policy.MaxBackoff < policy.InitialBackoff {
+		return fmt.Errorf("backoff must be non-negative with max_backoff >= initial_backoff")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retry = policy
+	return nil
+}
+
+// Delivery events.
+const (
+	EventFiring       = "firing"
+	EventResolved     = "resolved"
+	EventAcknowledged = "acknowledged"
+)
+
+// eventFor is the event a firing or resolution notification carries.
+func eventFor(alert Alert) string {
+	if alert.Status == "active" {
+		return EventFiring
+	}
+	return EventResolved
+}
+
+// deliver runs send until it succeeds, fails permanently or runs out of
+// attempts, logging every attempt against each alert it covers. An empty
+// event logs each alert's own firing or resolved event, for groups.
+func (m *AlertManager) deliver(receiver string, alerts []Alert, event string, send func() error) {
+	m.mu.RLock()
+	policy := m.retry
+	m.mu.RUnlock()
+
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := send()
+		m.recordDelivery(receiver, alerts, event, attempt, start, err)
+		if err == nil {
+			return
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= policy.MaxAttempts {
+			fmt.Printf("Failed to send alert notification via %s after %d attempt(s): %v\n", receiver, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// deliveryQueue holds the notifications waiting behind the one in flight
+// for a key, so a resolution never overtakes the firing it follows.
+type deliveryQueue struct {
+	pending []func()
+}
+
+// deliveryKey orders notifications per alert and receiver.
+func deliveryKey(alertID, receiver string) string {
+	return alertID + "|" + receiver
+}
+
+// enqueueLocked runs task after every task queued before it under the same
+// key. Retries of one notification hold back the later ones for its key.
+func (m *AlertManager) enqueueLocked(key string, task func()) {
+	if q, exists := m.queues[key]; exists {
+		q.pending = append(q.pending, task)
+		return
+	}
+	q := &deliveryQueue{pending: []func(){task}}
+	m.queues[key] = q
+	go m.drain(key, q)
+}
+
+func (m *AlertManager) drain(key string, q *deliveryQueue) {
+	for {
+		m.mu.Lock()
+		if len(q.pending) == 0 {
+			delete(m.queues, key)
+			m.mu.Unlock()
+			return
+		}
+		task := q.pending[0]
+		q.pending = q.pending[1:]
+		m.mu.Unlock()
+
+		task()
+	}
+}
+
+func (m *AlertManager) recordDelivery(receiver string, alerts []Alert, event string, attempt int, start time.Time, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, alert := range alerts {
+		d := Delivery{
+			AlertID:  alert.ID,
+			Receiver: receiver,
+			Event:    event,
+			Attempt:  attempt,
+			Status:   DeliveryDelivered,
+			At:       start,
+			Duration: time.Since(start),
+		}
+		if d.Event == "" {
+			d.Event = eventFor(alert)
+		}
+		if err != nil {
+			d.Status = DeliveryFailed
+			d.Error = err.Error()
+		}
+		log := append(m.deliveries[alert.ID], d)
+		if len(log) > maxDeliveriesPerAlert {
+			log = log[len(log)-maxDeliveriesPerAlert:]
+		}
+		m.deliveries[alert.ID] = log
+	}
+}
+
+// Deliveries returns the alert's delivery log, oldest first.
+func (m *AlertManager) Deliveries(alertID string) []Delivery {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Delivery{}, m.deliveries[alertID]...)
+}
+
+// notifierType names a default notifier in the delivery log, e.g.
+// "SlackNotifier".
+func notifierType(n AlertNotifier) string {
+	name := fmt.Sprintf("%T", n)
+	return name[strings.LastIndex(name, ".")+1:]
+}
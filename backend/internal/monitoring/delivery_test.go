@@ -0,0 +1,350 @@
+// backend/internal/monitoring/delivery_test.go
+package monitoring
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func testAlert(id string) Alert {
+	return Alert{
+		ID:        id,
+		Severity:  "critical",
+		Metric:    "cpu",
+		Value:     97,
+		Threshold: 90,
+		Message:   "CPU above 90%",
+		Timestamp: time.Now(),
+		Status:    "active",
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func deliveryStatuses(deliveries []Delivery) []string {
+	statuses := make([]string, len(deliveries))
+	for i, d := range deliveries {
+		statuses[i] = d.Event + ":" + d.Status
+	}
+	return statuses
+}
+
+// webhookServer answers each request with the next status in responses,
+// then 200, and records the status of every payload it receives.
+type webhookServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []int
+	received  []string
+}
+
+func newWebhookServer(t *testing.T, responses ...int) *webhookServer {
+	s := &webhookServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid webhook payload: %v", err)
+		}
+
+		s.mu.Lock()
+		s.received = append(s.received, payload.Status)
+		status := http.StatusOK
+		if len(s.responses) > 0 {
+			status, s.responses = s.responses[0], s.responses[1:]
+		}
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) Received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.received...)
+}
+
+func TestDeliveryRetriesTransientFailures(t *testing.T) {
+	server := newWebhookServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	m := NewAlertManager(&WebhookNotifier{URL: server.URL})
+	if err := m.SetRetryPolicy(fastRetry); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AddAlert(testAlert("a1"))
+	waitFor(t, "delivery", func() bool { return len(m.Deliveries("a1")) == 3 })
+
+	got := strings.Join(deliveryStatuses(m.Deliveries("a1")), " ")
+	if want := "firing:failed firing:failed firing:delivered"; got != want {
+		t.Fatalf("deliveries = %s, want %s", got, want)
+	}
+}
+
+func TestDeliveryGivesUpOnPermanentFailures(t *testing.T) {
+	server := newWebhookServer(t, http.StatusBadRequest)
+	m := NewAlertManager(&WebhookNotifier{URL: server.URL})
+	if err := m.SetRetryPolicy(fastRetry); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AddAlert(testAlert("a1"))
+	waitFor(t, "delivery", func() bool { return len(m.Deliveries("a1")) > 0 })
+	time.Sleep(50 * time.Millisecond)
+
+	got := strings.Join(deliveryStatuses(m.Deliveries("a1")), " ")
+	if want := "firing:failed"; got != want {
+		t.Fatalf("deliveries = %s, want %s", got, want)
+	}
+	if n := len(server.Received()); n != 1 {
+		t.Fatalf("server received %d requests, want 1", n)
+	}
+}
+
+func TestDeliveryKeepsResolutionBehindRetriedFiring(t *testing.T) {
+	server := newWebhookServer(t, http.StatusServiceUnavailable)
+	m := NewAlertManager(&WebhookNotifier{URL: server.URL})
+	// Long enough for the resolution to be raised while the firing waits
+	// to be retried
+	if err := m.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: 50 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AddAlert(testAlert("a1"))
+	if err := m.ResolveAlert("a1"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "both notifications", func() bool { return len(server.Received()) == 3 })
+
+	got := strings.Join(server.Received(), " ")
+	if want := "firing firing resolved"; got != want {
+		t.Fatalf("server received %s, want %s", got, want)
+	}
+}
+
+func TestDeliveryKeepsAcknowledgementBetweenFiringAndResolution(t *testing.T) {
+	notifier := &recordingNotifier{failFirst: 2}
+	m := NewAlertManager(notifier)
+	if err := m.SetRetryPolicy(RetryPolicy{MaxAttempts: 4, InitialBackoff: 20 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AddAlert(testAlert("a1"))
+	if _, err := m.AcknowledgeAlert("a1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ResolveAlert("a1"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "all notifications", func() bool { return len(notifier.Events()) == 3 })
+
+	got := strings.Join(notifier.Events(), " ")
+	if want := "firing acknowledged resolved"; got != want {
+		t.Fatalf("notified %s, want %s", got, want)
+	}
+}
+
+// recordingNotifier fails its first failFirst calls and records the events
+// of the calls that succeed.
+type recordingNotifier struct {
+	mu        sync.Mutex
+	failFirst int
+	events    []string
+}
+
+func (n *recordingNotifier) record(event string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.failFirst > 0 {
+		n.failFirst--
+		return fmt.Errorf("temporarily unavailable")
+	}
+	n.events = append(n.events, event)
+	return nil
+}
+
+func (n *recordingNotifier) Notify(alert Alert) error { return n.record(eventFor(alert)) }
+
+func (n *recordingNotifier) NotifyAcknowledged(alert Alert) error {
+	return n.record(EventAcknowledged)
+}
+
+func (n *recordingNotifier) Events() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string{}, n.events...)
+}
+
+// fakeSMTPServer speaks just enough SMTP for smtpSender. Each connection
+// answers RCPT with the next reply in rcptReplies, then "250 OK", and
+// records the messages it accepts.
+type fakeSMTPServer struct {
+	listener    net.Listener
+	mu          sync.Mutex
+	rcptReplies []string
+	messages    []string
+}
+
+func newFakeSMTPServer(t *testing.T, rcptReplies ...string) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{listener: listener, rcptReplies: rcptReplies}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			rcptReply := "250 OK"
+			if len(s.rcptReplies) > 0 {
+				rcptReply, s.rcptReplies = s.rcptReplies[0], s.rcptReplies[1:]
+			}
+			s.mu.Unlock()
+			go s.serve(conn, rcptReply)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn, rcptReply string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.Fields(line + " x")[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 fake")
+		case "MAIL", "RSET", "NOOP":
+			reply("250 OK")
+		case "RCPT":
+			reply(rcptReply)
+		case "DATA":
+			reply("354 go ahead")
+			var msg strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				msg.WriteString(l)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.messages...)
+}
+
+func (s *fakeSMTPServer) notifier() *EmailNotifier {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return &EmailNotifier{
+		Sender: NewSMTPSender(SMTPConfig{Server: "127.0.0.1", Port: addr.Port, From: "alerts@example.com", Timeout: time.Second}),
+		To:     []string{"oncall@example.com"},
+	}
+}
+
+func TestEmailDeliveryRetriesTransientSMTPFailures(t *testing.T) {
+	server := newFakeSMTPServer(t, "451 try again later")
+	m := NewAlertManager(server.notifier())
+	if err := m.SetRetryPolicy(fastRetry); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AddAlert(testAlert("a1"))
+	waitFor(t, "delivery", func() bool { return len(m.Deliveries("a1")) == 2 })
+
+	got := strings.Join(deliveryStatuses(m.Deliveries("a1")), " ")
+	if want := "firing:failed firing:delivered"; got != want {
+		t.Fatalf("deliveries = %s, want %s", got, want)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 || !strings.Contains(messages[0], "To: oncall@example.com") {
+		t.Fatalf("server accepted %q, want one message to oncall@example.com", messages)
+	}
+}
+
+func TestEmailDeliveryGivesUpOnPermanentSMTPFailures(t *testing.T) {
+	server := newFakeSMTPServer(t, "550 no such user")
+	m := NewAlertManager(server.notifier())
+	if err := m.SetRetryPolicy(fastRetry); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AddAlert(testAlert("a1"))
+	waitFor(t, "delivery", func() bool { return len(m.Deliveries("a1")) > 0 })
+	time.Sleep(50 * time.Millisecond)
+
+	got := strings.Join(deliveryStatuses(m.Deliveries("a1")), " ")
+	if want := "firing:failed"; got != want {
+		t.Fatalf("deliveries = %s, want %s", got, want)
+	}
+	if n := len(server.Messages()); n != 0 {
+		t.Fatalf("server accepted %d messages, want 0", n)
+	}
+}
+
+func TestEmailDeliveryKeepsResolutionBehindRetriedFiring(t *testing.T) {
+	server := newFakeSMTPServer(t, "451 try again later")
+	m := NewAlertManager(server.notifier())
+	if err := m.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: 50 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AddAlert(testAlert("a1"))
+	if err := m.ResolveAlert("a1"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "both messages", func() bool { return len(server.Messages()) == 2 })
+
+	messages := server.Messages()
+	if strings.Contains(messages[0], "Resolved:") || !strings.Contains(messages[1], "Resolved:") {
+		t.Fatalf("resolution was not sent after the firing:\n%s\n---\n%s", messages[0], messages[1])
+	}
+}
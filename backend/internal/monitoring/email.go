@@ -0,0 +1,145 @@
+// backend/internal/monitoring/email.go
+package monitoring
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailSender sends a plain-text email. It has the same signature as
+// frontend/integrations/smtp.EmailClient.SendEmail, so either can back an
+// EmailNotifier. Senders that also implement HTMLEmailSender get the HTML
+// template too.
+type EmailSender interface {
+	SendEmail(to []string, subject, body string) error
+}
+
+// HTMLEmailSender sends a multipart email with text and HTML bodies.
+type HTMLEmailSender interface {
+	SendHTMLEmail(to []string, subject, text, html string) error
+}
+
+type SMTPConfig struct {
+	Server   string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration // for the whole exchange; defaults to 10s
+}
+
+type smtpSender struct {
+	config SMTPConfig
+}
+
+// NewSMTPSender sends through an SMTP server, upgrading to TLS when the
+// server offers STARTTLS and authenticating when a username is set.
+func NewSMTPSender(config SMTPConfig) EmailSender {
+	return &smtpSender{config: config}
+}
+
+func (s *smtpSender) SendEmail(to []string, subject, body string) error {
+	return s.send(to, buildMessage(s.config.From, to, subject, "text/plain; charset=UTF-8", crlf(body)))
+}
+
+func (s *smtpSender) SendHTMLEmail(to []string, subject, text, html string) error {
+	boundary := fmt.Sprintf("autosysadmin-%d", time.Now().UnixNano())
+	var body strings.Builder
+	fmt.Fprintf(&body, "--%s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n", boundary, crlf(text))
+	fmt.Fprintf(&body, "--%s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s\r\n", boundary, crlf(html))
+	fmt.Fprintf(&body, "--%s--\r\n", boundary)
+	contentType := fmt.Sprintf("multipart/alternative; boundary=%q", boundary)
+	return s.send(to, buildMessage(s.config.From, to, subject, contentType, body.String()))
+}
+
+func (s *smtpSender) send(to []string, message []byte) error {
+	timeout := notifyTimeout(s.config.Timeout)
+	addr := net.JoinHostPort(s.config.Server, strconv.Itoa(s.config.Port))
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, s.config.Server)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Server}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Server)
+		if err := client.Auth(auth); err != nil {
+			return smtpError("failed to authenticate", err)
+		}
+	}
+
+	if err := client.Mail(s.config.From); err != nil {
+		return smtpError("failed to set sender", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return smtpError("failed to set recipient", err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError("failed to prepare data", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("failed to send message", err)
+	}
+	return client.Quit()
+}
+
+// smtpError marks 5xx replies permanent; 4xx replies are transient by
+// definition and worth retrying.
+func smtpError(msg string, err error) error {
+	err = fmt.Errorf("%s: %w", msg, err)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
+
+func buildMessage(from string, to []string, subject, contentType, body string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: %s\r\n", contentType)
+	b.WriteString("\r\n")
+	b.WriteString(body)
+	return []byte(b.String())
+}
+
+// crlf converts line endings to the CRLF SMTP requires.
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// headerValue keeps alert text from injecting extra headers.
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
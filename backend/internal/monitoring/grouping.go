@@ -0,0 +1,224 @@
+// backend/internal/monitoring/grouping.go
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupConfig batches alerts that share the GroupBy labels (and receivers)
+// into one notification. A new group waits GroupWait before its first
+// notification so related alerts arrive together, changes to a group are
+// sent at most every GroupInterval, and a group that is still firing is
+// re-sent every RepeatInterval.
+type GroupConfig struct {
+	GroupBy        []string      `json:"group_by"`
+	GroupWait      time.Duration `json:"group_wait"`
+	GroupInterval  time.Duration `json:"group_interval"`
+	RepeatInterval time.Duration `json:"repeat_interval"`
+}
+
+var DefaultGroupConfig = GroupConfig{
+	GroupBy:        []string{"alertname"},
+	GroupWait:      30 * time.Second,
+	GroupInterval:  5 * time.Minute,
+	RepeatInterval: 4 * time.Hour,
+}
+
+func (c *GroupConfig) validate() error {
+	for _, name := range c.GroupBy {
+		if !labelNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	if c.GroupWait < 0 {
+		return fmt.Errorf("group_wait must not be negative")
+	}
+	if c.GroupInterval <= 0 || c.RepeatInterval <= 0 {
+		return fmt.Errorf("group_interval and repeat_interval must be positive")
+	}
+	return nil
+}
+
+// AlertGroup is one grouped notification: the firing alerts of the group
+// and those resolved since the previous notification.
+type AlertGroup struct {
+	Key       string            `json:"key"`
+	Labels    map[string]string `json:"labels"` // the group_by labels
+	Receivers []string          `json:"receivers,omitempty"`
+	Alerts    []Alert           `json:"alerts"`
+}
+
+// GroupNotifier is implemented by notifiers that can send a whole group as
+// one message. Other notifiers get one Notify call per alert in the group.
+type GroupNotifier interface {
+	NotifyGroup(group AlertGroup) error
+}
+
+type alertGroup struct {
+	key       string
+	labels    map[string]string
+	receivers []string
+	alerts    map[string]Alert  // alertID -> latest state
+	sent      map[string]string // alertID -> status last notified
+	createdAt time.Time
+	flushedAt time.Time // zero until the first flush
+	changed   bool
+}
+
+func (g *alertGroup) hasActive() bool {
+	for _, alert := range g.alerts {
+		if alert.Status == "active" {
+			return true
+		}
+	}
+	return false
+}
+
+// SetGrouping turns on grouped notifications. Without it every alert is
+// notified on its own as soon as it fires or resolves. Run must be running
+// for grouped notifications to go out.
+func (m *AlertManager) SetGrouping(config GroupConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.grouping = &config
+	return nil
+}
+
+// Run flushes alert groups as their timers come due and notifies alerts
+// whose maintenance window has ended.
+func (m *AlertManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			m.unsuppressLocked()
+			m.flushGroupsLocked(now)
+			m.mu.Unlock()
+		}
+	}
+}
+
+// ListGroups returns the current alert groups.
+func (m *AlertManager) ListGroups() []AlertGroup {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groups := make([]AlertGroup, 0, len(m.groups))
+	for _, g := range m.groups {
+		group := AlertGroup{Key: g.key, Labels: g.labels, Receivers: g.receivers}
+		for _, alert := range g.alerts {
+			group.Alerts = append(group.Alerts, alert)
+		}
+		sortAlerts(group.Alerts)
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+func (m *AlertManager) groupLocked(alert Alert) {
+	labels := AlertLabels(alert)
+	groupLabels := make(map[string]string, len(m.grouping.GroupBy))
+	parts := []string{strings.Join(alert.Receivers, ",")}
+	for _, name := range m.grouping.GroupBy {
+		groupLabels[name] = labels[name]
+		parts = append(parts, name+"="+labels[name])
+	}
+	key := strings.Join(parts, "|")
+
+	g, exists := m.groups[key]
+	if !exists {
+		if alert.Status != "active" {
+			return // resolved before it was ever grouped; nothing to tell
+		}
+		g = &alertGroup{
+			key:       key,
+			labels:    groupLabels,
+			receivers: alert.Receivers,
+			alerts:    make(map[string]Alert),
+			sent:      make(map[string]string),
+			createdAt: time.Now(),
+		}
+		m.groups[key] = g
+	}
+	g.alerts[alert.ID] = alert
+	g.changed = true
+}
+
+func (m *AlertManager) flushGroupsLocked(now time.Time) {
+	for key, g := range m.groups {
+		var due bool
+		switch {
+		case g.flushedAt.IsZero():
+			due = now.Sub(g.createdAt) >= m.grouping.GroupWait
+		case g.changed || m.hasUnsentLocked(g, now):
+			due = now.Sub(g.flushedAt) >= m.grouping.GroupInterval
+		default:
+			due = g.hasActive() && now.Sub(g.flushedAt) >= m.grouping.RepeatInterval
+		}
+		if due {
+			m.flushGroupLocked(g, now)
+		}
+		if len(g.alerts) == 0 {
+			delete(m.groups, key)
+		}
+	}
+}
+
+// hasUnsentLocked reports whether the group has a firing alert that hasn't
+// been notified yet, e.g. because the silence muting it has expired.
+func (m *AlertManager) hasUnsentLocked(g *alertGroup, now time.Time) bool {
+	for id, alert := range g.alerts {
+		if alert.Status != "active" || g.sent[id] == "active" {
+			continue
+		}
+		if silenced, inhibited := m.mutedLocked(alert, now); len(silenced) == 0 && len(inhibited) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// flushGroupLocked sends the group's unmuted firing alerts, plus the
+// resolutions of alerts whose firing was sent, and forgets resolved alerts.
+func (m *AlertManager) flushGroupLocked(g *alertGroup, now time.Time) {
+	var alerts []Alert
+	for id, alert := range g.alerts {
+		if alert.Status != "active" {
+			if g.sent[id] == "active" {
+				alerts = append(alerts, alert)
+			}
+			delete(g.alerts, id)
+			delete(g.sent, id)
+			continue
+		}
+		if silenced, inhibited := m.mutedLocked(alert, now); len(silenced) == 0 && len(inhibited) == 0 {
+			alerts = append(alerts, alert)
+			g.sent[id] = "active"
+		}
+	}
+	g.changed = false
+	g.flushedAt = now
+
+	if len(alerts) == 0 {
+		return
+	}
+	sortAlerts(alerts)
+	m.notifyGroupLocked(AlertGroup{Key: g.key, Labels: g.labels, Receivers: g.receivers, Alerts: alerts})
+}
+
+func sortAlerts(alerts []Alert) {
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Timestamp.Before(alerts[j].Timestamp) })
+}
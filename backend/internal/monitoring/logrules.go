@@ -0,0 +1,258 @@
+// backend/internal/monitoring/logrules.go
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/logs"
+)
+
+// LogAlertRule fires when more than Threshold log lines matching Pattern or
+// Keyword arrive from one agent within Window.
+type LogAlertRule struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Selector  agent.Selector `json:"selector"`
+	Pattern   string         `json:"pattern,omitempty"` // regular expression
+	Keyword   string         `json:"keyword,omitempty"` // case-insensitive substring
+	Unit      string         `json:"unit,omitempty"`
+	Path      string         `json:"path,omitempty"`
+	Threshold int            `json:"threshold"`
+	Window    time.Duration  `json:"window"`
+	Severity  string         `json:"severity"` // info, warning, critical
+}
+
+type compiledLogRule struct {
+	rule    LogAlertRule
+	pattern *regexp.Regexp
+	keyword string
+}
+
+func (r *compiledLogRule) match(line logs.LogLine) bool {
+	if r.rule.Unit != "" && line.Unit != r.rule.Unit {
+		return false
+	}
+	if r.rule.Path != "" && line.Path != r.rule.Path {
+		return false
+	}
+	if r.pattern != nil {
+		return r.pattern.MatchString(line.Message)
+	}
+	return strings.Contains(strings.ToLower(line.Message), r.keyword)
+}
+
+// LogRuleEvaluator counts matching log lines per (rule, agent) over a
+// sliding window and raises and resolves alerts through the AlertManager.
+type LogRuleEvaluator struct {
+	agentManager *agent.Manager
+	alertManager *AlertManager
+	rules        map[string]*compiledLogRule       // ruleID -> rule
+	matches      map[string]map[string][]time.Time // ruleID -> agentID -> match times
+	firing       map[string]map[string]string      // ruleID -> agentID -> alertID
+	mu           sync.Mutex
+}
+
+func NewLogRuleEvaluator(agentManager *agent.Manager, alertManager *AlertManager) *LogRuleEvaluator {
+	return &LogRuleEvaluator{
+		agentManager: agentManager,
+		alertManager: alertManager,
+		rules:        make(map[string]*compiledLogRule),
+		matches:      make(map[string]map[string][]time.Time),
+		firing:       make(map[string]map[string]string),
+	}
+}
+
+func (e *LogRuleEvaluator) AddRule(rule LogAlertRule) (*LogAlertRule, error) {
+	if rule.Name == "" {
+		return nil, fmt.Errorf("rule name is required")
+	}
+	if (rule.Pattern == "") == (rule.Keyword == "") {
+		return nil, fmt.Errorf("exactly one of pattern or keyword is required")
+	}
+	if rule.Threshold < 0 {
+		return nil, fmt.Errorf("threshold must not be negative")
+	}
+	if rule.Window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+	if rule.Severity == "" {
+		rule.Severity = "warning"
+	}
+
+	compiled := &compiledLogRule{rule: rule, keyword: strings.ToLower(rule.Keyword)}
+	if rule.Pattern != "" {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		compiled.pattern = re
+	}
+
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("logrule-%d", time.Now().UnixNano())
+		compiled.rule.ID = rule.ID
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Replacing a rule starts its counts over
+	e.resolveRuleLocked(rule.ID)
+	e.rules[rule.ID] = compiled
+	e.matches[rule.ID] = make(map[string][]time.Time)
+	e.firing[rule.ID] = make(map[string]string)
+	return &rule, nil
+}
+
+func (e *LogRuleEvaluator) RemoveRule(ruleID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.rules[ruleID]; !exists {
+		return fmt.Errorf("log alert rule not found")
+	}
+
+	e.resolveRuleLocked(ruleID)
+	delete(e.rules, ruleID)
+	delete(e.matches, ruleID)
+	delete(e.firing, ruleID)
+	return nil
+}
+
+func (e *LogRuleEvaluator) ListRules() []LogAlertRule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rules := make([]LogAlertRule, 0, len(e.rules))
+	for _, r := range e.rules {
+		rules = append(rules, r.rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// Run re-evaluates periodically until the context is cancelled, so alerts
+// resolve once matches age out of the window even if the agent goes quiet.
+func (e *LogRuleEvaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.sweep()
+		}
+	}
+}
+
+// Process counts a batch of one agent's ingested lines. It is meant to be
+// registered with logs.Service.OnIngest, which sees every line, so counts
+// over a window are exact however large the batch.
+func (e *LogRuleEvaluator) Process(lines []logs.LogLine) {
+	if len(lines) == 0 {
+		return
+	}
+	a, exists := e.agentManager.GetAgent(lines[0].AgentID)
+	if !exists {
+		return
+	}
+
+	// Windows are measured in backend time so agent clock skew can't keep
+	// an alert firing or hide a burst
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ruleID, r := range e.rules {
+		if !r.rule.Selector.Matches(a) {
+			continue
+		}
+		matched := 0
+		for _, line := range lines {
+			if r.match(line) {
+				matched++
+			}
+		}
+		if matched == 0 {
+			continue
+		}
+		times := trimBefore(e.matches[ruleID][a.ID], now.Add(-r.rule.Window))
+		for i := 0; i < matched; i++ {
+			times = append(times, now)
+		}
+		e.matches[ruleID][a.ID] = times
+		e.evaluateLocked(r, a.ID, len(times))
+	}
+}
+
+func (e *LogRuleEvaluator) sweep() {
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ruleID, r := range e.rules {
+		for agentID, times := range e.matches[ruleID] {
+			times = trimBefore(times, now.Add(-r.rule.Window))
+			if len(times) == 0 {
+				delete(e.matches[ruleID], agentID)
+			} else {
+				e.matches[ruleID][agentID] = times
+			}
+			e.evaluateLocked(r, agentID, len(times))
+		}
+	}
+}
+
+func (e *LogRuleEvaluator) evaluateLocked(r *compiledLogRule, agentID string, count int) {
+	alertID, firing := e.firing[r.rule.ID][agentID]
+
+	switch {
+	case count > r.rule.Threshold && !firing:
+		what := r.rule.Keyword
+		if r.pattern != nil {
+			what = r.rule.Pattern
+		}
+		alert := Alert{
+			ID:        fmt.Sprintf("%s-%s-%d", r.rule.ID, agentID, time.Now().UnixNano()),
+			AgentID:   agentID,
+			Metric:    "log:" + r.rule.Name,
+			Value:     float64(count),
+			Threshold: float64(r.rule.Threshold),
+			Message:   fmt.Sprintf("%d log lines matching %q in the last %s (threshold %d)", count, what, r.rule.Window, r.rule.Threshold),
+			Timestamp: time.Now(),
+			Status:    "active",
+		}
+		e.firing[r.rule.ID][agentID] = alert.ID
+		e.alertManager.AddAlert(alert)
+
+	case count <= r.rule.Threshold && firing:
+		delete(e.firing[r.rule.ID], agentID)
+		e.alertManager.ResolveAlert(alertID)
+	}
+}
+
+func (e *LogRuleEvaluator) resolveRuleLocked(ruleID string) {
+	for _, alertID := range e.firing[ruleID] {
+		e.alertManager.ResolveAlert(alertID)
+	}
+}
+
+// trimBefore drops the leading timestamps older than cutoff.
+func trimBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
@@ -2,7 +2,7 @@
 package monitoring
 
 import (
-	"context"
+	"fmt"
 	"sync"
 	"time"
 )
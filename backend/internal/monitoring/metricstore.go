@@ -0,0 +1,73 @@
+// backend/internal/monitoring/metricstore.go
+package monitoring
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/tsdb"
+)
+
+type tsdbMetricsStorage struct {
+	db *tsdb.DB
+}
+
+// NewTSDBMetricsStorage keeps metrics in the embedded time-series store,
+// one series per agent, metric name and label set.
+func NewTSDBMetricsStorage(db *tsdb.DB) MetricsStorage {
+	return &tsdbMetricsStorage{db: db}
+}
+
+func (s *tsdbMetricsStorage) Store(metrics []Metric) error {
+	samples := make([]tsdb.Sample, 0, len(metrics))
+	for _, metric := range metrics {
+		labels := make(tsdb.Labels, len(metric.Labels)+2)
+		for name, value := range metric.Labels {
+			labels[name] = value
+		}
+		labels[tsdb.MetricName] = metric.Name
+		labels["agent_id"] = metric.AgentID
+		samples = append(samples, tsdb.Sample{
+			Labels: labels,
+			Time:   metric.Timestamp,
+			Value:  metric.Value,
+		})
+	}
+	return s.db.Append(samples...)
+}
+
+// Query reads raw samples while they're retained and the average of each
+// 1m or 1h rollup for older ranges.
+func (s *tsdbMetricsStorage) Query(agentID string, start, end time.Time) ([]Metric, error) {
+	series, err := s.db.Query(tsdb.Labels{"agent_id": agentID}, start, end, s.db.ResolutionFor(start))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Metric
+	for _, ser := range series {
+		labels := make(map[string]string, len(ser.Labels))
+		for name, value := range ser.Labels {
+			if name != tsdb.MetricName && name != "agent_id" {
+				labels[name] = value
+			}
+		}
+		for _, p := range ser.Points {
+			result = append(result, Metric{
+				AgentID:   agentID,
+				Name:      ser.Labels[tsdb.MetricName],
+				Labels:    labels,
+				Value:     p.Avg(),
+				Timestamp: p.Time,
+			})
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no metrics found for agent %s", agentID)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result, nil
+}
@@ -4,6 +4,7 @@ package monitoring
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,38 +19,65 @@ type Monitor interface {
 	SetAlertThreshold(agentID, metric string, threshold float64) error
 }
 
+// Metric is a sample of one series, identified by the agent, the metric
+// name and its labels, e.g. filesystem_usage{mount="/var",env="prod"}.
 type Metric struct {
-	Name      string    `json:"name"`
-	Value     float64   `json:"value"`
-	Timestamp time.Time `json:"timestamp"`
+	AgentID   string            `json:"agent_id,omitempty"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     float64           `json:"value"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Labels the monitor sets itself, which agent tags can't override.
+var reservedMetricLabels = map[string]bool{
+	"agent_id":  true,
+	"hostname":  true,
+	"mount":     true,
+	"device":    true,
+	"interface": true,
 }
 
 type Alert struct {
-	ID        string    `json:"id"`
-	AgentID   string    `json:"agent_id"`
-	Metric    string    `json:"metric"`
-	Value     float64   `json:"value"`
-	Threshold float64   `json:"threshold"`
-	Message   string    `json:"message"`
-	Timestamp time.Time `json:"timestamp"`
-	Status    string    `json:"status"` // active, resolved
+	ID             string            `json:"id"`
+	AgentID        string            `json:"agent_id"`
+	RuleID         string            `json:"rule_id,omitempty"`
+	Severity       string            `json:"severity,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	RunbookURL     string            `json:"runbook_url,omitempty"`
+	Receivers      []string          `json:"receivers,omitempty"` // empty means the default notifiers
+	Metric         string            `json:"metric"`
+	Value          float64           `json:"value"`
+	Threshold      float64           `json:"threshold"`
+	Message        string            `json:"message"`
+	Timestamp      time.Time         `json:"timestamp"`
+	Status         string            `json:"status"` // active, resolved
+	ResolvedAt     *time.Time        `json:"resolved_at,omitempty"`
+	Suppressed     bool              `json:"suppressed,omitempty"` // raised during maintenance; not notified
+	AcknowledgedBy string            `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time        `json:"acknowledged_at,omitempty"`
 }
 
+// GetMetrics returns this much history; older data is read through the
+// MetricsStorage.
+const recentMetricsWindow = time.Hour
+
 type monitor struct {
 	agentManager *agent.Manager
-	metrics      map[string][]Metric // agentID -> metrics
-	alerts       map[string][]Alert // agentID -> alerts
-	thresholds   map[string]map[string]float64 // agentID -> metric -> threshold
+	rules        *RuleEngine
+	storage      MetricsStorage
 	mu           sync.RWMutex
 	cancelFuncs  map[string]context.CancelFunc // agentID -> cancelFunc
 }
 
-func NewMonitor() Monitor {
+// NewMonitor samples agent metrics into storage and hands each sample to
+// the rule engine, which owns alerting.
+func NewMonitor(agentManager *agent.Manager, rules *RuleEngine, storage MetricsStorage) Monitor {
 	return &monitor{
-		metrics:     make(map[string][]Metric),
-		alerts:      make(map[string][]Alert),
-		thresholds:  make(map[string]map[string]float64),
-		cancelFuncs: make(map[string]context.CancelFunc),
+		agentManager: agentManager,
+		rules:        rules,
+		storage:      storage,
+		cancelFuncs:  make(map[string]context.CancelFunc),
 	}
 }
 
@@ -91,85 +119,123 @@ func (m *monitor) monitorAgent(ctx context.Context, agentID string, interval tim
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			agent, exists := m.agentManager.GetAgent(agentID)
-			if !exists {
-				continue
-			}
-
-			stats, err := agent.CollectStats()
-			if err != nil {
+			labels := m.agentLabels(agentID)
+			stats, err := m.agentManager.GetStats(agentID)
+			if err != nil || !m.agentManager.Alive(agentID) {
+				// "up" lets a rule alert on the agent being unreachable, and
+				// an inhibit rule mute that agent's other alerts meanwhile.
+				// Down means no heartbeat or no fresh stats; either way the
+				// other values would be stale or made up
+				metrics := []Metric{{AgentID: agentID, Name: "up", Labels: labels, Value: 0, Timestamp: time.Now()}}
+				m.record(metrics)
+				m.rules.Evaluate(agentID, metrics)
 				continue
 			}
 
 			// Record metrics
+			now := time.Now()
 			metrics := []Metric{
-				{Name: "cpu", Value: stats.System.CPUUsage, Timestamp: time.Now()},
-				{Name: "memory", Value: stats.System.MemoryUsage, Timestamp: time.Now()},
-				{Name: "disk", Value: stats.System.DiskUsage, Timestamp: time.Now()},
-				{Name: "network_in", Value: stats.System.NetworkIn, Timestamp: time.Now()},
-				{Name: "network_out", Value: stats.System.NetworkOut, Timestamp: time.Now()},
+				{AgentID: agentID, Name: "cpu", Labels: labels, Value: stats.System.CPUUsage, Timestamp: now},
+				{AgentID: agentID, Name: "memory", Labels: labels, Value: stats.System.MemoryUsage, Timestamp: now},
+				{AgentID: agentID, Name: "disk", Labels: labels, Value: stats.System.DiskUsage, Timestamp: now},
+				{AgentID: agentID, Name: "network_in", Labels: labels, Value: stats.System.NetworkIn, Timestamp: now},
+				{AgentID: agentID, Name: "network_out", Labels: labels, Value: stats.System.NetworkOut, Timestamp: now},
+				{AgentID: agentID, Name: "up", Labels: labels, Value: 1, Timestamp: now},
 			}
+			m.record(append(breakdownMetrics(agentID, labels, stats.System, now), metrics...))
 
-			m.mu.Lock()
-			m.metrics[agentID] = append(m.metrics[agentID], metrics...)
-			
-			// Check thresholds and generate alerts
-			for _, metric := range metrics {
-				if thresholds, ok := m.thresholds[agentID]; ok {
-					if threshold, ok := thresholds[metric.Name]; ok {
-						if metric.Value > threshold {
-							alert := Alert{
-								ID:        fmt.Sprintf("%s-%s-%d", agentID, metric.Name, time.Now().Unix()),
-								AgentID:   agentID,
-								Metric:    metric.Name,
-								Value:     metric.Value,
-								Threshold: threshold,
-								Message:   fmt.Sprintf("%s exceeded threshold (%.2f > %.2f)", metric.Name, metric.Value, threshold),
-								Timestamp: time.Now(),
-								Status:    "active",
-							}
-							m.alerts[agentID] = append(m.alerts[agentID], alert)
-						}
-					}
-				}
-			}
-			m.mu.Unlock()
+			// Rules see the agent-wide metrics, which have one value per name
+			m.rules.Evaluate(agentID, metrics)
 		}
 	}
 }
 
-func (m *monitor) GetMetrics(agentID string) ([]Metric, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// agentLabels are the labels on every series of an agent: its hostname and
+// its "key:value" or "key=value" tags, e.g. env:prod.
+func (m *monitor) agentLabels(agentID string) map[string]string {
+	a, ok := m.agentManager.GetAgent(agentID)
+	if !ok {
+		return nil
+	}
+	labels := map[string]string{"hostname": a.Hostname}
+	for _, tag := range a.Tags {
+		name, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			name, value, ok = strings.Cut(tag, "=")
+		}
+		if ok && value != "" && labelNamePattern.MatchString(name) && !reservedMetricLabels[name] {
+			labels[name] = value
+		}
+	}
+	return labels
+}
 
-	metrics, exists := m.metrics[agentID]
-	if !exists {
-		return nil, fmt.Errorf("no metrics found for agent %s", agentID)
+// breakdownMetrics are the per-mount and per-interface series.
+func breakdownMetrics(agentID string, labels map[string]string, stats agent.SystemStats, now time.Time) []Metric {
+	var metrics []Metric
+	for _, d := range stats.Disks {
+		metrics = append(metrics, Metric{
+			AgentID:   agentID,
+			Name:      "filesystem_usage",
+			Labels:    withLabel(withLabel(labels, "mount", d.Mount), "device", d.Device),
+			Value:     d.Usage,
+			Timestamp: now,
+		})
+	}
+	for _, iface := range stats.Interfaces {
+		ifaceLabels := withLabel(labels, "interface", iface.Name)
+		metrics = append(metrics,
+			Metric{AgentID: agentID, Name: "network_interface_in_bytes", Labels: ifaceLabels, Value: iface.BytesIn, Timestamp: now},
+			Metric{AgentID: agentID, Name: "network_interface_out_bytes", Labels: ifaceLabels, Value: iface.BytesOut, Timestamp: now},
+		)
 	}
+	return metrics
+}
 
-	return metrics, nil
+// withLabel copies labels with one more set, unless value is empty.
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	if value == "" {
+		return labels
+	}
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[name] = value
+	return out
 }
 
-func (m *monitor) GetAlerts(agentID string) ([]Alert, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+func (m *monitor) record(metrics []Metric) {
+	if err := m.storage.Store(metrics); err != nil {
+		fmt.Printf("Failed to store metrics: %v\n", err)
+	}
+}
 
-	alerts, exists := m.alerts[agentID]
-	if !exists {
+// GetMetrics returns the agent's metrics from the last hour.
+func (m *monitor) GetMetrics(agentID string) ([]Metric, error) {
+	now := time.Now()
+	return m.storage.Query(agentID, now.Add(-recentMetricsWindow), now)
+}
+
+func (m *monitor) GetAlerts(agentID string) ([]Alert, error) {
+	alerts := m.rules.alertManager.GetAgentAlerts(agentID)
+	if len(alerts) == 0 {
 		return nil, fmt.Errorf("no alerts found for agent %s", agentID)
 	}
 
 	return alerts, nil
 }
 
+// SetAlertThreshold keeps the simple per-agent "metric > threshold" API as
+// a rule scoped to the one agent.
 func (m *monitor) SetAlertThreshold(agentID, metric string, threshold float64) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if _, exists := m.thresholds[agentID]; !exists {
-		m.thresholds[agentID] = make(map[string]float64)
-	}
-
-	m.thresholds[agentID][metric] = threshold
-	return nil
-}
\ No newline at end of file
+	_, err := m.rules.AddRule(AlertRule{
+		ID:        fmt.Sprintf("threshold-%s-%s", agentID, metric),
+		Name:      fmt.Sprintf("%s threshold", metric),
+		Selector:  agent.Selector{AgentIDs: []string{agentID}},
+		Metric:    metric,
+		Operator:  OpGreater,
+		Threshold: threshold,
+	})
+	return err
+}
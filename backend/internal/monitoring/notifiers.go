@@ -0,0 +1,228 @@
+// backend/internal/monitoring/notifiers.go
+package monitoring
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Used when a notifier doesn't set its own Timeout
+const defaultNotifyTimeout = 10 * time.Second
+
+func notifyTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultNotifyTimeout
+	}
+	return timeout
+}
+
+// EmailNotifier implements AlertNotifier for email notifications. The
+// body is the text template, plus the HTML one when the sender supports
+// HTML email.
+type EmailNotifier struct {
+	Sender    EmailSender
+	To        []string
+	Templates *TemplateRenderer // nil uses the default templates
+}
+
+func (n *EmailNotifier) Notify(alert Alert) error {
+	return n.send([]Alert{alert}, nil)
+}
+
+func (n *EmailNotifier) NotifyGroup(group AlertGroup) error {
+	return n.send(group.Alerts, group.Labels)
+}
+
+func (n *EmailNotifier) send(alerts []Alert, groupLabels map[string]string) error {
+	if len(n.To) == 0 {
+		return Permanent(fmt.Errorf("email notifier has no recipients"))
+	}
+	templates := rendererOrDefault(n.Templates)
+	subject, text, err := templates.RenderAll(ChannelText, alerts, groupLabels)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	sender, ok := n.Sender.(HTMLEmailSender)
+	if !ok {
+		return n.Sender.SendEmail(n.To, subject, text)
+	}
+	_, html, err := templates.RenderAll(ChannelHTML, alerts, groupLabels)
+	if err != nil {
+		return Permanent(err)
+	}
+	return sender.SendHTMLEmail(n.To, subject, text, html)
+}
+
+// SlackNotifier implements AlertNotifier for Slack incoming webhooks,
+// sending the Block Kit blocks rendered by the slack template.
+type SlackNotifier struct {
+	WebhookURL string
+	Timeout    time.Duration
+	Templates  *TemplateRenderer // nil uses the default templates
+}
+
+type slackMessage struct {
+	Text   string          `json:"text"` // fallback for notifications
+	Blocks json.RawMessage `json:"blocks"`
+}
+
+func (n *SlackNotifier) Notify(alert Alert) error {
+	return n.post([]Alert{alert}, nil)
+}
+
+func (n *SlackNotifier) NotifyGroup(group AlertGroup) error {
+	return n.post(group.Alerts, group.Labels)
+}
+
+func (n *SlackNotifier) post(alerts []Alert, groupLabels map[string]string) error {
+	title, blocks, err := rendererOrDefault(n.Templates).RenderAll(ChannelSlack, alerts, groupLabels)
+	if err != nil {
+		return Permanent(err)
+	}
+	body, err := json.Marshal(slackMessage{Text: title, Blocks: json.RawMessage(blocks)})
+	if err != nil {
+		return Permanent(err)
+	}
+	return postJSON(notifyTimeout(n.Timeout), n.WebhookURL, body, nil)
+}
+
+// WebhookNotifier implements AlertNotifier for generic JSON webhooks. With
+// a Secret, each request carries X-Autosysadmin-Timestamp (Unix seconds)
+// and X-Autosysadmin-Signature, "sha256=" followed by the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the secret. Receivers should recompute
+// it and reject stale timestamps to stop replays.
+type WebhookNotifier struct {
+	URL     string
+	Secret  string
+	Timeout time.Duration
+}
+
+const (
+	webhookTimestampHeader = "X-Autosysadmin-Timestamp"
+	webhookSignatureHeader = "X-Autosysadmin-Signature"
+)
+
+type webhookPayload struct {
+	Version     string            `json:"version"`
+	Status      string            `json:"status"` // firing, resolved
+	GroupKey    string            `json:"group_key,omitempty"`
+	GroupLabels map[string]string `json:"group_labels,omitempty"`
+	Alerts      []Alert           `json:"alerts"`
+	SentAt      time.Time         `json:"sent_at"`
+}
+
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	return n.post(webhookPayload{Alerts: []Alert{alert}})
+}
+
+func (n *WebhookNotifier) NotifyGroup(group AlertGroup) error {
+	return n.post(webhookPayload{GroupKey: group.Key, GroupLabels: group.Labels, Alerts: group.Alerts})
+}
+
+func (n *WebhookNotifier) post(payload webhookPayload) error {
+	payload.Version = "1"
+	payload.Status = "resolved"
+	for _, alert := range payload.Alerts {
+		if alert.Status == "active" {
+			payload.Status = "firing"
+			break
+		}
+	}
+	payload.SentAt = time.Now().UTC()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Permanent(err)
+	}
+	var headers map[string]string
+	if n.Secret != "" {
+		timestamp := strconv.FormatInt(payload.SentAt.Unix(), 10)
+		headers = map[string]string{
+			webhookTimestampHeader: timestamp,
+			webhookSignatureHeader: "sha256=" + SignWebhook(n.Secret, timestamp, body),
+		}
+	}
+	return postJSON(notifyTimeout(n.Timeout), n.URL, body, headers)
+}
+
+// SignWebhook computes the hex signature sent in X-Autosysadmin-Signature.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postJSON posts body to url. Rate limiting and server errors are worth
+// retrying; any other non-2xx response is permanent. The URL is left out
+// of errors since webhook URLs often embed credentials.
+func postJSON(timeout time.Duration, endpoint string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid notification URL: %w", stripURL(err)))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "autosysadmin-alerts")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notification request failed: %w", stripURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("notification endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return Permanent(err)
+}
+
+// stripURL drops the URL from an *url.Error, keeping the cause.
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// truncate shortens s to at most max characters.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,202 @@
+// backend/internal/monitoring/paging.go
+package monitoring
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DedupKey identifies an alert by what raised it and where, rather than by
+// alert ID, so a paging service sees every firing of a rule on an agent as
+// one incident and our resolve closes the page it opened. Alerts not raised
+// by a rule are told apart by their full label set, since their metric and
+// agent alone can be shared by unrelated alerts.
+func DedupKey(alert Alert) string {
+	if alert.RuleID != "" {
+		return fmt.Sprintf("autosysadmin/%s/%s", alert.RuleID, alert.AgentID)
+	}
+	// encoding/json writes map keys sorted, so equal label sets hash equally
+	labels, _ := json.Marshal(AlertLabels(alert))
+	sum := sha256.Sum256(labels)
+	return fmt.Sprintf("autosysadmin/labels/%s", hex.EncodeToString(sum[:16]))
+}
+
+const defaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyNotifier sends alerts to a PagerDuty service as Events API v2
+// trigger, acknowledge and resolve events.
+type PagerDutyNotifier struct {
+	RoutingKey string
+	URL        string // defaults to the public Events API v2 endpoint
+	Timeout    time.Duration
+	Templates  *TemplateRenderer // renders the summary; nil uses the default templates
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // trigger, acknowledge, resolve
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Client      string            `json:"client,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"` // critical, error, warning, info
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func (n *PagerDutyNotifier) Notify(alert Alert) error {
+	event := pagerDutyEvent{EventAction: "resolve"}
+	if alert.Status == "active" {
+		templates := rendererOrDefault(n.Templates)
+		summary, err := templates.Render(ChannelSubject, templates.Data([]Alert{alert}, nil))
+		if err != nil {
+			return Permanent(err)
+		}
+		event.EventAction = "trigger"
+		event.Payload = &pagerDutyPayload{
+			Summary:       truncate(summary, 1024),
+			Source:        alert.AgentID,
+			Severity:      pagerDutySeverity(alert.Severity),
+			Timestamp:     alert.Timestamp.UTC().Format(time.RFC3339),
+			Component:     alert.Metric,
+			Class:         AlertLabels(alert)["alertname"],
+			CustomDetails: pagingDetails(alert),
+		}
+	}
+	return n.send(event, alert)
+}
+
+func (n *PagerDutyNotifier) NotifyAcknowledged(alert Alert) error {
+	return n.send(pagerDutyEvent{EventAction: "acknowledge"}, alert)
+}
+
+func (n *PagerDutyNotifier) send(event pagerDutyEvent, alert Alert) error {
+	event.RoutingKey = n.RoutingKey
+	event.DedupKey = DedupKey(alert)
+	event.Client = "autosysadmin"
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return Permanent(err)
+	}
+	endpoint := n.URL
+	if endpoint == "" {
+		endpoint = defaultPagerDutyURL
+	}
+	return postJSON(notifyTimeout(n.Timeout), endpoint, body, nil)
+}
+
+func pagerDutySeverity(severity string) string {
+	switch severity {
+	case "critical", "warning", "info":
+		return severity
+	}
+	return "error"
+}
+
+const defaultOpsgenieURL = "https://api.opsgenie.com"
+
+// OpsgenieNotifier creates, acknowledges and closes Opsgenie alerts through
+// the Alert API, using the dedup key as the Opsgenie alias.
+type OpsgenieNotifier struct {
+	APIKey    string
+	URL       string // defaults to https://api.opsgenie.com; use api.eu.opsgenie.com for EU accounts
+	Timeout   time.Duration
+	Templates *TemplateRenderer // nil uses the default templates
+}
+
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"` // P1 highest to P5
+}
+
+type opsgenieAction struct {
+	User   string `json:"user,omitempty"`
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+func (n *OpsgenieNotifier) Notify(alert Alert) error {
+	alias := DedupKey(alert)
+	if alert.Status != "active" {
+		return n.post("/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias",
+			opsgenieAction{Source: "autosysadmin", Note: "Resolved in autosysadmin"})
+	}
+
+	labels := AlertLabels(alert)
+	tags := []string{"autosysadmin"}
+	if alert.Severity != "" {
+		tags = append(tags, alert.Severity)
+	}
+	title, description, err := rendererOrDefault(n.Templates).RenderAll(ChannelText, []Alert{alert}, nil)
+	if err != nil {
+		return Permanent(err)
+	}
+	return n.post("/v2/alerts", opsgenieAlert{
+		Message:     truncate(title, 130),
+		Alias:       alias,
+		Description: truncate(description, 15000),
+		Tags:        tags,
+		Details:     pagingDetails(alert),
+		Entity:      labels["agent_id"],
+		Source:      "autosysadmin",
+		Priority:    opsgeniePriority(alert.Severity),
+	})
+}
+
+func (n *OpsgenieNotifier) NotifyAcknowledged(alert Alert) error {
+	return n.post("/v2/alerts/"+url.PathEscape(DedupKey(alert))+"/acknowledge?identifierType=alias",
+		opsgenieAction{User: alert.AcknowledgedBy, Source: "autosysadmin"})
+}
+
+func (n *OpsgenieNotifier) post(path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Permanent(err)
+	}
+	base := n.URL
+	if base == "" {
+		base = defaultOpsgenieURL
+	}
+	headers := map[string]string{"Authorization": "GenieKey " + n.APIKey}
+	return postJSON(notifyTimeout(n.Timeout), strings.TrimRight(base, "/")+path, body, headers)
+}
+
+func opsgeniePriority(severity string) string {
+	switch severity {
+	case "critical":
+		return "P1"
+	case "info":
+		return "P5"
+	}
+	return "P3"
+}
+
+// pagingDetails is the structured context attached to a page.
+func pagingDetails(alert Alert) map[string]string {
+	details := AlertLabels(alert)
+	details["alert_id"] = alert.ID
+	if alert.Metric != "" {
+		details["metric"] = alert.Metric
+		details["value"] = fmt.Sprintf("%.2f", alert.Value)
+		details["threshold"] = fmt.Sprintf("%.2f", alert.Threshold)
+	}
+	return details
+}
@@ -0,0 +1,265 @@
+// backend/internal/monitoring/processrules.go
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+// ProcessWatchRule describes the processes expected on matching agents.
+// Processes are selected by Process (exact name), Cmdline (regular
+// expression) and User; the rule fires when the number selected falls
+// outside MinCount..MaxCount or any of them exceeds MaxCPU or MaxRSS. For
+// example {Process: "nginx", Cmdline: "worker process", MinCount: 2}
+// requires at least two nginx workers.
+type ProcessWatchRule struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Selector agent.Selector `json:"selector"`
+	Process  string         `json:"process,omitempty"`
+	Cmdline  string         `json:"cmdline,omitempty"`
+	User     string         `json:"user,omitempty"`
+	MinCount int            `json:"min_count"`
+	MaxCount int            `json:"max_count,omitempty"` // 0 means no limit
+	MaxCPU   float64        `json:"max_cpu,omitempty"`   // percent, per process
+	MaxRSS   int64          `json:"max_rss,omitempty"`   // bytes, per process
+	Severity string         `json:"severity"`            // info, warning, critical
+}
+
+type compiledProcessRule struct {
+	rule    ProcessWatchRule
+	cmdline *regexp.Regexp
+}
+
+func (r *compiledProcessRule) match(p agent.ProcessStats) bool {
+	if r.rule.Process != "" && p.Name != r.rule.Process {
+		return false
+	}
+	if r.rule.User != "" && p.User != r.rule.User {
+		return false
+	}
+	if r.cmdline != nil && !r.cmdline.MatchString(p.Cmdline) {
+		return false
+	}
+	return true
+}
+
+// violation returns a description of how procs break the rule, or "" when
+// they satisfy it, along with the offending value and its limit.
+func (r *compiledProcessRule) violation(procs []agent.ProcessStats) (string, float64, float64) {
+	var matched []agent.ProcessStats
+	for _, p := range procs {
+		if r.match(p) {
+			matched = append(matched, p)
+		}
+	}
+
+	count := len(matched)
+	if count < r.rule.MinCount {
+		if count == 0 {
+			return "no matching processes running", 0, float64(r.rule.MinCount)
+		}
+		return fmt.Sprintf("%d matching processes running, expected at least %d", count, r.rule.MinCount),
+			float64(count), float64(r.rule.MinCount)
+	}
+	if r.rule.MaxCount > 0 && count > r.rule.MaxCount {
+		return fmt.Sprintf("%d matching processes running, expected at most %d", count, r.rule.MaxCount),
+			float64(count), float64(r.rule.MaxCount)
+	}
+
+	var over []string
+	worst, limit := 0.0, 0.0
+	for _, p := range matched {
+		if r.rule.MaxCPU > 0 && p.CPUUsage > r.rule.MaxCPU {
+			over = append(over, fmt.Sprintf("pid %d cpu %.1f%%", p.PID, p.CPUUsage))
+			if p.CPUUsage > worst {
+				worst, limit = p.CPUUsage, r.rule.MaxCPU
+			}
+		}
+		if r.rule.MaxRSS > 0 && p.RSS > r.rule.MaxRSS {
+			over = append(over, fmt.Sprintf("pid %d rss %d bytes", p.PID, p.RSS))
+			if limit == 0 {
+				worst, limit = float64(p.RSS), float64(r.rule.MaxRSS)
+			}
+		}
+	}
+	if len(over) > 0 {
+		return "limits exceeded: " + strings.Join(over, ", "), worst, limit
+	}
+	return "", 0, 0
+}
+
+// ProcessRuleEvaluator checks each agent's reported process table against
+// the watch rules and raises and resolves alerts through the AlertManager.
+type ProcessRuleEvaluator struct {
+	agentManager *agent.Manager
+	alertManager *AlertManager
+	interval     time.Duration
+	rules        map[string]*compiledProcessRule // ruleID -> rule
+	firing       map[string]map[string]string    // ruleID -> agentID -> alertID
+	mu           sync.Mutex
+}
+
+func NewProcessRuleEvaluator(agentManager *agent.Manager, alertManager *AlertManager) *ProcessRuleEvaluator {
+	return &ProcessRuleEvaluator{
+		agentManager: agentManager,
+		alertManager: alertManager,
+		interval:     30 * time.Second,
+		rules:        make(map[string]*compiledProcessRule),
+		firing:       make(map[string]map[string]string),
+	}
+}
+
+func (e *ProcessRuleEvaluator) AddRule(rule ProcessWatchRule) (*ProcessWatchRule, error) {
+	if rule.Name == "" {
+		return nil, fmt.Errorf("rule name is required")
+	}
+	if rule.Process == "" && rule.Cmdline == "" && rule.User == "" {
+		return nil, fmt.Errorf("at least one of process, cmdline or user is required")
+	}
+	if rule.MinCount < 0 || rule.MaxCount < 0 || rule.MaxCPU < 0 || rule.MaxRSS < 0 {
+		return nil, fmt.Errorf("limits must not be negative")
+	}
+	if rule.MaxCount > 0 && rule.MaxCount < rule.MinCount {
+		return nil, fmt.Errorf("max_count must not be less than min_count")
+	}
+	if rule.MinCount == 0 && rule.MaxCount == 0 && rule.MaxCPU == 0 && rule.MaxRSS == 0 {
+		return nil, fmt.Errorf("rule must set min_count, max_count, max_cpu or max_rss")
+	}
+	if rule.Severity == "" {
+		rule.Severity = "warning"
+	}
+
+	compiled := &compiledProcessRule{rule: rule}
+	if rule.Cmdline != "" {
+		re, err := regexp.Compile(rule.Cmdline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cmdline pattern: %w", err)
+		}
+		compiled.cmdline = re
+	}
+
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("procrule-%d", time.Now().UnixNano())
+		compiled.rule.ID = rule.ID
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.resolveRuleLocked(rule.ID)
+	e.rules[rule.ID] = compiled
+	e.firing[rule.ID] = make(map[string]string)
+	return &rule, nil
+}
+
+func (e *ProcessRuleEvaluator) RemoveRule(ruleID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.rules[ruleID]; !exists {
+		return fmt.Errorf("process watch rule not found")
+	}
+
+	e.resolveRuleLocked(ruleID)
+	delete(e.rules, ruleID)
+	delete(e.firing, ruleID)
+	return nil
+}
+
+func (e *ProcessRuleEvaluator) ListRules() []ProcessWatchRule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rules := make([]ProcessWatchRule, 0, len(e.rules))
+	for _, r := range e.rules {
+		rules = append(rules, r.rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// Run evaluates all rules periodically until the context is cancelled.
+func (e *ProcessRuleEvaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Evaluate()
+		}
+	}
+}
+
+// Evaluate checks every rule against the latest stats of the agents it
+// selects.
+func (e *ProcessRuleEvaluator) Evaluate() {
+	agents := e.agentManager.ListAgents()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ruleID, r := range e.rules {
+		seen := make(map[string]bool)
+		for _, a := range agents {
+			if !r.rule.Selector.Matches(a) {
+				continue
+			}
+			stats, err := e.agentManager.GetStats(a.ID)
+			if err != nil {
+				continue
+			}
+			seen[a.ID] = true
+			e.evaluateLocked(r, a.ID, stats.Processes)
+		}
+
+		// Agents removed or no longer selected can't keep an alert open
+		for agentID, alertID := range e.firing[ruleID] {
+			if !seen[agentID] {
+				delete(e.firing[ruleID], agentID)
+				e.alertManager.ResolveAlert(alertID)
+			}
+		}
+	}
+}
+
+func (e *ProcessRuleEvaluator) evaluateLocked(r *compiledProcessRule, agentID string, procs []agent.ProcessStats) {
+	alertID, firing := e.firing[r.rule.ID][agentID]
+	problem, value, limit := r.violation(procs)
+
+	switch {
+	case problem != "" && !firing:
+		alert := Alert{
+			ID:        fmt.Sprintf("%s-%s-%d", r.rule.ID, agentID, time.Now().UnixNano()),
+			AgentID:   agentID,
+			Metric:    "process:" + r.rule.Name,
+			Value:     value,
+			Threshold: limit,
+			Message:   fmt.Sprintf("Process watch %q: %s", r.rule.Name, problem),
+			Timestamp: time.Now(),
+			Status:    "active",
+		}
+		e.firing[r.rule.ID][agentID] = alert.ID
+		e.alertManager.AddAlert(alert)
+
+	case problem == "" && firing:
+		delete(e.firing[r.rule.ID], agentID)
+		e.alertManager.ResolveAlert(alertID)
+	}
+}
+
+func (e *ProcessRuleEvaluator) resolveRuleLocked(ruleID string) {
+	for _, alertID := range e.firing[ruleID] {
+		e.alertManager.ResolveAlert(alertID)
+	}
+}
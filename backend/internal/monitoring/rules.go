@@ -0,0 +1,556 @@
+// backend/internal/monitoring/rules.go
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+// Comparison operators for AlertRule.Operator.
+const (
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+)
+
+// Rule states, per (rule, agent).
+const (
+	StateInactive = "inactive"
+	StatePending  = "pending" // condition true, waiting out For
+	StateFiring   = "firing"
+)
+
+// AlertRule fires when Metric compared with Threshold by Operator has held
+// for at least For. Once firing it resolves only when the comparison fails
+// against ResolveThreshold, which gives hysteresis: a CPU rule with
+// "> 90" and resolve threshold 80 stays firing while CPU hovers at 85.
+// Without a resolve threshold the rule resolves as soon as the condition
+// is false.
+//
+// An empty selector applies the rule to the whole fleet. Message is a
+// text/template rendered with AlertTemplateData; Receivers names the
+// AlertManager receivers to notify, or the default notifiers when empty.
+type AlertRule struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description,omitempty"`
+	Selector         agent.Selector    `json:"selector"`
+	Metric           string            `json:"metric"`
+	Operator         string            `json:"operator"`
+	Threshold        float64           `json:"threshold"`
+	ResolveThreshold *float64          `json:"resolve_threshold,omitempty"`
+	For              time.Duration     `json:"for"`
+	Severity         string            `json:"severity"` // info, warning, critical
+	Labels           map[string]string `json:"labels,omitempty"`
+	Message          string            `json:"message,omitempty"`
+	RunbookURL       string            `json:"runbook_url,omitempty"`
+	Receivers        []string          `json:"receivers,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// AlertTemplateData is what a rule's message template can refer to, e.g.
+// "{{.Hostname}} CPU at {{printf \"%.0f\" .Value}}%".
+type AlertTemplateData struct {
+	Rule      AlertRule
+	AgentID   string
+	Hostname  string
+	Value     float64
+	Threshold float64
+	Labels    map[string]string
+}
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	validSeverities   = map[string]bool{"info": true, "warning": true, "critical": true}
+)
+
+func (r *AlertRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	if r.ID != "" && !ruleIDPattern.MatchString(r.ID) {
+		return fmt.Errorf("invalid rule id %q", r.ID)
+	}
+	if !metricNamePattern.MatchString(r.Metric) {
+		return fmt.Errorf("invalid metric name %q", r.Metric)
+	}
+	for name := range r.Labels {
+		if !labelNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	if _, err := r.messageTemplate(); err != nil {
+		return fmt.Errorf("invalid message template: %w", err)
+	}
+	if r.RunbookURL != "" {
+		if u, err := url.Parse(r.RunbookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("runbook_url must be an http(s) URL")
+		}
+	}
+	if r.Operator == "" {
+		r.Operator = OpGreater
+	}
+	if r.For < 0 {
+		return fmt.Errorf("for must not be negative")
+	}
+	if r.Severity == "" {
+		r.Severity = "warning"
+	}
+	if !validSeverities[r.Severity] {
+		return fmt.Errorf("severity must be info, warning or critical")
+	}
+
+	if r.ResolveThreshold == nil {
+		if _, ok := compare(r.Operator, 0, 0); !ok {
+			return fmt.Errorf("unsupported operator %q", r.Operator)
+		}
+		return nil
+	}
+	// The resolve threshold must sit on the "clear" side of the firing one,
+	// otherwise an alert could resolve while still meeting its condition
+	resolve := *r.ResolveThreshold
+	switch r.Operator {
+	case OpGreater, OpGreaterEqual:
+		if resolve > r.Threshold {
+			return fmt.Errorf("resolve_threshold must not be above threshold for %s", r.Operator)
+		}
+	case OpLess, OpLessEqual:
+		if resolve < r.Threshold {
+			return fmt.Errorf("resolve_threshold must not be below threshold for %s", r.Operator)
+		}
+	case OpEqual, OpNotEqual:
+		return fmt.Errorf("resolve_threshold is not supported with %s", r.Operator)
+	default:
+		return fmt.Errorf("unsupported operator %q", r.Operator)
+	}
+	return nil
+}
+
+func (r *AlertRule) messageTemplate() (*template.Template, error) {
+	if r.Message == "" {
+		return nil, nil
+	}
+	return template.New(r.Name).Option("missingkey=zero").Parse(r.Message)
+}
+
+// firingCondition and resolvedCondition apply the rule's comparison to a
+// value against the firing and resolve thresholds.
+func (r *AlertRule) firingCondition(value float64) bool {
+	met, _ := compare(r.Operator, value, r.Threshold)
+	return met
+}
+
+func (r *AlertRule) resolvedCondition(value float64) bool {
+	threshold := r.Threshold
+	if r.ResolveThreshold != nil {
+		threshold = *r.ResolveThreshold
+	}
+	met, _ := compare(r.Operator, value, threshold)
+	return !met
+}
+
+func compare(op string, value, threshold float64) (bool, bool) {
+	switch op {
+	case OpGreater:
+		return value > threshold, true
+	case OpGreaterEqual:
+		return value >= threshold, true
+	case OpLess:
+		return value < threshold, true
+	case OpLessEqual:
+		return value <= threshold, true
+	case OpEqual:
+		return value == threshold, true
+	case OpNotEqual:
+		return value != threshold, true
+	}
+	return false, false
+}
+
+// RuleState is where one rule stands for one agent.
+type RuleState struct {
+	RuleID      string    `json:"rule_id"`
+	AgentID     string    `json:"agent_id"`
+	State       string    `json:"state"`
+	Value       float64   `json:"value"`
+	ActiveSince time.Time `json:"active_since,omitempty"` // condition first met
+	FiredAt     time.Time `json:"fired_at,omitempty"`
+	AlertID     string    `json:"alert_id,omitempty"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+const (
+	ruleSweepInterval = time.Minute
+	// A state not evaluated for this long belongs to an agent that is no
+	// longer monitored
+	ruleStateMaxAge = 10 * time.Minute
+)
+
+// RuleEngine evaluates metric alert rules and keeps one alert per firing
+// (rule, agent). Alerts are raised and resolved through the AlertManager,
+// so notifications go out on those two transitions only.
+type RuleEngine struct {
+	agentManager *agent.Manager
+	alertManager *AlertManager
+	store        RuleStore
+	rules        map[string]AlertRule             // ruleID -> rule
+	templates    map[string]*template.Template    // ruleID -> message template
+	states       map[string]map[string]*RuleState // ruleID -> agentID -> state
+	mu           sync.Mutex
+}
+
+func NewRuleEngine(agentManager *agent.Manager, alertManager *AlertManager) *RuleEngine {
+	return &RuleEngine{
+		agentManager: agentManager,
+		alertManager: alertManager,
+		rules:        make(map[string]AlertRule),
+		templates:    make(map[string]*template.Template),
+		states:       make(map[string]map[string]*RuleState),
+	}
+}
+
+// SetStore loads the rules saved in store and persists every later change
+// to it. Saved rules are loaded even if a receiver they name has since been
+// removed from the configuration; those notify the default notifiers.
+func (e *RuleEngine) SetStore(store RuleStore) error {
+	rules, err := store.ListRules()
+	if err != nil {
+		return fmt.Errorf("failed to load alert rules: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.store = store
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("stored alert rule %s: %w", rule.ID, err)
+		}
+		e.putLocked(rule)
+	}
+	return nil
+}
+
+// AddRule creates a rule, or replaces the one with the same ID.
+func (e *RuleEngine) AddRule(rule AlertRule) (*AlertRule, error) {
+	if err := e.validate(&rule); err != nil {
+		return nil, err
+	}
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("rule-%d", time.Now().UnixNano())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rule.UpdatedAt = time.Now()
+	if existing, exists := e.rules[rule.ID]; exists {
+		rule.CreatedAt = existing.CreatedAt
+	} else {
+		rule.CreatedAt = rule.UpdatedAt
+	}
+	if err := e.saveLocked(rule); err != nil {
+		return nil, err
+	}
+	e.putLocked(rule)
+	return &rule, nil
+}
+
+// UpdateRule replaces an existing rule.
+func (e *RuleEngine) UpdateRule(rule AlertRule) (*AlertRule, error) {
+	e.mu.Lock()
+	_, exists := e.rules[rule.ID]
+	e.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("alert rule not found")
+	}
+	return e.AddRule(rule)
+}
+
+func (e *RuleEngine) RemoveRule(ruleID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.rules[ruleID]; !exists {
+		return fmt.Errorf("alert rule not found")
+	}
+	if e.store != nil {
+		if err := e.store.DeleteRule(ruleID); err != nil {
+			return err
+		}
+	}
+	e.resolveRuleLocked(ruleID)
+	delete(e.rules, ruleID)
+	delete(e.templates, ruleID)
+	delete(e.states, ruleID)
+	return nil
+}
+
+func (e *RuleEngine) validate(rule *AlertRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	for _, name := range rule.Receivers {
+		if !e.alertManager.HasReceiver(name) {
+			return fmt.Errorf("unknown receiver %q", name)
+		}
+	}
+	return nil
+}
+
+func (e *RuleEngine) saveLocked(rule AlertRule) error {
+	if e.store == nil {
+		return nil
+	}
+	return e.store.SaveRule(rule)
+}
+
+// putLocked installs a validated rule. A changed rule starts over rather
+// than keep an alert raised under conditions that no longer apply.
+func (e *RuleEngine) putLocked(rule AlertRule) {
+	e.resolveRuleLocked(rule.ID)
+	e.rules[rule.ID] = rule
+	e.states[rule.ID] = make(map[string]*RuleState)
+	if tmpl, _ := rule.messageTemplate(); tmpl != nil {
+		e.templates[rule.ID] = tmpl
+	} else {
+		delete(e.templates, rule.ID)
+	}
+}
+
+func (e *RuleEngine) GetRule(ruleID string) (*AlertRule, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rule, exists := e.rules[ruleID]
+	if !exists {
+		return nil, fmt.Errorf("alert rule not found")
+	}
+	return &rule, nil
+}
+
+func (e *RuleEngine) ListRules() []AlertRule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rules := make([]AlertRule, 0, len(e.rules))
+	for _, r := range e.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// States returns the non-inactive states of a rule, or of all rules when
+// ruleID is empty.
+func (e *RuleEngine) States(ruleID string) []RuleState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var states []RuleState
+	for id, byAgent := range e.states {
+		if ruleID != "" && id != ruleID {
+			continue
+		}
+		for _, st := range byAgent {
+			states = append(states, *st)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].RuleID != states[j].RuleID {
+			return states[i].RuleID < states[j].RuleID
+		}
+		return states[i].AgentID < states[j].AgentID
+	})
+	return states
+}
+
+// Evaluate runs every rule that applies to the agent against its latest
+// metrics. A rule that no longer applies to the agent, or whose metric
+// isn't in the sample, drops its state and resolves any alert it raised.
+func (e *RuleEngine) Evaluate(agentID string, metrics []Metric) {
+	a, exists := e.agentManager.GetAgent(agentID)
+	values := make(map[string]float64, len(metrics))
+	for _, m := range metrics {
+		values[m.Name] = m.Value
+	}
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, rule := range e.rules {
+		if !exists || !rule.Selector.Matches(a) {
+			e.clearLocked(rule.ID, agentID)
+			continue
+		}
+		value, ok := values[rule.Metric]
+		if !ok {
+			e.clearLocked(rule.ID, agentID)
+			continue
+		}
+		e.evaluateLocked(rule, a, value, now)
+	}
+}
+
+// Run sweeps rule states periodically until the context is cancelled.
+func (e *RuleEngine) Run(ctx context.Context) {
+	ticker := time.NewTicker(ruleSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Sweep()
+		}
+	}
+}
+
+// Sweep clears the states of agents that were removed, no longer match the
+// rule's selector, or stopped being evaluated (their monitoring was
+// stopped), so none of them keeps an alert open.
+func (e *RuleEngine) Sweep() {
+	agents := make(map[string]*agent.Agent)
+	for _, a := range e.agentManager.ListAgents() {
+		agents[a.ID] = a
+	}
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ruleID, byAgent := range e.states {
+		rule := e.rules[ruleID]
+		for agentID, st := range byAgent {
+			a, exists := agents[agentID]
+			if !exists || !rule.Selector.Matches(a) || now.Sub(st.EvaluatedAt) > ruleStateMaxAge {
+				e.clearLocked(ruleID, agentID)
+			}
+		}
+	}
+}
+
+// clearLocked forgets a (rule, agent) state, resolving its alert if firing.
+func (e *RuleEngine) clearLocked(ruleID, agentID string) {
+	st, exists := e.states[ruleID][agentID]
+	if !exists {
+		return
+	}
+	if st.State == StateFiring {
+		e.alertManager.ResolveAlert(st.AlertID)
+	}
+	delete(e.states[ruleID], agentID)
+}
+
+func (e *RuleEngine) evaluateLocked(rule AlertRule, a *agent.Agent, value float64, now time.Time) {
+	agentID := a.ID
+	st, exists := e.states[rule.ID][agentID]
+	if !exists {
+		st = &RuleState{RuleID: rule.ID, AgentID: agentID, State: StateInactive}
+	}
+	st.Value = value
+	st.EvaluatedAt = now
+
+	switch st.State {
+	case StateInactive:
+		if rule.firingCondition(value) {
+			st.State = StatePending
+			st.ActiveSince = now
+		}
+	case StatePending:
+		if !rule.firingCondition(value) {
+			st.State = StateInactive
+			st.ActiveSince = time.Time{}
+		}
+	case StateFiring:
+		if rule.resolvedCondition(value) {
+			e.alertManager.ResolveAlert(st.AlertID)
+			st.State = StateInactive
+			st.ActiveSince = time.Time{}
+			st.FiredAt = time.Time{}
+			st.AlertID = ""
+		}
+	}
+
+	// Checked after the transition above so a rule with For 0 fires on the
+	// first evaluation that meets its condition
+	if st.State == StatePending && now.Sub(st.ActiveSince) >= rule.For {
+		labels := map[string]string{
+			"alertname": rule.Name,
+			"severity":  rule.Severity,
+			"agent_id":  agentID,
+		}
+		for k, v := range rule.Labels {
+			labels[k] = v
+		}
+		alert := Alert{
+			ID:         fmt.Sprintf("%s-%s-%d", rule.ID, agentID, now.UnixNano()),
+			AgentID:    agentID,
+			RuleID:     rule.ID,
+			Severity:   rule.Severity,
+			Labels:     labels,
+			Receivers:  rule.Receivers,
+			RunbookURL: rule.RunbookURL,
+			Metric:     rule.Metric,
+			Value:      value,
+			Threshold:  rule.Threshold,
+			Message:    e.messageLocked(rule, a, value, labels),
+			Timestamp:  now,
+			Status:     "active",
+		}
+		e.alertManager.AddAlert(alert)
+		st.State = StateFiring
+		st.FiredAt = now
+		st.AlertID = alert.ID
+	}
+
+	if st.State == StateInactive {
+		delete(e.states[rule.ID], agentID)
+	} else {
+		e.states[rule.ID][agentID] = st
+	}
+}
+
+// messageLocked renders the rule's message template, falling back to a
+// generic message if there is none or it fails to execute.
+func (e *RuleEngine) messageLocked(rule AlertRule, a *agent.Agent, value float64, labels map[string]string) string {
+	if tmpl, ok := e.templates[rule.ID]; ok {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, AlertTemplateData{
+			Rule:      rule,
+			AgentID:   a.ID,
+			Hostname:  a.Hostname,
+			Value:     value,
+			Threshold: rule.Threshold,
+			Labels:    labels,
+		})
+		if err == nil {
+			return buf.String()
+		}
+	}
+	return fmt.Sprintf("%s: %s %s %g (value %.2f)", rule.Name, rule.Metric, rule.Operator, rule.Threshold, value)
+}
+
+func (e *RuleEngine) resolveRuleLocked(ruleID string) {
+	for _, st := range e.states[ruleID] {
+		if st.State == StateFiring {
+			e.alertManager.ResolveAlert(st.AlertID)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// backend/internal/monitoring/rulestore.go
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// RuleStore persists alert rule definitions so they survive restarts.
+type RuleStore interface {
+	SaveRule(rule AlertRule) error
+	DeleteRule(id string) error
+	ListRules() ([]AlertRule, error)
+}
+
+// Rule IDs become file names, so keep them to a safe alphabet
+var ruleIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+type fileRuleStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileRuleStore keeps one JSON file per rule in dir.
+func NewFileRuleStore(dir string) (RuleStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create alert rule directory: %w", err)
+	}
+	return &fileRuleStore{dir: dir}, nil
+}
+
+func (s *fileRuleStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *fileRuleStore) SaveRule(rule AlertRule) error {
+	if !ruleIDPattern.MatchString(rule.ID) {
+		return fmt.Errorf("invalid rule id %q", rule.ID)
+	}
+	data, err := json.MarshalIndent(rule, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Write then rename so a crash never leaves a half-written rule
+	tmp := s.path(rule.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write alert rule: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(rule.ID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save alert rule: %w", err)
+	}
+	return nil
+}
+
+func (s *fileRuleStore) DeleteRule(id string) error {
+	if !ruleIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid rule id %q", id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	return nil
+}
+
+func (s *fileRuleStore) ListRules() ([]AlertRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]AlertRule, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var rule AlertRule
+		if err := json.Unmarshal(data, &rule); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
@@ -0,0 +1,277 @@
+// backend/internal/monitoring/silence.go
+package monitoring
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Matcher selects alerts by one label. Value is a regular expression,
+// anchored at both ends, when Regex is set.
+type Matcher struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Regex    bool   `json:"regex,omitempty"`
+	Negative bool   `json:"negative,omitempty"` // != or !~
+
+	re *regexp.Regexp
+}
+
+func (m *Matcher) compile() error {
+	if !labelNamePattern.MatchString(m.Name) {
+		return fmt.Errorf("invalid label name %q", m.Name)
+	}
+	if !m.Regex {
+		return nil
+	}
+	re, err := regexp.Compile("^(?:" + m.Value + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid regex for %s: %w", m.Name, err)
+	}
+	m.re = re
+	return nil
+}
+
+func (m *Matcher) matches(labels map[string]string) bool {
+	value := labels[m.Name]
+	var ok bool
+	if m.re != nil {
+		ok = m.re.MatchString(value)
+	} else {
+		ok = value == m.Value
+	}
+	return ok != m.Negative
+}
+
+func compileMatchers(matchers []Matcher) error {
+	for i := range matchers {
+		if err := matchers[i].compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchAll(matchers []Matcher, labels map[string]string) bool {
+	for i := range matchers {
+		if !matchers[i].matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Silence mutes notifications for alerts matching all its matchers between
+// StartsAt and EndsAt. Muted alerts are still recorded.
+type Silence struct {
+	ID        string    `json:"id"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *Silence) Active(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// InhibitRule mutes alerts matching Target while an active alert matches
+// Source and has the same values for the Equal labels. For example, source
+// alertname="agent down", target severity=~"warning|critical", equal
+// agent_id keeps a down host from also paging for its CPU.
+type InhibitRule struct {
+	ID     string    `json:"id"`
+	Source []Matcher `json:"source"`
+	Target []Matcher `json:"target"`
+	Equal  []string  `json:"equal"`
+}
+
+// AlertLabels returns the labels an alert is matched on: its own labels
+// plus agent_id, severity and alertname, derived from the alert itself
+// where the raising rule didn't set them.
+func AlertLabels(alert Alert) map[string]string {
+	labels := make(map[string]string, len(alert.Labels)+3)
+	for k, v := range alert.Labels {
+		labels[k] = v
+	}
+	defaults := map[string]string{
+		"agent_id":  alert.AgentID,
+		"severity":  alert.Severity,
+		"alertname": alert.Metric,
+		"rule_id":   alert.RuleID,
+	}
+	for k, v := range defaults {
+		if _, set := labels[k]; !set && v != "" {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+func (m *AlertManager) AddSilence(silence Silence) (*Silence, error) {
+	if len(silence.Matchers) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required")
+	}
+	if err := compileMatchers(silence.Matchers); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return nil, fmt.Errorf("ends_at must be after starts_at")
+	}
+	if silence.ID == "" {
+		silence.ID = fmt.Sprintf("silence-%d", now.UnixNano())
+	}
+	silence.CreatedAt = now
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.silences[silence.ID] = silence
+	return &silence, nil
+}
+
+// ExpireSilence ends a silence now. It stays listed until it is older
+// than the retention for expired silences.
+func (m *AlertManager) ExpireSilence(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	silence, exists := m.silences[id]
+	if !exists {
+		return fmt.Errorf("silence not found")
+	}
+	now := time.Now()
+	if silence.EndsAt.After(now) {
+		silence.EndsAt = now
+		if silence.StartsAt.After(now) {
+			silence.StartsAt = now
+		}
+		m.silences[id] = silence
+	}
+	return nil
+}
+
+// ListSilences returns the silences, pending, active and expired, newest
+// first.
+func (m *AlertManager) ListSilences() []Silence {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gcSilencesLocked(time.Now())
+	silences := make([]Silence, 0, len(m.silences))
+	for _, silence := range m.silences {
+		silences = append(silences, silence)
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].CreatedAt.After(silences[j].CreatedAt) })
+	return silences
+}
+
+// expiredSilenceRetention is how long an expired silence stays listed
+const expiredSilenceRetention = 24 * time.Hour
+
+func (m *AlertManager) gcSilencesLocked(now time.Time) {
+	for id, silence := range m.silences {
+		if now.Sub(silence.EndsAt) > expiredSilenceRetention {
+			delete(m.silences, id)
+		}
+	}
+}
+
+func (m *AlertManager) AddInhibitRule(rule InhibitRule) (*InhibitRule, error) {
+	if len(rule.Source) == 0 || len(rule.Target) == 0 {
+		return nil, fmt.Errorf("source and target matchers are required")
+	}
+	if err := compileMatchers(rule.Source); err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	if err := compileMatchers(rule.Target); err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+	for _, name := range rule.Equal {
+		if !labelNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("inhibit-%d", time.Now().UnixNano())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inhibitRules[rule.ID] = rule
+	return &rule, nil
+}
+
+func (m *AlertManager) RemoveInhibitRule(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.inhibitRules[id]; !exists {
+		return fmt.Errorf("inhibit rule not found")
+	}
+	delete(m.inhibitRules, id)
+	return nil
+}
+
+func (m *AlertManager) ListInhibitRules() []InhibitRule {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rules := make([]InhibitRule, 0, len(m.inhibitRules))
+	for _, rule := range m.inhibitRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// mutedLocked reports the silences and inhibit rules currently muting an
+// alert. Resolved alerts are never muted, so a resolution is always sent
+// for an alert whose firing was.
+func (m *AlertManager) mutedLocked(alert Alert, now time.Time) (silencedBy, inhibitedBy []string) {
+	if alert.Status != "active" {
+		return nil, nil
+	}
+	labels := AlertLabels(alert)
+
+	for id, silence := range m.silences {
+		if silence.Active(now) && matchAll(silence.Matchers, labels) {
+			silencedBy = append(silencedBy, id)
+		}
+	}
+
+	for id, rule := range m.inhibitRules {
+		if !matchAll(rule.Target, labels) {
+			continue
+		}
+		for _, source := range m.alerts {
+			if source.ID == alert.ID || source.Status != "active" {
+				continue
+			}
+			sourceLabels := AlertLabels(source)
+			if matchAll(rule.Source, sourceLabels) && equalLabels(rule.Equal, labels, sourceLabels) {
+				inhibitedBy = append(inhibitedBy, id)
+				break
+			}
+		}
+	}
+	sort.Strings(silencedBy)
+	sort.Strings(inhibitedBy)
+	return silencedBy, inhibitedBy
+}
+
+func equalLabels(names []string, a, b map[string]string) bool {
+	for _, name := range names {
+		if a[name] != b[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,393 @@
+// backend/internal/monitoring/templates.go
+package monitoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+// Notification template channels. Subject is the one-line title used for
+// email subjects, Slack fallbacks and paging summaries; Slack renders to a
+// JSON array of Block Kit blocks.
+const (
+	ChannelSubject = "subject"
+	ChannelText    = "text"
+	ChannelHTML    = "html"
+	ChannelSlack   = "slack"
+)
+
+// Channels lists the template channels in display order.
+var Channels = []string{ChannelSubject, ChannelText, ChannelHTML, ChannelSlack}
+
+// How many recent values of an alert's metric templates get
+const recentMetricValues = 10
+
+// NotificationData is what notification templates are executed with. For a
+// single alert, Alerts has one entry and Alert is that entry.
+type NotificationData struct {
+	Title       string    // the rendered subject; empty when rendering it
+	Status      string    // firing, resolved
+	Alert       AlertData // the first alert
+	Alerts      []AlertData
+	Firing      int
+	Resolved    int
+	GroupLabels map[string]string // empty outside grouped notifications
+}
+
+// AlertData is an alert with context for templates: all of its labels
+// (see AlertLabels), the agent it fired on, and the latest values of its
+// metric, oldest first.
+type AlertData struct {
+	Alert
+	Labels map[string]string
+	Agent  *agent.Agent // nil if the agent is gone
+	Recent []Metric
+}
+
+// MetricSource provides recent metrics per agent; Monitor implements it.
+type MetricSource interface {
+	GetMetrics(agentID string) ([]Metric, error)
+}
+
+// TemplateRenderer renders notifications per channel, from custom
+// templates where set and the defaults otherwise.
+type TemplateRenderer struct {
+	agentManager *agent.Manager
+	metrics      MetricSource
+	custom       map[string]string // channel -> template source
+	mu           sync.RWMutex
+}
+
+// NewTemplateRenderer gives templates agent metadata from agentManager,
+// which may be nil, e.g. in tools that render without a fleet.
+func NewTemplateRenderer(agentManager *agent.Manager) *TemplateRenderer {
+	return &TemplateRenderer{
+		agentManager: agentManager,
+		custom:       make(map[string]string),
+	}
+}
+
+// defaultRenderer serves notifiers that weren't given a renderer.
+var defaultRenderer = NewTemplateRenderer(nil)
+
+func rendererOrDefault(r *TemplateRenderer) *TemplateRenderer {
+	if r == nil {
+		return defaultRenderer
+	}
+	return r
+}
+
+// SetMetricSource makes recent metric values available to templates.
+func (r *TemplateRenderer) SetMetricSource(source MetricSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = source
+}
+
+// SetTemplate replaces a channel's template after checking that it renders
+// against SampleAlert. An empty source restores the default.
+func (r *TemplateRenderer) SetTemplate(channel, source string) error {
+	if _, ok := defaultTemplates[channel]; !ok {
+		return fmt.Errorf("unknown template channel %q", channel)
+	}
+	if source != "" {
+		if _, err := r.Preview(channel, source, SampleAlert()); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if source == "" {
+		delete(r.custom, channel)
+	} else {
+		r.custom[channel] = source
+	}
+	return nil
+}
+
+// Template returns the channel's template source and whether it is a
+// custom one.
+func (r *TemplateRenderer) Template(channel string) (string, bool, error) {
+	def, ok := defaultTemplates[channel]
+	if !ok {
+		return "", false, fmt.Errorf("unknown template channel %q", channel)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if source, ok := r.custom[channel]; ok {
+		return source, true, nil
+	}
+	return def, false, nil
+}
+
+// Preview renders source, or the channel's current template when source
+// is empty, against the given alert.
+func (r *TemplateRenderer) Preview(channel, source string, alert Alert) (string, error) {
+	if source == "" {
+		var err error
+		if source, _, err = r.Template(channel); err != nil {
+			return "", err
+		}
+	}
+	data := r.Data([]Alert{alert}, nil)
+	if channel != ChannelSubject {
+		title, err := r.Render(ChannelSubject, data)
+		if err != nil {
+			return "", err
+		}
+		data.Title = title
+	}
+	return execute(channel, source, data)
+}
+
+// Render renders a channel for data. A broken custom template is logged
+// and the default used, so a template mistake never costs a notification.
+func (r *TemplateRenderer) Render(channel string, data NotificationData) (string, error) {
+	source, custom, err := r.Template(channel)
+	if err != nil {
+		return "", err
+	}
+	out, err := execute(channel, source, data)
+	if err != nil && custom {
+		fmt.Printf("Custom %s notification template failed, using the default: %v\n", channel, err)
+		out, err = execute(channel, defaultTemplates[channel], data)
+	}
+	return out, err
+}
+
+// RenderAll renders the subject and then, with it as Title, the given
+// channel.
+func (r *TemplateRenderer) RenderAll(channel string, alerts []Alert, groupLabels map[string]string) (subject, body string, err error) {
+	data := r.Data(alerts, groupLabels)
+	if subject, err = r.Render(ChannelSubject, data); err != nil {
+		return "", "", err
+	}
+	data.Title = subject
+	if body, err = r.Render(channel, data); err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+// Data builds the template data for alerts, looking up their agents and
+// recent metric values.
+func (r *TemplateRenderer) Data(alerts []Alert, groupLabels map[string]string) NotificationData {
+	r.mu.RLock()
+	metrics := r.metrics
+	r.mu.RUnlock()
+
+	data := NotificationData{Status: "resolved", GroupLabels: groupLabels}
+	for _, alert := range alerts {
+		ad := AlertData{Alert: alert, Labels: AlertLabels(alert)}
+		if r.agentManager != nil {
+			if a, ok := r.agentManager.GetAgent(alert.AgentID); ok {
+				ad.Agent = a
+			}
+		}
+		if metrics != nil && alert.Metric != "" {
+			if all, err := metrics.GetMetrics(alert.AgentID); err == nil {
+				ad.Recent = recentValues(all, alert.Metric)
+			}
+		}
+		if alert.Status == "active" {
+			data.Status = "firing"
+			data.Firing++
+		} else {
+			data.Resolved++
+		}
+		data.Alerts = append(data.Alerts, ad)
+	}
+	if len(data.Alerts) > 0 {
+		data.Alert = data.Alerts[0]
+	}
+	return data
+}
+
+func recentValues(metrics []Metric, name string) []Metric {
+	var recent []Metric
+	for i := len(metrics) - 1; i >= 0 && len(recent) < recentMetricValues; i-- {
+		if metrics[i].Name == name {
+			recent = append(recent, metrics[i])
+		}
+	}
+	for i, j := 0, len(recent)-1; i < j; i, j = i+1, j-1 {
+		recent[i], recent[j] = recent[j], recent[i]
+	}
+	return recent
+}
+
+// execute parses and runs a template: html/template for the HTML channel,
+// text/template otherwise. Slack output must be a JSON array of blocks.
+func execute(channel, source string, data NotificationData) (string, error) {
+	var buf bytes.Buffer
+	if channel == ChannelHTML {
+		tmpl, err := htmltemplate.New(channel).Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s template: %w", channel, err)
+		}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render %s template: %w", channel, err)
+		}
+		return buf.String(), nil
+	}
+
+	tmpl, err := template.New(channel).Funcs(templateFuncs).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", channel, err)
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", channel, err)
+	}
+	out := buf.String()
+	switch channel {
+	case ChannelSubject:
+		out = strings.Join(strings.Fields(out), " ")
+	case ChannelSlack:
+		var blocks []json.RawMessage
+		if err := json.Unmarshal([]byte(out), &blocks); err != nil {
+			return "", fmt.Errorf("slack template must render a JSON array of blocks: %w", err)
+		}
+		if len(blocks) > slackMaxBlocks {
+			return truncateSlackBlocks(blocks)
+		}
+	}
+	return out, nil
+}
+
+// Slack rejects messages with more blocks than this with a 400, which
+// would drop the notification. The default template stays under it with
+// 15 alerts of up to 3 blocks each plus a header and a footer.
+const slackMaxBlocks = 50
+
+// truncateSlackBlocks cuts a custom template's output down to the limit,
+// ending with a note that it was cut.
+func truncateSlackBlocks(blocks []json.RawMessage) (string, error) {
+	note, err := json.Marshal(map[string]interface{}{
+		"type": "context",
+		"elements": []map[string]string{{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf("…%d more blocks not shown", len(blocks)-slackMaxBlocks+1),
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(append(blocks[:slackMaxBlocks-1:slackMaxBlocks-1], note))
+	return string(out), err
+}
+
+var templateFuncs = template.FuncMap{
+	// json renders a value as a JSON literal, for building Slack blocks
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"labels":   formatLabels,
+	"truncate": func(max int, s string) string { return truncate(s, max) },
+	"time":     func(t time.Time) string { return t.UTC().Format(time.RFC1123) },
+	"upper":    strings.ToUpper,
+	"sub":      func(a, b int) int { return a - b },
+	"sortedKeys": func(m map[string]string) []string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	},
+}
+
+// SampleAlert is the alert templates are checked and previewed against.
+func SampleAlert() Alert {
+	return Alert{
+		ID:         "sample-alert",
+		AgentID:    "sample-agent",
+		RuleID:     "cpu-high",
+		Severity:   "critical",
+		Labels:     map[string]string{"alertname": "cpu high", "team": "ops"},
+		Metric:     "cpu",
+		Value:      97.5,
+		Threshold:  90,
+		Message:    "cpu high: cpu > 90 (value 97.50)",
+		Timestamp:  time.Now().Add(-5 * time.Minute),
+		Status:     "active",
+		RunbookURL: "https://runbooks.example.com/cpu-high",
+	}
+}
+
+var defaultTemplates = map[string]string{
+	ChannelSubject: `[{{upper .Status}}{{if .Firing}}:{{.Firing}}{{end}}]
+{{- if .GroupLabels}} {{labels .GroupLabels}}
+{{- else}} {{.Alert.Labels.alertname}} on {{with .Alert.Agent}}{{.Hostname}}{{else}}{{.Alert.AgentID}}{{end}}{{end}}`,
+
+	ChannelText: `{{range $i, $a := .Alerts}}{{if $i}}
+---
+
+{{end}}{{$a.Message}}
+
+Status:    {{$a.Status}}
+Agent:     {{$a.AgentID}}{{with $a.Agent}} ({{.Hostname}}, {{.IPAddress}}){{end}}
+{{- if $a.Severity}}
+Severity:  {{$a.Severity}}{{end}}
+{{- if $a.Metric}}
+Metric:    {{$a.Metric}} = {{printf "%.2f" $a.Value}} (threshold {{printf "%.2f" $a.Threshold}}){{end}}
+{{- if $a.Recent}}
+Recent:    {{range $j, $m := $a.Recent}}{{if $j}}, {{end}}{{printf "%.1f" $m.Value}}{{end}}{{end}}
+Started:   {{time $a.Timestamp}}
+{{- with $a.ResolvedAt}}
+Resolved:  {{time .}}{{end}}
+Labels:    {{labels $a.Labels}}
+{{- if $a.RunbookURL}}
+Runbook:   {{$a.RunbookURL}}{{end}}
+Alert ID:  {{$a.ID}}
+{{end}}`,
+
+	ChannelHTML: `<html><body style="font-family: sans-serif">
+<h2>{{.Title}}</h2>
+{{range .Alerts}}<div style="border-left: 4px solid {{if eq .Status "active"}}#d9534f{{else}}#5cb85c{{end}}; padding-left: 12px; margin-bottom: 16px">
+<p><strong>{{.Message}}</strong></p>
+<table cellpadding="2">
+<tr><td>Status</td><td>{{.Status}}</td></tr>
+<tr><td>Agent</td><td>{{.AgentID}}{{with .Agent}} ({{.Hostname}}, {{.IPAddress}}){{end}}</td></tr>
+{{if .Severity}}<tr><td>Severity</td><td>{{.Severity}}</td></tr>{{end}}
+{{if .Metric}}<tr><td>{{.Metric}}</td><td>{{printf "%.2f" .Value}} (threshold {{printf "%.2f" .Threshold}})</td></tr>{{end}}
+{{if .Recent}}<tr><td>Recent</td><td>{{range $j, $m := .Recent}}{{if $j}}, {{end}}{{printf "%.1f" $m.Value}}{{end}}</td></tr>{{end}}
+<tr><td>Started</td><td>{{time .Timestamp}}</td></tr>
+{{with .ResolvedAt}}<tr><td>Resolved</td><td>{{time .}}</td></tr>{{end}}
+<tr><td>Labels</td><td>{{labels .Labels}}</td></tr>
+</table>
+{{if .RunbookURL}}<p><a href="{{.RunbookURL}}">Runbook</a></p>{{end}}
+</div>
+{{end}}</body></html>`,
+
+	ChannelSlack: `[
+{"type": "header", "text": {"type": "plain_text", "text": {{json (truncate 150 .Title)}}}}
+{{- range $i, $a := .Alerts}}{{if lt $i 15}},
+{"type": "section",
+ "text": {"type": "mrkdwn", "text": {{json (truncate 3000 (printf "%s  %s" (or (and (eq $a.Status "active") ":red_circle: *Firing*") ":large_green_circle: *Resolved*") $a.Message))}}},
+ "fields": [
+  {"type": "mrkdwn", "text": {{json (printf "*Agent*\n%s" (or (and $a.Agent $a.Agent.Hostname) $a.AgentID))}}},
+  {"type": "mrkdwn", "text": {{json (printf "*Severity*\n%s" (or $a.Severity "-"))}}},
+  {"type": "mrkdwn", "text": {{json (printf "*%s*\n%.2f (threshold %.2f)" (or $a.Metric "-") $a.Value $a.Threshold)}}},
+  {"type": "mrkdwn", "text": {{json (printf "*Started*\n%s" (time $a.Timestamp))}}}
+ ]}
+{{- if $a.RunbookURL}},
+{"type": "context", "elements": [{"type": "mrkdwn", "text": {{json (printf "<%s|Runbook>" $a.RunbookURL)}}}]}
+{{- end}},
+{"type": "divider"}
+{{- end}}{{end}}
+{{- if gt (len .Alerts) 15}},
+{"type": "context", "elements": [{"type": "mrkdwn", "text": {{json (printf "…and %d more" (sub (len .Alerts) 15))}}}]}
+{{- end}}
+]`,
+}
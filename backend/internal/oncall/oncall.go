@@ -0,0 +1,142 @@
+// backend/internal/oncall/oncall.go
+package oncall
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/monitoring"
+)
+
+type Service interface {
+	CreateSchedule(schedule Schedule) (*Schedule, error)
+	UpdateSchedule(schedule Schedule) (*Schedule, error)
+	GetSchedule(id string) (*Schedule, error)
+	ListSchedules() []Schedule
+	// DeleteSchedule fails while an escalation policy still pages it.
+	DeleteSchedule(id string) error
+	AddOverride(scheduleID string, override Override) (*Override, error)
+	RemoveOverride(scheduleID, overrideID string) error
+	// OnCall returns who is on call for the schedule at the given time.
+	OnCall(scheduleID string, at time.Time) (string, error)
+
+	// CreatePolicy also registers the policy as an AlertManager receiver
+	// named ReceiverName(policy.ID), which alert rules route to.
+	CreatePolicy(policy EscalationPolicy) (*EscalationPolicy, error)
+	UpdatePolicy(policy EscalationPolicy) (*EscalationPolicy, error)
+	GetPolicy(id string) (*EscalationPolicy, error)
+	ListPolicies() []EscalationPolicy
+	DeletePolicy(id string) error
+
+	// Acknowledge acknowledges the alert and halts its escalation.
+	Acknowledge(alertID, userID string) (*monitoring.Alert, error)
+	GetEscalation(alertID string) (*Escalation, error)
+	ListEscalations(activeOnly bool) []Escalation
+	// Run moves escalations to their next step as they come due.
+	Run(ctx context.Context)
+}
+
+// Pager delivers a page for an alert to one user.
+type Pager interface {
+	Page(userID string, alert monitoring.Alert) error
+}
+
+// LogPager only logs pages, for setups without a paging channel.
+type LogPager struct{}
+
+func (LogPager) Page(userID string, alert monitoring.Alert) error {
+	fmt.Printf("Paging %s for alert %s: %s\n", userID, alert.ID, alert.Message)
+	return nil
+}
+
+// ReceiverName is the AlertManager receiver an escalation policy is
+// registered under.
+func ReceiverName(policyID string) string {
+	return "oncall:" + policyID
+}
+
+// Schedule says who is on call. Rotations are layers: where several cover
+// the same time the last one wins, and an override beats all of them.
+type Schedule struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Rotations []Rotation `json:"rotations"`
+	Overrides []Override `json:"overrides,omitempty"`
+}
+
+// Rotation hands off from one user to the next every ShiftLength, starting
+// with Users[0] at Start. A weekly rotation has a ShiftLength of 168h.
+type Rotation struct {
+	Name        string        `json:"name"`
+	Users       []string      `json:"users"`
+	Start       time.Time     `json:"start"`
+	ShiftLength time.Duration `json:"shift_length"`
+}
+
+// Override puts User on call from Start to End, e.g. to cover a swap.
+type Override struct {
+	ID     string    `json:"id"`
+	User   string    `json:"user"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Target types for EscalationTarget.Type.
+const (
+	TargetUser     = "user"
+	TargetSchedule = "schedule"
+)
+
+// EscalationPolicy pages its steps in order until the alert is
+// acknowledged or resolved, waiting each step's EscalateAfter before
+// moving on. After the last step it starts over Repeat more times.
+type EscalationPolicy struct {
+	ID     string           `json:"id"`
+	Name   string           `json:"name"`
+	Steps  []EscalationStep `json:"steps"`
+	Repeat int              `json:"repeat,omitempty"`
+}
+
+type EscalationStep struct {
+	Targets       []EscalationTarget `json:"targets"`
+	EscalateAfter time.Duration      `json:"escalate_after"`
+}
+
+// EscalationTarget pages a user directly, or whoever is on call for a
+// schedule at the time of the page.
+type EscalationTarget struct {
+	Type string `json:"type"` // user, schedule
+	ID   string `json:"id"`
+}
+
+// Escalation statuses.
+const (
+	StatusEscalating   = "escalating"
+	StatusAcknowledged = "acknowledged"
+	StatusResolved     = "resolved"
+	StatusExhausted    = "exhausted" // every step paged, nobody acknowledged
+	StatusCancelled    = "cancelled" // the policy was deleted
+)
+
+// Escalation tracks one alert through its policy.
+type Escalation struct {
+	AlertID        string     `json:"alert_id"`
+	PolicyID       string     `json:"policy_id"`
+	Status         string     `json:"status"`
+	Step           int        `json:"step"` // next step to page
+	Round          int        `json:"round"`
+	NextAt         time.Time  `json:"next_at,omitempty"`
+	Pages          []Page     `json:"pages"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+}
+
+type Page struct {
+	Step  int       `json:"step"`
+	User  string    `json:"user"`
+	At    time.Time `json:"at"`
+	Error string    `json:"error,omitempty"`
+}
@@ -0,0 +1,83 @@
+// backend/internal/oncall/pager.go
+package oncall
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/autosysadmin/backend/internal/monitoring"
+)
+
+// Contact is where a user is paged. Every channel that is set gets the page.
+type Contact struct {
+	Email           string `json:"email,omitempty"`
+	SlackWebhookURL string `json:"slack_webhook_url,omitempty"`
+	WebhookURL      string `json:"webhook_url,omitempty"`
+	WebhookSecret   string `json:"webhook_secret,omitempty"`
+}
+
+// LoadContacts reads a JSON object mapping user IDs to their Contact.
+func LoadContacts(path string) (map[string]Contact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contacts: %w", err)
+	}
+	var contacts map[string]Contact
+	if err := json.Unmarshal(data, &contacts); err != nil {
+		return nil, fmt.Errorf("invalid contacts file %s: %w", path, err)
+	}
+	return contacts, nil
+}
+
+// NotifierPager pages users through the alert notifiers, using each user's
+// Contact. Email pages need a Sender. Users without a contact go to
+// Fallback when set, and fail otherwise.
+type NotifierPager struct {
+	Contacts  map[string]Contact
+	Sender    monitoring.EmailSender
+	Templates *monitoring.TemplateRenderer // nil uses the default templates
+	Fallback  Pager
+}
+
+func (p *NotifierPager) Page(userID string, alert monitoring.Alert) error {
+	notifiers := p.notifiers(userID)
+	if len(notifiers) == 0 {
+		if p.Fallback != nil {
+			return p.Fallback.Page(userID, alert)
+		}
+		return fmt.Errorf("no contact configured for user %s", userID)
+	}
+
+	// Try every channel; the page got through if any of them delivered it
+	var errs []error
+	for _, n := range notifiers {
+		if err := n.Notify(alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(notifiers) {
+		return fmt.Errorf("failed to page %s: %w", userID, errors.Join(errs...))
+	}
+	return nil
+}
+
+func (p *NotifierPager) notifiers(userID string) []monitoring.AlertNotifier {
+	contact, exists := p.Contacts[userID]
+	if !exists {
+		return nil
+	}
+
+	var notifiers []monitoring.AlertNotifier
+	if contact.Email != "" && p.Sender != nil {
+		notifiers = append(notifiers, &monitoring.EmailNotifier{Sender: p.Sender, To: []string{contact.Email}, Templates: p.Templates})
+	}
+	if contact.SlackWebhookURL != "" {
+		notifiers = append(notifiers, &monitoring.SlackNotifier{WebhookURL: contact.SlackWebhookURL, Templates: p.Templates})
+	}
+	if contact.WebhookURL != "" {
+		notifiers = append(notifiers, &monitoring.WebhookNotifier{URL: contact.WebhookURL, Secret: contact.WebhookSecret})
+	}
+	return notifiers
+}
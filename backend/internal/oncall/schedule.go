@@ -0,0 +1,68 @@
+// backend/internal/oncall/schedule.go
+package oncall
+
+import (
+	"fmt"
+	"time"
+)
+
+func (s *Schedule) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("schedule name is required")
+	}
+	if len(s.Rotations) == 0 {
+		return fmt.Errorf("at least one rotation is required")
+	}
+	for i, r := range s.Rotations {
+		if len(r.Users) == 0 {
+			return fmt.Errorf("rotation %d has no users", i)
+		}
+		if r.Start.IsZero() {
+			return fmt.Errorf("rotation %d has no start", i)
+		}
+		if r.ShiftLength <= 0 {
+			return fmt.Errorf("rotation %d: shift_length must be positive", i)
+		}
+	}
+	for _, o := range s.Overrides {
+		if err := o.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Override) validate() error {
+	if o.User == "" {
+		return fmt.Errorf("override user is required")
+	}
+	if !o.End.After(o.Start) {
+		return fmt.Errorf("override end must be after start")
+	}
+	return nil
+}
+
+// onCall is whoever the schedule puts on call at the given time, or ""
+// when nothing covers it. The latest override wins over earlier ones.
+func (s *Schedule) onCall(at time.Time) string {
+	for i := len(s.Overrides) - 1; i >= 0; i-- {
+		o := s.Overrides[i]
+		if !at.Before(o.Start) && at.Before(o.End) {
+			return o.User
+		}
+	}
+	for i := len(s.Rotations) - 1; i >= 0; i-- {
+		if user := s.Rotations[i].onCall(at); user != "" {
+			return user
+		}
+	}
+	return ""
+}
+
+func (r *Rotation) onCall(at time.Time) string {
+	if at.Before(r.Start) {
+		return ""
+	}
+	shift := int64(at.Sub(r.Start) / r.ShiftLength)
+	return r.Users[shift%int64(len(r.Users))]
+}
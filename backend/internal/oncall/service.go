@@ -0,0 +1,497 @@
+// backend/internal/oncall/service.go
+package oncall
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/monitoring"
+)
+
+// How long finished escalations stay listed
+const escalationRetention = 7 * 24 * time.Hour
+
+type service struct {
+	alertManager *monitoring.AlertManager
+	pager        Pager
+	schedules    map[string]Schedule         // scheduleID -> schedule
+	policies     map[string]EscalationPolicy // policyID -> policy
+	escalations  map[string]*Escalation      // alertID -> escalation
+	mu           sync.RWMutex
+}
+
+func NewService(alertManager *monitoring.AlertManager, pager Pager) Service {
+	return &service{
+		alertManager: alertManager,
+		pager:        pager,
+		schedules:    make(map[string]Schedule),
+		policies:     make(map[string]EscalationPolicy),
+		escalations:  make(map[string]*Escalation),
+	}
+}
+
+func (s *service) CreateSchedule(schedule Schedule) (*Schedule, error) {
+	schedule.ID = fmt.Sprintf("schedule-%d", time.Now().UnixNano())
+	return s.putSchedule(schedule)
+}
+
+func (s *service) UpdateSchedule(schedule Schedule) (*Schedule, error) {
+	s.mu.RLock()
+	_, exists := s.schedules[schedule.ID]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("schedule not found")
+	}
+	return s.putSchedule(schedule)
+}
+
+func (s *service) putSchedule(schedule Schedule) (*Schedule, error) {
+	for i := range schedule.Overrides {
+		if schedule.Overrides[i].ID == "" {
+			schedule.Overrides[i].ID = fmt.Sprintf("override-%d-%d", time.Now().UnixNano(), i)
+		}
+	}
+	if err := schedule.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules[schedule.ID] = schedule
+	return &schedule, nil
+}
+
+func (s *service) GetSchedule(id string) (*Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, exists := s.schedules[id]
+	if !exists {
+		return nil, fmt.Errorf("schedule not found")
+	}
+	return &schedule, nil
+}
+
+func (s *service) ListSchedules() []Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedules := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+	return schedules
+}
+
+func (s *service) DeleteSchedule(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.schedules[id]; !exists {
+		return fmt.Errorf("schedule not found")
+	}
+	for _, policy := range s.policies {
+		for _, step := range policy.Steps {
+			for _, target := range step.Targets {
+				if target.Type == TargetSchedule && target.ID == id {
+					return fmt.Errorf("schedule is used by escalation policy %s", policy.Name)
+				}
+			}
+		}
+	}
+	delete(s.schedules, id)
+	return nil
+}
+
+func (s *service) AddOverride(scheduleID string, override Override) (*Override, error) {
+	if err := override.validate(); err != nil {
+		return nil, err
+	}
+	override.ID = fmt.Sprintf("override-%d", time.Now().UnixNano())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, exists := s.schedules[scheduleID]
+	if !exists {
+		return nil, fmt.Errorf("schedule not found")
+	}
+	// Drop overrides that have ended while we're here
+	now := time.Now()
+	overrides := []Override{}
+	for _, o := range schedule.Overrides {
+		if o.End.After(now) {
+			overrides = append(overrides, o)
+		}
+	}
+	schedule.Overrides = append(overrides, override)
+	s.schedules[scheduleID] = schedule
+	return &override, nil
+}
+
+func (s *service) RemoveOverride(scheduleID, overrideID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, exists := s.schedules[scheduleID]
+	if !exists {
+		return fmt.Errorf("schedule not found")
+	}
+	for i, o := range schedule.Overrides {
+		if o.ID == overrideID {
+			schedule.Overrides = append(schedule.Overrides[:i:i], schedule.Overrides[i+1:]...)
+			s.schedules[scheduleID] = schedule
+			return nil
+		}
+	}
+	return fmt.Errorf("override not found")
+}
+
+func (s *service) OnCall(scheduleID string, at time.Time) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, exists := s.schedules[scheduleID]
+	if !exists {
+		return "", fmt.Errorf("schedule not found")
+	}
+	user := schedule.onCall(at)
+	if user == "" {
+		return "", fmt.Errorf("nobody is on call for %s at %s", schedule.Name, at.Format(time.RFC3339))
+	}
+	return user, nil
+}
+
+func (s *service) CreatePolicy(policy EscalationPolicy) (*EscalationPolicy, error) {
+	policy.ID = fmt.Sprintf("policy-%d", time.Now().UnixNano())
+	return s.putPolicy(policy)
+}
+
+func (s *service) UpdatePolicy(policy EscalationPolicy) (*EscalationPolicy, error) {
+	s.mu.RLock()
+	_, exists := s.policies[policy.ID]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("escalation policy not found")
+	}
+	return s.putPolicy(policy)
+}
+
+func (s *service) putPolicy(policy EscalationPolicy) (*EscalationPolicy, error) {
+	if policy.Name == "" {
+		return nil, fmt.Errorf("policy name is required")
+	}
+	if len(policy.Steps) == 0 {
+		return nil, fmt.Errorf("at least one step is required")
+	}
+	if policy.Repeat < 0 {
+		return nil, fmt.Errorf("repeat must not be negative")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, step := range policy.Steps {
+		if len(step.Targets) == 0 {
+			return nil, fmt.Errorf("step %d has no targets", i)
+		}
+		last := i == len(policy.Steps)-1
+		if step.EscalateAfter <= 0 && (!last || policy.Repeat > 0) {
+			return nil, fmt.Errorf("step %d: escalate_after must be positive", i)
+		}
+		for _, target := range step.Targets {
+			switch target.Type {
+			case TargetUser:
+				if target.ID == "" {
+					return nil, fmt.Errorf("step %d: user target needs an id", i)
+				}
+			case TargetSchedule:
+				if _, exists := s.schedules[target.ID]; !exists {
+					return nil, fmt.Errorf("step %d: schedule %q not found", i, target.ID)
+				}
+			default:
+				return nil, fmt.Errorf("step %d: target type must be user or schedule", i)
+			}
+		}
+	}
+
+	s.policies[policy.ID] = policy
+	s.alertManager.AddReceiver(ReceiverName(policy.ID), &policyReceiver{service: s, policyID: policy.ID})
+	return &policy, nil
+}
+
+func (s *service) GetPolicy(id string) (*EscalationPolicy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	policy, exists := s.policies[id]
+	if !exists {
+		return nil, fmt.Errorf("escalation policy not found")
+	}
+	return &policy, nil
+}
+
+func (s *service) ListPolicies() []EscalationPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	policies := make([]EscalationPolicy, 0, len(s.policies))
+	for _, policy := range s.policies {
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	return policies
+}
+
+// DeletePolicy removes the policy and its receiver. Escalations running
+// under it are cancelled at their next step.
+func (s *service) DeletePolicy(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.policies[id]; !exists {
+		return fmt.Errorf("escalation policy not found")
+	}
+	delete(s.policies, id)
+	s.alertManager.RemoveReceiver(ReceiverName(id))
+	return nil
+}
+
+// policyReceiver starts and stops escalations as the AlertManager notifies
+// it of alerts routed to the policy.
+type policyReceiver struct {
+	service  *service
+	policyID string
+}
+
+func (r *policyReceiver) Notify(alert monitoring.Alert) error {
+	if alert.Status != "active" {
+		r.service.finish(alert.ID, StatusResolved, "")
+		return nil
+	}
+	if !r.service.start(r.policyID, alert) {
+		return nil // already escalating; a repeat notification
+	}
+	r.service.step(alert.ID, time.Now())
+	return nil
+}
+
+func (s *service) start(policyID string, alert monitoring.Alert) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.escalations[alert.ID]; exists {
+		return false
+	}
+	now := time.Now()
+	s.escalations[alert.ID] = &Escalation{
+		AlertID:   alert.ID,
+		PolicyID:  policyID,
+		Status:    StatusEscalating,
+		NextAt:    now,
+		Pages:     []Page{},
+		StartedAt: now,
+	}
+	return true
+}
+
+func (s *service) finish(alertID, status, acknowledgedBy string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finishLocked(alertID, status, acknowledgedBy)
+}
+
+func (s *service) finishLocked(alertID, status, acknowledgedBy string) {
+	esc, exists := s.escalations[alertID]
+	if !exists || esc.Status != StatusEscalating {
+		return
+	}
+	now := time.Now()
+	esc.Status = status
+	esc.AcknowledgedBy = acknowledgedBy
+	esc.NextAt = time.Time{}
+	esc.EndedAt = &now
+}
+
+func (s *service) Acknowledge(alertID, userID string) (*monitoring.Alert, error) {
+	alert, err := s.alertManager.AcknowledgeAlert(alertID, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.finish(alertID, StatusAcknowledged, alert.AcknowledgedBy)
+	return alert, nil
+}
+
+// step pages the escalation's current step and schedules the next one. It
+// checks the alert first, so an ack or resolution that raced the timer
+// still halts the escalation.
+func (s *service) step(alertID string, now time.Time) {
+	alert, err := s.alertManager.GetAlert(alertID)
+
+	s.mu.Lock()
+	esc, exists := s.escalations[alertID]
+	if !exists || esc.Status != StatusEscalating || now.Before(esc.NextAt) {
+		s.mu.Unlock()
+		return
+	}
+	switch {
+	case err != nil || alert.Status != "active":
+		s.finishLocked(alertID, StatusResolved, "")
+		s.mu.Unlock()
+		return
+	case alert.AcknowledgedAt != nil:
+		s.finishLocked(alertID, StatusAcknowledged, alert.AcknowledgedBy)
+		s.mu.Unlock()
+		return
+	}
+	policy, exists := s.policies[esc.PolicyID]
+	if !exists {
+		s.finishLocked(alertID, StatusCancelled, "")
+		s.mu.Unlock()
+		return
+	}
+
+	// UpdatePolicy may have shortened the policy since the last step; being
+	// past its end counts as finishing the round
+	if esc.Step >= len(policy.Steps) {
+		if esc.Round >= policy.Repeat {
+			esc.Status = StatusExhausted
+			esc.NextAt = time.Time{}
+			esc.EndedAt = &now
+			s.mu.Unlock()
+			return
+		}
+		esc.Round++
+		esc.Step = 0
+	}
+
+	stepIndex := esc.Step
+	step := policy.Steps[stepIndex]
+	users, pages := s.targetsLocked(step, stepIndex, now)
+
+	esc.Step++
+	if esc.Step == len(policy.Steps) {
+		if esc.Round < policy.Repeat {
+			esc.Round++
+			esc.Step = 0
+		} else {
+			esc.Status = StatusExhausted
+			esc.EndedAt = &now
+		}
+	}
+	if esc.Status == StatusEscalating {
+		esc.NextAt = now.Add(step.EscalateAfter)
+	} else {
+		esc.NextAt = time.Time{}
+	}
+	s.mu.Unlock()
+
+	// Page outside the lock; the pager may be slow
+	for _, user := range users {
+		page := Page{Step: stepIndex, User: user, At: now}
+		if err := s.pager.Page(user, *alert); err != nil {
+			page.Error = err.Error()
+		}
+		pages = append(pages, page)
+	}
+
+	s.mu.Lock()
+	esc.Pages = append(esc.Pages, pages...)
+	s.mu.Unlock()
+}
+
+// targetsLocked resolves a step's targets to users, once each. Schedules
+// nobody covers are recorded as failed pages.
+func (s *service) targetsLocked(step EscalationStep, stepIndex int, now time.Time) ([]string, []Page) {
+	var users []string
+	var failed []Page
+	seen := make(map[string]bool)
+	for _, target := range step.Targets {
+		user := target.ID
+		if target.Type == TargetSchedule {
+			schedule, exists := s.schedules[target.ID]
+			if exists {
+				user = schedule.onCall(now)
+			} else {
+				user = ""
+			}
+			if user == "" {
+				failed = append(failed, Page{Step: stepIndex, At: now, Error: fmt.Sprintf("nobody on call for schedule %s", target.ID)})
+				continue
+			}
+		}
+		if !seen[user] {
+			seen[user] = true
+			users = append(users, user)
+		}
+	}
+	return users, failed
+}
+
+func (s *service) GetEscalation(alertID string) (*Escalation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	esc, exists := s.escalations[alertID]
+	if !exists {
+		return nil, fmt.Errorf("escalation not found")
+	}
+	copied := *esc
+	copied.Pages = append([]Page(nil), esc.Pages...)
+	return &copied, nil
+}
+
+func (s *service) ListEscalations(activeOnly bool) []Escalation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	escalations := []Escalation{}
+	for _, esc := range s.escalations {
+		if activeOnly && esc.Status != StatusEscalating {
+			continue
+		}
+		copied := *esc
+		copied.Pages = append([]Page(nil), esc.Pages...)
+		escalations = append(escalations, copied)
+	}
+	sort.Slice(escalations, func(i, j int) bool { return escalations[i].StartedAt.After(escalations[j].StartedAt) })
+	return escalations
+}
+
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, alertID := range s.due(now) {
+				s.step(alertID, now)
+			}
+		}
+	}
+}
+
+// due returns the escalations whose next step has come, and forgets
+// finished ones past their retention.
+func (s *service) due(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []string
+	for alertID, esc := range s.escalations {
+		switch {
+		case esc.Status == StatusEscalating && !now.Before(esc.NextAt):
+			due = append(due, alertID)
+		case esc.EndedAt != nil && now.Sub(*esc.EndedAt) > escalationRetention:
+			delete(s.escalations, alertID)
+		}
+	}
+	return due
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/autosysadmin/backend/internal/agent"
 	"github.com/autosysadmin/backend/internal/jobqueue"
+	"github.com/autosysadmin/backend/internal/maintenance"
 )
 
 type PatchManager interface {
@@ -31,7 +32,7 @@ type PatchRecord struct {
 	ID        string    `json:"id"`
 	AgentID   string    `json:"agent_id"`
 	Updates   []string  `json:"updates"`
-	Status    string    `json:"status"` // pending, in-progress, completed, failed
+	Status    string    `json:"status"` // scheduled, pending, in-progress, completed, failed, blocked
 	StartedAt time.Time `json:"started_at"`
 	EndedAt   time.Time `json:"ended_at"`
 	Logs      string    `json:"logs"`
@@ -40,15 +41,19 @@ type PatchRecord struct {
 type patchManager struct {
 	agentManager *agent.Manager
 	jobQueue     jobqueue.JobQueue
+	maintenance  maintenance.Service
 	updates      map[string][]Update // agentID -> updates
 	history      map[string][]PatchRecord // agentID -> history
 	mu           sync.RWMutex
 }
 
-func NewPatchManager() PatchManager {
+func NewPatchManager(agentManager *agent.Manager, jobQueue jobqueue.JobQueue, maintenanceService maintenance.Service) PatchManager {
 	return &patchManager{
-		updates: make(map[string][]Update),
-		history: make(map[string][]PatchRecord),
+		agentManager: agentManager,
+		jobQueue:     jobQueue,
+		maintenance:  maintenanceService,
+		updates:      make(map[string][]Update),
+		history:      make(map[string][]PatchRecord),
 	}
 }
 
@@ -65,7 +70,7 @@ func (m *patchManager) CheckForUpdates(agentID string) ([]Update, error) {
 }
 
 func (m *patchManager) ApplyUpdates(agentID string, updates []string) (string, error) {
-	agent, exists := m.agentManager.GetAgent(agentID)
+	a, exists := m.agentManager.GetAgent(agentID)
 	if !exists {
 		return "", fmt.Errorf("agent not found")
 	}
@@ -83,6 +88,13 @@ func (m *patchManager) ApplyUpdates(agentID string, updates []string) (string, e
 	m.history[agentID] = append(m.history[agentID], record)
 	m.mu.Unlock()
 
+	if err := m.startPatch(a, record.ID, updates); err != nil {
+		return "", err
+	}
+	return record.ID, nil
+}
+
+func (m *patchManager) startPatch(a *agent.Agent, patchID string, updates []string) error {
 	// Create job to apply updates
 	cmd := agent.AgentCommand{
 		Command: "apply-updates",
@@ -91,14 +103,15 @@ func (m *patchManager) ApplyUpdates(agentID string, updates []string) (string, e
 	}
 
 	ctx := context.Background()
-	result, err := agent.ExecuteCommand(ctx, cmd, m.jobQueue)
+	result, err := a.ExecuteCommand(ctx, cmd, m.jobQueue)
 	if err != nil {
-		m.updatePatchStatus(agentID, record.ID, "failed", err.Error())
-		return "", err
+		m.updatePatchStatus(a.ID, patchID, "failed", err.Error())
+		return err
 	}
 
-	go m.monitorPatchJob(agentID, record.ID, string(result))
-	return record.ID, nil
+	m.updatePatchStatus(a.ID, patchID, "in-progress", "")
+	go m.monitorPatchJob(a.ID, patchID, string(result))
+	return nil
 }
 
 func (m *patchManager) monitorPatchJob(agentID, patchID, jobID string) {
@@ -114,7 +127,11 @@ func (m *patchManager) updatePatchStatus(agentID, patchID, status, logs string)
 	for i, record := range m.history[agentID] {
 		if record.ID == patchID {
 			m.history[agentID][i].Status = status
-			m.history[agentID][i].EndedAt = time.Now()
+			if status == "in-progress" {
+				m.history[agentID][i].StartedAt = time.Now()
+			} else {
+				m.history[agentID][i].EndedAt = time.Now()
+			}
 			m.history[agentID][i].Logs = logs
 			break
 		}
@@ -134,7 +151,10 @@ func (m *patchManager) GetPatchHistory(agentID string) ([]PatchRecord, error) {
 }
 
 func (m *patchManager) SchedulePatch(agentID string, updates []string, when time.Time) (string, error) {
-	// In a real implementation, this would schedule a job for future execution
+	if _, exists := m.agentManager.GetAgent(agentID); !exists {
+		return "", fmt.Errorf("agent not found")
+	}
+
 	record := PatchRecord{
 		ID:        fmt.Sprintf("patch-%s-%d", agentID, time.Now().Unix()),
 		AgentID:   agentID,
@@ -147,5 +167,28 @@ func (m *patchManager) SchedulePatch(agentID string, updates []string, when time
 	m.history[agentID] = append(m.history[agentID], record)
 	m.mu.Unlock()
 
+	time.AfterFunc(time.Until(when), func() {
+		m.runScheduledPatch(agentID, record.ID, updates)
+	})
 	return record.ID, nil
+}
+
+// runScheduledPatch starts a scheduled patch unless the agent is in a
+// maintenance window, in which case the patch is blocked rather than
+// deferred; it can be rescheduled once the work on the host is done.
+func (m *patchManager) runScheduledPatch(agentID, patchID string, updates []string) {
+	if m.maintenance != nil {
+		if w := m.maintenance.ActiveWindow(agentID); w != nil {
+			m.updatePatchStatus(agentID, patchID, "blocked",
+				fmt.Sprintf("Agent in maintenance window %s (%s, owner %s)", w.ID, w.Reason, w.Owner))
+			return
+		}
+	}
+
+	a, exists := m.agentManager.GetAgent(agentID)
+	if !exists {
+		m.updatePatchStatus(agentID, patchID, "failed", "agent not found")
+		return
+	}
+	m.startPatch(a, patchID, updates)
 }
\ No newline at end of file
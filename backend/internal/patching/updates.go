@@ -3,7 +3,8 @@ package patching
 
 import (
 	"context"
-	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
 )
 
 type UpdateChecker interface {
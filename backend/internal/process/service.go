@@ -0,0 +1,139 @@
+// backend/internal/process/service.go
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type Service interface {
+	// ListProcesses returns the agent's last reported process table, or
+	// asks the agent for a fresh one when live is set.
+	ListProcesses(ctx context.Context, agentID string, live bool) ([]agent.ProcessStats, error)
+	Signal(ctx context.Context, agentID string, pid int, signal string) (*ActionResult, error)
+	KillTree(ctx context.Context, agentID string, pid int, signal string) (*ActionResult, error)
+	Renice(ctx context.Context, agentID string, pid, nice int) (*ActionResult, error)
+}
+
+type ActionResult struct {
+	AgentID  string `json:"agent_id"`
+	PID      int    `json:"pid"`
+	Action   string `json:"action"`
+	Signal   string `json:"signal,omitempty"`
+	Nice     *int   `json:"nice,omitempty"`
+	Signaled []int  `json:"signaled,omitempty"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+type service struct {
+	agentManager *agent.Manager
+	timeout      time.Duration
+}
+
+func NewService(agentManager *agent.Manager) Service {
+	return &service{
+		agentManager: agentManager,
+		timeout:      time.Minute,
+	}
+}
+
+func (s *service) ListProcesses(ctx context.Context, agentID string, live bool) ([]agent.ProcessStats, error) {
+	if !live {
+		stats, err := s.agentManager.GetStats(agentID)
+		if err != nil {
+			return nil, err
+		}
+		return stats.Processes, nil
+	}
+
+	output, err := s.run(ctx, agentID, agent.ProcessListCommand)
+	if err != nil {
+		return nil, err
+	}
+	var procs []agent.ProcessStats
+	if err := json.Unmarshal([]byte(output), &procs); err != nil {
+		return nil, fmt.Errorf("failed to parse process list: %w", err)
+	}
+	return procs, nil
+}
+
+func (s *service) Signal(ctx context.Context, agentID string, pid int, signal string) (*ActionResult, error) {
+	return s.signal(ctx, agentID, agent.ProcessSignalCommand, "signal", pid, signal)
+}
+
+func (s *service) KillTree(ctx context.Context, agentID string, pid int, signal string) (*ActionResult, error) {
+	if signal == "" {
+		signal = "TERM"
+	}
+	return s.signal(ctx, agentID, agent.ProcessKillTreeCommand, "kill-tree", pid, signal)
+}
+
+func (s *service) signal(ctx context.Context, agentID, command, action string, pid int, signal string) (*ActionResult, error) {
+	if err := validatePID(pid); err != nil {
+		return nil, err
+	}
+	if _, err := agent.ParseSignal(signal); err != nil {
+		return nil, err
+	}
+
+	result := &ActionResult{AgentID: agentID, PID: pid, Action: action, Signal: signal}
+	output, err := s.run(ctx, agentID, command, strconv.Itoa(pid), signal)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	var out struct {
+		Signaled []int `json:"signaled"`
+	}
+	json.Unmarshal([]byte(output), &out)
+	result.Signaled = out.Signaled
+	result.Success = true
+	return result, nil
+}
+
+func (s *service) Renice(ctx context.Context, agentID string, pid, nice int) (*ActionResult, error) {
+	if err := validatePID(pid); err != nil {
+		return nil, err
+	}
+	if nice < -20 || nice > 19 {
+		return nil, fmt.Errorf("nice must be between -20 and 19")
+	}
+
+	result := &ActionResult{AgentID: agentID, PID: pid, Action: "renice", Nice: &nice}
+	if _, err := s.run(ctx, agentID, agent.ProcessReniceCommand, strconv.Itoa(pid), strconv.Itoa(nice)); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+	}
+	return result, nil
+}
+
+func (s *service) run(ctx context.Context, agentID, command string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	job, err := s.agentManager.RunCommandAndWait(ctx, agentID, agent.AgentCommand{
+		Command: command,
+		Args:    args,
+		Timeout: s.timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	return job.Result, nil
+}
+
+func validatePID(pid int) error {
+	// PID 1 is init; signalling it takes the whole host down
+	if pid <= 1 {
+		return fmt.Errorf("invalid pid %d", pid)
+	}
+	return nil
+}
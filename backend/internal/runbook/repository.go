@@ -0,0 +1,121 @@
+// backend/internal/runbook/repository.go
+package runbook
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+type Repository interface {
+	SaveRunbook(ctx context.Context, rb *Runbook) error
+	GetRunbook(ctx context.Context, id string) (*Runbook, error)
+	ListRunbooks(ctx context.Context) ([]Runbook, error)
+	DeleteRunbook(ctx context.Context, id string) error
+	SaveExecution(ctx context.Context, exec *Execution) error
+	GetExecution(ctx context.Context, id string) (*Execution, error)
+	ListExecutions(ctx context.Context, runbookID string) ([]Execution, error)
+}
+
+type inMemoryRepository struct {
+	runbooks   map[string]Runbook
+	executions map[string]Execution
+	mu         sync.RWMutex
+}
+
+func NewInMemoryRepository() Repository {
+	return &inMemoryRepository{
+		runbooks:   make(map[string]Runbook),
+		executions: make(map[string]Execution),
+	}
+}
+
+func (r *inMemoryRepository) SaveRunbook(ctx context.Context, rb *Runbook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runbooks[rb.ID] = *rb
+	return nil
+}
+
+func (r *inMemoryRepository) GetRunbook(ctx context.Context, id string) (*Runbook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rb, exists := r.runbooks[id]
+	if !exists {
+		return nil, errors.New("runbook not found")
+	}
+	return &rb, nil
+}
+
+func (r *inMemoryRepository) ListRunbooks(ctx context.Context) ([]Runbook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	runbooks := make([]Runbook, 0, len(r.runbooks))
+	for _, rb := range r.runbooks {
+		runbooks = append(runbooks, rb)
+	}
+	sort.Slice(runbooks, func(i, j int) bool { return runbooks[i].Name < runbooks[j].Name })
+	return runbooks, nil
+}
+
+func (r *inMemoryRepository) DeleteRunbook(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.runbooks[id]; !exists {
+		return errors.New("runbook not found")
+	}
+	delete(r.runbooks, id)
+	return nil
+}
+
+// SaveExecution stores a deep copy so the engine can keep mutating its own
+// record while readers see consistent snapshots.
+func (r *inMemoryRepository) SaveExecution(ctx context.Context, exec *Execution) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.executions[exec.ID] = copyExecution(exec)
+	return nil
+}
+
+func (r *inMemoryRepository) GetExecution(ctx context.Context, id string) (*Execution, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	exec, exists := r.executions[id]
+	if !exists {
+		return nil, errors.New("execution not found")
+	}
+	c := copyExecution(&exec)
+	return &c, nil
+}
+
+func (r *inMemoryRepository) ListExecutions(ctx context.Context, runbookID string) ([]Execution, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var executions []Execution
+	for _, exec := range r.executions {
+		if runbookID == "" || exec.RunbookID == runbookID {
+			executions = append(executions, copyExecution(&exec))
+		}
+	}
+	sort.Slice(executions, func(i, j int) bool { return executions[i].StartedAt.After(executions[j].StartedAt) })
+	return executions, nil
+}
+
+func copyExecution(exec *Execution) Execution {
+	c := *exec
+	c.Hosts = make(map[string]*HostExecution, len(exec.Hosts))
+	for agentID, host := range exec.Hosts {
+		h := *host
+		h.Steps = append([]StepResult(nil), host.Steps...)
+		c.Hosts[agentID] = &h
+	}
+	return c
+}
@@ -0,0 +1,181 @@
+// backend/internal/runbook/runbook.go
+package runbook
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+// Runbook is a named sequence of steps executed on each targeted agent.
+// It is written in YAML, for example:
+//
+//	name: patch-web
+//	vars:
+//	  service: nginx
+//	steps:
+//	  - name: drain
+//	    type: http
+//	    http: {method: POST, url: "http://lb.internal/drain/{{ .Agent.Hostname }}"}
+//	  - name: stop
+//	    type: command
+//	    command: systemctl
+//	    args: [stop, "{{ .Vars.service }}"]
+//	    on_failure:
+//	      - {name: undrain, type: http, http: {method: POST, url: "http://lb.internal/undrain/{{ .Agent.Hostname }}"}}
+//	  - name: approve-reboot
+//	    type: approval
+//	    message: "Reboot {{ .Agent.Hostname }}?"
+type Runbook struct {
+	ID          string            `yaml:"-" json:"id"`
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description" json:"description"`
+	Vars        map[string]string `yaml:"vars" json:"vars"`
+	Steps       []Step            `yaml:"steps" json:"steps"`
+	Source      string            `yaml:"-" json:"source"`
+	CreatedAt   time.Time         `yaml:"-" json:"created_at"`
+}
+
+type Step struct {
+	Name              string        `yaml:"name" json:"name"`
+	Type              string        `yaml:"type" json:"type"` // command, file, wait, http, approval
+	When              string        `yaml:"when" json:"when,omitempty"`
+	Timeout           time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+	ContinueOnFailure bool          `yaml:"continue_on_failure" json:"continue_on_failure,omitempty"`
+	OnFailure         []Step        `yaml:"on_failure" json:"on_failure,omitempty"`
+
+	// command
+	Command string   `yaml:"command" json:"command,omitempty"`
+	Args    []string `yaml:"args" json:"args,omitempty"`
+
+	// file
+	File *FileSpec `yaml:"file" json:"file,omitempty"`
+
+	// wait
+	Duration time.Duration `yaml:"duration" json:"duration,omitempty"`
+
+	// http
+	HTTP *HTTPSpec `yaml:"http" json:"http,omitempty"`
+
+	// approval
+	Message string `yaml:"message" json:"message,omitempty"`
+}
+
+type FileSpec struct {
+	Path    string `yaml:"path" json:"path"`
+	Content string `yaml:"content" json:"content"`
+	Mode    string `yaml:"mode" json:"mode,omitempty"`
+	Owner   string `yaml:"owner" json:"owner,omitempty"`
+	Group   string `yaml:"group" json:"group,omitempty"`
+}
+
+type HTTPSpec struct {
+	Method       string            `yaml:"method" json:"method"`
+	URL          string            `yaml:"url" json:"url"`
+	Headers      map[string]string `yaml:"headers" json:"headers,omitempty"`
+	Body         string            `yaml:"body" json:"body,omitempty"`
+	ExpectStatus int               `yaml:"expect_status" json:"expect_status,omitempty"` // 0 means any 2xx
+	ExpectBody   string            `yaml:"expect_body" json:"expect_body,omitempty"`     // substring
+}
+
+const defaultStepTimeout = 10 * time.Minute
+
+// Parse decodes and validates a YAML runbook.
+func Parse(source []byte) (*Runbook, error) {
+	var rb Runbook
+	if err := yaml.Unmarshal(source, &rb); err != nil {
+		return nil, fmt.Errorf("invalid runbook YAML: %w", err)
+	}
+	if rb.Name == "" {
+		return nil, fmt.Errorf("runbook name is required")
+	}
+	if len(rb.Steps) == 0 {
+		return nil, fmt.Errorf("runbook has no steps")
+	}
+	if err := validateSteps(rb.Steps, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+	rb.Source = string(source)
+	return &rb, nil
+}
+
+// validateSteps checks each step and that names are unique across the
+// runbook, including failure handlers, since later steps refer to earlier
+// ones by name.
+func validateSteps(steps []Step, seen map[string]bool) error {
+	for _, step := range steps {
+		if step.Name == "" {
+			return fmt.Errorf("every step needs a name")
+		}
+		if seen[step.Name] {
+			return fmt.Errorf("duplicate step name %q", step.Name)
+		}
+		seen[step.Name] = true
+
+		switch step.Type {
+		case "command":
+			if step.Command == "" {
+				return fmt.Errorf("step %q: command is required", step.Name)
+			}
+		case "file":
+			if step.File == nil || step.File.Path == "" {
+				return fmt.Errorf("step %q: file.path is required", step.Name)
+			}
+		case "wait":
+			if step.Duration <= 0 {
+				return fmt.Errorf("step %q: duration must be positive", step.Name)
+			}
+		case "http":
+			if step.HTTP == nil || step.HTTP.URL == "" {
+				return fmt.Errorf("step %q: http.url is required", step.Name)
+			}
+		case "approval":
+		default:
+			return fmt.Errorf("step %q: unknown type %q", step.Name, step.Type)
+		}
+
+		if err := validateSteps(step.OnFailure, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ExecuteRequest struct {
+	Selector agent.Selector    `json:"selector"`
+	Vars     map[string]string `json:"vars"`
+	Parallel int               `json:"parallel"` // agents run at once; default 1 (rolling)
+}
+
+type Execution struct {
+	ID          string                    `json:"id"`
+	RunbookID   string                    `json:"runbook_id"`
+	RunbookName string                    `json:"runbook_name"`
+	Vars        map[string]string         `json:"vars"`
+	Status      string                    `json:"status"` // running, succeeded, failed, cancelled
+	Hosts       map[string]*HostExecution `json:"hosts"`  // agentID -> progress
+	StartedAt   time.Time                 `json:"started_at"`
+	EndedAt     time.Time                 `json:"ended_at,omitempty"`
+}
+
+type HostExecution struct {
+	AgentID string       `json:"agent_id"`
+	Status  string       `json:"status"` // pending, running, succeeded, failed, cancelled, skipped
+	Steps   []StepResult `json:"steps"`
+}
+
+type StepResult struct {
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Handler    bool      `json:"handler,omitempty"` // ran as an on_failure handler
+	Status     string    `json:"status"`            // running, succeeded, failed, skipped, awaiting-approval
+	Output     string    `json:"output,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ApprovedBy string    `json:"approved_by,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at,omitempty"`
+}
@@ -0,0 +1,595 @@
+// backend/internal/runbook/service.go
+package runbook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/transfer"
+)
+
+const (
+	defaultApprovalTimeout = 24 * time.Hour
+	maxOutputBytes         = 64 * 1024
+)
+
+type Service interface {
+	CreateRunbook(ctx context.Context, source []byte) (*Runbook, error)
+	GetRunbook(ctx context.Context, id string) (*Runbook, error)
+	ListRunbooks(ctx context.Context) ([]Runbook, error)
+	DeleteRunbook(ctx context.Context, id string) error
+	Execute(ctx context.Context, runbookID string, req ExecuteRequest) (*Execution, error)
+	GetExecution(ctx context.Context, id string) (*Execution, error)
+	ListExecutions(ctx context.Context, runbookID string) ([]Execution, error)
+	Approve(executionID, agentID, step, approver string, approved bool) error
+	Cancel(executionID string) error
+}
+
+type approval struct {
+	approved bool
+	approver string
+}
+
+// run is the in-flight state of an execution. The execution record is
+// only touched under service.mu and written through to the repository
+// after every change.
+type run struct {
+	exec      *Execution
+	cancel    context.CancelFunc
+	approvals map[string]chan approval // agentID|step -> pending approval
+}
+
+type service struct {
+	agentManager    *agent.Manager
+	transferService transfer.Service
+	repo            Repository
+	client          *http.Client
+	runs            map[string]*run // executionID -> run
+	mu              sync.Mutex
+}
+
+func NewService(agentManager *agent.Manager, transferService transfer.Service, repo Repository) Service {
+	return &service{
+		agentManager:    agentManager,
+		transferService: transferService,
+		repo:            repo,
+		client:          &http.Client{},
+		runs:            make(map[string]*run),
+	}
+}
+
+func (s *service) CreateRunbook(ctx context.Context, source []byte) (*Runbook, error) {
+	rb, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	rb.ID = fmt.Sprintf("rb-%d", time.Now().UnixNano())
+	rb.CreatedAt = time.Now()
+
+	if err := s.repo.SaveRunbook(ctx, rb); err != nil {
+		return nil, fmt.Errorf("failed to save runbook: %w", err)
+	}
+	return rb, nil
+}
+
+func (s *service) GetRunbook(ctx context.Context, id string) (*Runbook, error) {
+	return s.repo.GetRunbook(ctx, id)
+}
+
+func (s *service) ListRunbooks(ctx context.Context) ([]Runbook, error) {
+	return s.repo.ListRunbooks(ctx)
+}
+
+func (s *service) DeleteRunbook(ctx context.Context, id string) error {
+	return s.repo.DeleteRunbook(ctx, id)
+}
+
+func (s *service) GetExecution(ctx context.Context, id string) (*Execution, error) {
+	return s.repo.GetExecution(ctx, id)
+}
+
+func (s *service) ListExecutions(ctx context.Context, runbookID string) ([]Execution, error) {
+	return s.repo.ListExecutions(ctx, runbookID)
+}
+
+// Execute starts the runbook on every agent matching the selector and
+// returns immediately; progress is read back with GetExecution. At most
+// req.Parallel agents run at once, and once any agent fails no further
+// agents are started.
+func (s *service) Execute(ctx context.Context, runbookID string, req ExecuteRequest) (*Execution, error) {
+	rb, err := s.repo.GetRunbook(ctx, runbookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Selector.IsEmpty() {
+		return nil, fmt.Errorf("selector is required")
+	}
+	agents := s.agentManager.SelectAgents(req.Selector)
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agents match the selector")
+	}
+	if req.Parallel <= 0 {
+		req.Parallel = 1
+	}
+
+	vars := make(map[string]string)
+	for k, v := range rb.Vars {
+		vars[k] = v
+	}
+	for k, v := range req.Vars {
+		vars[k] = v
+	}
+
+	exec := &Execution{
+		ID:          fmt.Sprintf("rbx-%d", time.Now().UnixNano()),
+		RunbookID:   rb.ID,
+		RunbookName: rb.Name,
+		Vars:        vars,
+		Status:      "running",
+		Hosts:       make(map[string]*HostExecution),
+		StartedAt:   time.Now(),
+	}
+	for _, a := range agents {
+		exec.Hosts[a.ID] = &HostExecution{AgentID: a.ID, Status: "pending", Steps: []StepResult{}}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	r := &run{exec: exec, cancel: cancel, approvals: make(map[string]chan approval)}
+
+	s.mu.Lock()
+	s.runs[exec.ID] = r
+	s.persist(exec)
+	snapshot := copyExecution(exec)
+	s.mu.Unlock()
+
+	go s.run(runCtx, r, rb, agents, req.Parallel)
+
+	return &snapshot, nil
+}
+
+func (s *service) Approve(executionID, agentID, step, approver string, approved bool) error {
+	s.mu.Lock()
+	r, exists := s.runs[executionID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("execution is not running")
+	}
+	ch, pending := r.approvals[agentID+"|"+step]
+	delete(r.approvals, agentID+"|"+step)
+	s.mu.Unlock()
+
+	if !pending {
+		return fmt.Errorf("step %q on agent %s is not awaiting approval", step, agentID)
+	}
+	ch <- approval{approved: approved, approver: approver}
+	return nil
+}
+
+func (s *service) Cancel(executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, exists := s.runs[executionID]
+	if !exists {
+		return fmt.Errorf("execution is not running")
+	}
+	r.cancel()
+	return nil
+}
+
+func (s *service) run(ctx context.Context, r *run, rb *Runbook, agents []*agent.Agent, parallel int) {
+	defer r.cancel()
+
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var failed bool
+	var failedMu sync.Mutex
+
+	for _, a := range agents {
+		sem <- struct{}{}
+
+		failedMu.Lock()
+		stop := failed || ctx.Err() != nil
+		failedMu.Unlock()
+		if stop {
+			<-sem
+			s.setHostStatus(r, a.ID, "skipped")
+			continue
+		}
+
+		wg.Add(1)
+		go func(a *agent.Agent) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if !s.runHost(ctx, r, rb, a) {
+				failedMu.Lock()
+				failed = true
+				failedMu.Unlock()
+			}
+		}(a)
+	}
+	wg.Wait()
+
+	status := "succeeded"
+	switch {
+	case ctx.Err() != nil:
+		status = "cancelled"
+	case failed:
+		status = "failed"
+	}
+
+	s.mu.Lock()
+	r.exec.Status = status
+	r.exec.EndedAt = time.Now()
+	s.persist(r.exec)
+	delete(s.runs, r.exec.ID)
+	s.mu.Unlock()
+}
+
+// stepData is what templates see of an earlier step as .Steps.<name>.
+type stepData struct {
+	Status     string
+	Output     string
+	StatusCode int
+	Error      string
+}
+
+type templateData struct {
+	Vars  map[string]string
+	Agent *agent.Agent
+	Steps map[string]stepData
+}
+
+// runHost runs the runbook's steps in order on one agent. A failed step
+// runs its on_failure handlers and, unless continue_on_failure is set,
+// stops the remaining steps.
+func (s *service) runHost(ctx context.Context, r *run, rb *Runbook, a *agent.Agent) bool {
+	s.setHostStatus(r, a.ID, "running")
+
+	data := &templateData{Vars: r.exec.Vars, Agent: a, Steps: make(map[string]stepData)}
+
+	ok := s.runSteps(ctx, r, a.ID, rb.Steps, data, false)
+
+	status := "succeeded"
+	switch {
+	case ctx.Err() != nil:
+		status = "cancelled"
+	case !ok:
+		status = "failed"
+	}
+	s.setHostStatus(r, a.ID, status)
+	return ok
+}
+
+func (s *service) runSteps(ctx context.Context, r *run, agentID string, steps []Step, data *templateData, handler bool) bool {
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		result := StepResult{Name: step.Name, Type: step.Type, Handler: handler, Status: "running", StartedAt: time.Now()}
+
+		if step.When != "" {
+			ok, err := evalCondition(step.When, data)
+			if err != nil {
+				result.Status = "failed"
+				result.Error = err.Error()
+			} else if !ok {
+				result.Status = "skipped"
+			}
+		}
+
+		if result.Status == "running" {
+			idx := s.appendStep(r, agentID, result)
+			result = s.executeStep(ctx, r, agentID, idx, step, data, result)
+		} else {
+			result.EndedAt = time.Now()
+			s.appendStep(r, agentID, result)
+		}
+
+		data.Steps[step.Name] = stepData{
+			Status:     result.Status,
+			Output:     result.Output,
+			StatusCode: result.StatusCode,
+			Error:      result.Error,
+		}
+
+		if result.Status != "failed" {
+			continue
+		}
+
+		// Handlers still run after a timeout, but not after cancellation
+		if len(step.OnFailure) > 0 && ctx.Err() == nil {
+			s.runSteps(ctx, r, agentID, step.OnFailure, data, true)
+		}
+		if !step.ContinueOnFailure {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *service) executeStep(ctx context.Context, r *run, agentID string, idx int, step Step, data *templateData, result StepResult) StepResult {
+	timeout := step.Timeout
+	if timeout <= 0 {
+		switch step.Type {
+		case "approval":
+			timeout = defaultApprovalTimeout
+		case "wait":
+			// A wait step must be allowed to last its whole duration
+			timeout = step.Duration + time.Minute
+		default:
+			timeout = defaultStepTimeout
+		}
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	switch step.Type {
+	case "command":
+		err = s.runCommand(stepCtx, agentID, step, data, &result, timeout)
+	case "file":
+		err = s.pushFile(stepCtx, agentID, step, data, &result)
+	case "wait":
+		select {
+		case <-stepCtx.Done():
+			err = stepCtx.Err()
+		case <-time.After(step.Duration):
+		}
+	case "http":
+		err = s.httpCheck(stepCtx, step, data, &result)
+	case "approval":
+		err = s.awaitApproval(stepCtx, r, agentID, idx, step, data, &result)
+	}
+
+	result.Status = "succeeded"
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		if stepCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			result.Error = fmt.Sprintf("timed out after %s: %v", timeout, err)
+		}
+	}
+	result.EndedAt = time.Now()
+	s.updateStep(r, agentID, idx, result)
+	return result
+}
+
+func (s *service) runCommand(ctx context.Context, agentID string, step Step, data *templateData, result *StepResult, timeout time.Duration) error {
+	command, err := render(step.Command, data)
+	if err != nil {
+		return err
+	}
+	args := make([]string, len(step.Args))
+	for i, arg := range step.Args {
+		if args[i], err = render(arg, data); err != nil {
+			return err
+		}
+	}
+
+	job, err := s.agentManager.RunCommandAndWait(ctx, agentID, agent.AgentCommand{
+		Command: command,
+		Args:    args,
+		Timeout: timeout,
+	})
+	if job != nil {
+		result.Output = truncate(job.Result)
+	}
+	return err
+}
+
+func (s *service) pushFile(ctx context.Context, agentID string, step Step, data *templateData, result *StepResult) error {
+	path, err := render(step.File.Path, data)
+	if err != nil {
+		return err
+	}
+	content, err := render(step.File.Content, data)
+	if err != nil {
+		return err
+	}
+
+	t, err := s.transferService.Upload(ctx, agentID, transfer.UploadRequest{
+		Path:  path,
+		Mode:  step.File.Mode,
+		Owner: step.File.Owner,
+		Group: step.File.Group,
+	}, []byte(content))
+	if err != nil {
+		return err
+	}
+	result.Output = "transfer " + t.ID
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		t, err = s.transferService.GetTransfer(t.ID)
+		if err != nil {
+			return err
+		}
+		switch t.Status {
+		case "completed":
+			return nil
+		case "failed":
+			return fmt.Errorf("transfer %s failed: %s", t.ID, t.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *service) httpCheck(ctx context.Context, step Step, data *templateData, result *StepResult) error {
+	url, err := render(step.HTTP.URL, data)
+	if err != nil {
+		return err
+	}
+	body, err := render(step.HTTP.Body, data)
+	if err != nil {
+		return err
+	}
+	method := step.HTTP.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	for k, v := range step.HTTP.Headers {
+		value, err := render(v, data)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(k, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutputBytes))
+	result.StatusCode = resp.StatusCode
+	result.Output = string(respBody)
+
+	if step.HTTP.ExpectStatus != 0 {
+		if resp.StatusCode != step.HTTP.ExpectStatus {
+			return fmt.Errorf("expected status %d, got %d", step.HTTP.ExpectStatus, resp.StatusCode)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if step.HTTP.ExpectBody != "" && !bytes.Contains(respBody, []byte(step.HTTP.ExpectBody)) {
+		return fmt.Errorf("response does not contain %q", step.HTTP.ExpectBody)
+	}
+	return nil
+}
+
+func (s *service) awaitApproval(ctx context.Context, r *run, agentID string, idx int, step Step, data *templateData, result *StepResult) error {
+	message, err := render(step.Message, data)
+	if err != nil {
+		return err
+	}
+
+	key := agentID + "|" + step.Name
+	ch := make(chan approval, 1)
+
+	s.mu.Lock()
+	r.approvals[key] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(r.approvals, key)
+		s.mu.Unlock()
+	}()
+
+	result.Status = "awaiting-approval"
+	result.Output = message
+	s.updateStep(r, agentID, idx, *result)
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("no approval received: %w", ctx.Err())
+	case a := <-ch:
+		result.ApprovedBy = a.approver
+		if !a.approved {
+			return fmt.Errorf("rejected by %s", a.approver)
+		}
+		return nil
+	}
+}
+
+func (s *service) setHostStatus(r *run, agentID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.exec.Hosts[agentID].Status = status
+	s.persist(r.exec)
+}
+
+func (s *service) appendStep(r *run, agentID string, result StepResult) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	host := r.exec.Hosts[agentID]
+	host.Steps = append(host.Steps, result)
+	s.persist(r.exec)
+	return len(host.Steps) - 1
+}
+
+func (s *service) updateStep(r *run, agentID string, idx int, result StepResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.exec.Hosts[agentID].Steps[idx] = result
+	s.persist(r.exec)
+}
+
+// persist writes the execution through to the repository. Callers hold
+// s.mu so writes land in order.
+func (s *service) persist(exec *Execution) {
+	if err := s.repo.SaveExecution(context.Background(), exec); err != nil {
+		fmt.Printf("Failed to persist runbook execution %s: %v\n", exec.ID, err)
+	}
+}
+
+func render(text string, data *templateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
+
+// evalCondition renders a when expression, e.g.
+// `{{ eq .Steps.check.StatusCode 200 }}`, and parses the result as a bool.
+// An empty result counts as false.
+func evalCondition(expr string, data *templateData) (bool, error) {
+	if !strings.Contains(expr, "{{") {
+		expr = "{{ " + expr + " }}"
+	}
+	out, err := render(expr, data)
+	if err != nil {
+		return false, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(out)
+	if err != nil {
+		return false, fmt.Errorf("condition %q evaluated to %q, not a boolean", expr, out)
+	}
+	return b, nil
+}
+
+func truncate(s string) string {
+	if len(s) > maxOutputBytes {
+		return s[:maxOutputBytes]
+	}
+	return s
+}
@@ -0,0 +1,215 @@
+// backend/internal/scheduler/scheduler.go
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+	"github.com/autosysadmin/backend/internal/maintenance"
+)
+
+const (
+	minInterval  = time.Minute
+	tickInterval = 5 * time.Second
+)
+
+type Service interface {
+	CreateJob(job RecurringJob) (*RecurringJob, error)
+	GetJob(id string) (*RecurringJob, error)
+	ListJobs() []RecurringJob
+	SetEnabled(id string, enabled bool) (*RecurringJob, error)
+	DeleteJob(id string) error
+	Run(ctx context.Context)
+}
+
+// RecurringJob runs Command on every matching agent each Interval. Agents
+// in a maintenance window are skipped for that run.
+type RecurringJob struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Selector  agent.Selector `json:"selector"`
+	Command   string         `json:"command"`
+	Args      []string       `json:"args"`
+	Timeout   time.Duration  `json:"timeout"`
+	Interval  time.Duration  `json:"interval"`
+	Enabled   bool           `json:"enabled"`
+	NextRunAt time.Time      `json:"next_run_at"`
+	LastRun   *Run           `json:"last_run,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type Run struct {
+	StartedAt time.Time         `json:"started_at"`
+	Jobs      map[string]string `json:"jobs"`    // agentID -> queued job ID
+	Skipped   []string          `json:"skipped"` // agents in maintenance
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+type service struct {
+	agentManager *agent.Manager
+	maintenance  maintenance.Service
+	jobs         map[string]RecurringJob // jobID -> job
+	mu           sync.RWMutex
+}
+
+func NewService(agentManager *agent.Manager, maintenanceService maintenance.Service) Service {
+	return &service{
+		agentManager: agentManager,
+		maintenance:  maintenanceService,
+		jobs:         make(map[string]RecurringJob),
+	}
+}
+
+func (s *service) CreateJob(job RecurringJob) (*RecurringJob, error) {
+	if job.Name == "" || job.Command == "" {
+		return nil, fmt.Errorf("name and command are required")
+	}
+	if job.Interval < minInterval {
+		return nil, fmt.Errorf("interval must be at least %s", minInterval)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = 10 * time.Minute
+	}
+
+	job.ID = fmt.Sprintf("recurring-%d", time.Now().UnixNano())
+	job.CreatedAt = time.Now()
+	job.NextRunAt = job.CreatedAt.Add(job.Interval)
+	job.LastRun = nil
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	return &job, nil
+}
+
+func (s *service) GetJob(id string) (*RecurringJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("recurring job not found")
+	}
+	return &job, nil
+}
+
+func (s *service) ListJobs() []RecurringJob {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]RecurringJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+func (s *service) SetEnabled(id string, enabled bool) (*RecurringJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("recurring job not found")
+	}
+	if enabled && !job.Enabled {
+		job.NextRunAt = time.Now().Add(job.Interval)
+	}
+	job.Enabled = enabled
+	s.jobs[id] = job
+	return &job, nil
+}
+
+func (s *service) DeleteJob(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[id]; !exists {
+		return fmt.Errorf("recurring job not found")
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+// Run fires due jobs until ctx is cancelled.
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, job := range s.due(now) {
+				s.fire(ctx, job)
+			}
+		}
+	}
+}
+
+// due returns the enabled jobs whose time has come and advances their next
+// run, skipping any runs missed while the backend was busy or down.
+func (s *service) due(now time.Time) []RecurringJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []RecurringJob
+	for id, job := range s.jobs {
+		if !job.Enabled || now.Before(job.NextRunAt) {
+			continue
+		}
+		due = append(due, job)
+		for !job.NextRunAt.After(now) {
+			job.NextRunAt = job.NextRunAt.Add(job.Interval)
+		}
+		s.jobs[id] = job
+	}
+	return due
+}
+
+func (s *service) fire(ctx context.Context, job RecurringJob) {
+	run := &Run{
+		StartedAt: time.Now(),
+		Jobs:      make(map[string]string),
+		Skipped:   []string{},
+		Errors:    make(map[string]string),
+	}
+
+	for _, a := range s.agentManager.SelectAgents(job.Selector) {
+		if s.maintenance != nil && s.maintenance.InMaintenance(a.ID) {
+			run.Skipped = append(run.Skipped, a.ID)
+			continue
+		}
+
+		out, err := s.agentManager.RunCommandOnAgent(ctx, a.ID, agent.AgentCommand{
+			Command: job.Command,
+			Args:    job.Args,
+			Timeout: job.Timeout,
+		})
+		if err != nil {
+			run.Errors[a.ID] = err.Error()
+			continue
+		}
+		var queued struct {
+			JobID string `json:"job_id"`
+		}
+		json.Unmarshal(out, &queued)
+		run.Jobs[a.ID] = queued.JobID
+	}
+	sort.Strings(run.Skipped)
+
+	s.mu.Lock()
+	if current, exists := s.jobs[job.ID]; exists {
+		current.LastRun = run
+		s.jobs[job.ID] = current
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,266 @@
+// backend/internal/scripts/scripts.go
+package scripts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type Service interface {
+	CreateScript(def ScriptDefinition) (*Script, error)
+	AddVersion(scriptID string, def ScriptDefinition) (*Script, error)
+	GetScript(scriptID string, version int) (*Script, error)
+	ListScripts() []Script
+	ListVersions(scriptID string) ([]Script, error)
+	DeleteScript(scriptID string) error
+	Resolve(scriptID string, version int, params map[string]string) (*ResolvedScript, error)
+	Run(ctx context.Context, req RunRequest) ([]RunResult, error)
+}
+
+// ScriptDefinition is what a user submits; each submission for an existing
+// script becomes a new immutable version.
+type ScriptDefinition struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Interpreter string        `json:"interpreter"` // bash, sh, python3
+	Content     string        `json:"content"`
+	Params      []Param       `json:"params"`
+	Timeout     time.Duration `json:"timeout"`
+}
+
+type Script struct {
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+	ScriptDefinition
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Param struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"` // string, int, float, bool, enum
+	Required bool     `json:"required"`
+	Default  string   `json:"default,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"` // string only
+	Choices  []string `json:"choices,omitempty"` // enum only
+	Min      *float64 `json:"min,omitempty"`     // int and float only
+	Max      *float64 `json:"max,omitempty"`
+}
+
+// ResolvedScript is a script version with its parameters bound: Content
+// starts with a generated header assigning every parameter as a variable,
+// followed by the script body.
+type ResolvedScript struct {
+	ScriptID    string            `json:"script_id"`
+	Version     int               `json:"version"`
+	Interpreter string            `json:"interpreter"`
+	Params      map[string]string `json:"params"`
+	Content     string            `json:"content"`
+}
+
+func (r *ResolvedScript) Ref() string {
+	return fmt.Sprintf("%s@%d", r.ScriptID, r.Version)
+}
+
+type RunRequest struct {
+	ScriptID string            `json:"script_id"`
+	Version  int               `json:"version"` // 0 means latest
+	Selector agent.Selector    `json:"selector"`
+	Params   map[string]string `json:"params"`
+}
+
+type RunResult struct {
+	AgentID string `json:"agent_id"`
+	JobID   string `json:"job_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func validateDefinition(def ScriptDefinition) error {
+	if def.Name == "" {
+		return fmt.Errorf("script name is required")
+	}
+	if !agent.AllowedInterpreters[def.Interpreter] {
+		return fmt.Errorf("interpreter must be one of bash, sh, python3")
+	}
+	if strings.TrimSpace(def.Content) == "" {
+		return fmt.Errorf("script content is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range def.Params {
+		if !paramNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("invalid parameter name %q", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate parameter %q", p.Name)
+		}
+		seen[p.Name] = true
+
+		switch p.Type {
+		case "string":
+			if p.Pattern != "" {
+				if _, err := regexp.Compile(anchored(p.Pattern)); err != nil {
+					return fmt.Errorf("parameter %q: invalid pattern: %w", p.Name, err)
+				}
+			}
+		case "enum":
+			if len(p.Choices) == 0 {
+				return fmt.Errorf("parameter %q: enum needs choices", p.Name)
+			}
+		case "int", "float", "bool":
+		default:
+			return fmt.Errorf("parameter %q: unknown type %q", p.Name, p.Type)
+		}
+
+		if p.Default != "" {
+			if err := p.validate(p.Default); err != nil {
+				return fmt.Errorf("parameter %q: invalid default: %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p Param) validate(value string) error {
+	switch p.Type {
+	case "string":
+		if p.Pattern != "" && !regexp.MustCompile(anchored(p.Pattern)).MatchString(value) {
+			return fmt.Errorf("does not match %s", p.Pattern)
+		}
+	case "enum":
+		for _, choice := range p.Choices {
+			if value == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(p.Choices, ", "))
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("not a boolean")
+		}
+	case "int", "float":
+		var n float64
+		if p.Type == "int" {
+			i, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("not an integer")
+			}
+			n = float64(i)
+		} else {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("not a number")
+			}
+			n = f
+		}
+		if p.Min != nil && n < *p.Min {
+			return fmt.Errorf("must be at least %v", *p.Min)
+		}
+		if p.Max != nil && n > *p.Max {
+			return fmt.Errorf("must be at most %v", *p.Max)
+		}
+	}
+	return nil
+}
+
+// anchored makes a pattern match the whole value rather than any part of it.
+func anchored(pattern string) string {
+	return `^(?:` + pattern + `)$`
+}
+
+// bindParams validates the supplied values against the declared
+// parameters, fills in defaults and rejects unknown names.
+func bindParams(declared []Param, values map[string]string) (map[string]string, error) {
+	known := make(map[string]bool)
+	bound := make(map[string]string)
+	for _, p := range declared {
+		known[p.Name] = true
+		value, ok := values[p.Name]
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("parameter %q is required", p.Name)
+			}
+			value = p.Default
+		}
+		if value != "" || ok {
+			if err := p.validate(value); err != nil {
+				return nil, fmt.Errorf("parameter %q: %w", p.Name, err)
+			}
+		}
+		bound[p.Name] = value
+	}
+	for name := range values {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return bound, nil
+}
+
+// header renders parameter assignments in the interpreter's own syntax.
+// Values are always emitted as quoted literals so they can't inject code.
+func header(s *Script, bound map[string]string) string {
+	var b strings.Builder
+	if s.Interpreter == "python3" {
+		b.WriteString("# parameters\n")
+		for _, p := range s.Params {
+			b.WriteString(p.Name + " = " + pythonLiteral(p.Type, bound[p.Name]) + "\n")
+		}
+	} else {
+		b.WriteString("# parameters\n")
+		for _, p := range s.Params {
+			b.WriteString(p.Name + "=" + shellQuote(bound[p.Name]) + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func pythonLiteral(typ, value string) string {
+	if value == "" && typ != "string" && typ != "enum" {
+		return "None"
+	}
+	// Re-format numbers rather than pass them through: Go accepts "007",
+	// "NaN" and "0x1p-2", none of which Python parses as a literal
+	switch typ {
+	case "int":
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return strconv.FormatInt(n, 10)
+	case "float":
+		f, _ := strconv.ParseFloat(value, 64)
+		switch {
+		case math.IsNaN(f):
+			return "float('nan')"
+		case math.IsInf(f, 1):
+			return "float('inf')"
+		case math.IsInf(f, -1):
+			return "float('-inf')"
+		}
+		literal := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(literal, ".e") {
+			literal += ".0"
+		}
+		return literal
+	case "bool":
+		if b, _ := strconv.ParseBool(value); b {
+			return "True"
+		}
+		return "False"
+	}
+	// A JSON string is also a valid Python string literal
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
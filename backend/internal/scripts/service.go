@@ -0,0 +1,187 @@
+// backend/internal/scripts/service.go
+package scripts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type service struct {
+	agentManager *agent.Manager
+	scripts      map[string][]Script // scriptID -> versions, oldest first
+	mu           sync.RWMutex
+}
+
+func NewService(agentManager *agent.Manager) Service {
+	return &service{
+		agentManager: agentManager,
+		scripts:      make(map[string][]Script),
+	}
+}
+
+func (s *service) CreateScript(def ScriptDefinition) (*Script, error) {
+	if err := validateDefinition(def); err != nil {
+		return nil, err
+	}
+
+	script := Script{
+		ID:               fmt.Sprintf("script-%d", time.Now().UnixNano()),
+		Version:          1,
+		ScriptDefinition: def,
+		CreatedAt:        time.Now(),
+	}
+
+	s.mu.Lock()
+	s.scripts[script.ID] = []Script{script}
+	s.mu.Unlock()
+
+	return &script, nil
+}
+
+func (s *service) AddVersion(scriptID string, def ScriptDefinition) (*Script, error) {
+	if err := validateDefinition(def); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, exists := s.scripts[scriptID]
+	if !exists {
+		return nil, fmt.Errorf("script not found")
+	}
+	script := Script{
+		ID:               scriptID,
+		Version:          versions[len(versions)-1].Version + 1,
+		ScriptDefinition: def,
+		CreatedAt:        time.Now(),
+	}
+	s.scripts[scriptID] = append(versions, script)
+	return &script, nil
+}
+
+// GetScript returns one version of a script; version 0 means the latest.
+func (s *service) GetScript(scriptID string, version int) (*Script, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions, exists := s.scripts[scriptID]
+	if !exists {
+		return nil, fmt.Errorf("script not found")
+	}
+	if version == 0 {
+		script := versions[len(versions)-1]
+		return &script, nil
+	}
+	for _, script := range versions {
+		if script.Version == version {
+			return &script, nil
+		}
+	}
+	return nil, fmt.Errorf("version %d of script %s not found", version, scriptID)
+}
+
+// ListScripts returns the latest version of every script.
+func (s *service) ListScripts() []Script {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scripts := make([]Script, 0, len(s.scripts))
+	for _, versions := range s.scripts {
+		scripts = append(scripts, versions[len(versions)-1])
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].Name < scripts[j].Name })
+	return scripts
+}
+
+func (s *service) ListVersions(scriptID string) ([]Script, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions, exists := s.scripts[scriptID]
+	if !exists {
+		return nil, fmt.Errorf("script not found")
+	}
+	return append([]Script(nil), versions...), nil
+}
+
+func (s *service) DeleteScript(scriptID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.scripts[scriptID]; !exists {
+		return fmt.Errorf("script not found")
+	}
+	delete(s.scripts, scriptID)
+	return nil
+}
+
+func (s *service) Resolve(scriptID string, version int, params map[string]string) (*ResolvedScript, error) {
+	script, err := s.GetScript(scriptID, version)
+	if err != nil {
+		return nil, err
+	}
+	bound, err := bindParams(script.Params, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResolvedScript{
+		ScriptID:    script.ID,
+		Version:     script.Version,
+		Interpreter: script.Interpreter,
+		Params:      bound,
+		Content:     header(script, bound) + script.Content,
+	}, nil
+}
+
+// Run resolves the script once and queues it on every selected agent. The
+// returned job IDs can be followed through the job queue; each job carries
+// the resolved content and script reference.
+func (s *service) Run(ctx context.Context, req RunRequest) ([]RunResult, error) {
+	if req.Selector.IsEmpty() {
+		return nil, fmt.Errorf("selector is required")
+	}
+	resolved, err := s.Resolve(req.ScriptID, req.Version, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	script, err := s.GetScript(resolved.ScriptID, resolved.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	agents := s.agentManager.SelectAgents(req.Selector)
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agents match the selector")
+	}
+
+	results := make([]RunResult, 0, len(agents))
+	for _, a := range agents {
+		result := RunResult{AgentID: a.ID}
+		out, err := s.agentManager.RunCommandOnAgent(ctx, a.ID, agent.AgentCommand{
+			Command:   agent.ScriptRunCommand,
+			Args:      []string{resolved.Interpreter},
+			Timeout:   script.Timeout,
+			Script:    resolved.Content,
+			ScriptRef: resolved.Ref(),
+		})
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			var queued struct {
+				JobID string `json:"job_id"`
+			}
+			json.Unmarshal(out, &queued)
+			result.JobID = queued.JobID
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
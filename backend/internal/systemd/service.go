@@ -0,0 +1,271 @@
+// backend/internal/systemd/service.go
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type Service interface {
+	ListUnits(ctx context.Context, agentID string) ([]Unit, error)
+	ControlUnit(ctx context.Context, agentID, unit, action string) (*ActionResult, error)
+	GetJournal(ctx context.Context, agentID, unit string, lines int) ([]JournalEntry, error)
+	ControlFleet(ctx context.Context, sel agent.Selector, unit, action string) []ActionResult
+}
+
+type Unit struct {
+	Name        string `json:"name"`
+	LoadState   string `json:"load_state"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	Description string `json:"description"`
+}
+
+type ActionResult struct {
+	AgentID       string `json:"agent_id"`
+	Unit          string `json:"unit"`
+	Action        string `json:"action"`
+	Success       bool   `json:"success"`
+	ActiveState   string `json:"active_state,omitempty"`
+	SubState      string `json:"sub_state,omitempty"`
+	UnitFileState string `json:"unit_file_state,omitempty"` // enabled, disabled, static, ...
+	Error         string `json:"error,omitempty"`
+}
+
+type JournalEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Priority  int       `json:"priority"`
+	PID       string    `json:"pid,omitempty"`
+	Message   string    `json:"message"`
+}
+
+const (
+	defaultJournalLines = 100
+	maxJournalLines     = 5000
+)
+
+var (
+	validActions = map[string]bool{
+		"start":   true,
+		"stop":    true,
+		"restart": true,
+		"reload":  true,
+		"enable":  true,
+		"disable": true,
+	}
+
+	// Leading character must not be "-" so a unit name can never be read as a flag
+	unitNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9:_.@-]*$`)
+)
+
+type service struct {
+	agentManager *agent.Manager
+	timeout      time.Duration
+}
+
+func NewService(agentManager *agent.Manager) Service {
+	return &service{
+		agentManager: agentManager,
+		timeout:      2 * time.Minute,
+	}
+}
+
+func (s *service) ListUnits(ctx context.Context, agentID string) ([]Unit, error) {
+	output, err := s.run(ctx, agentID, "systemctl",
+		"list-units", "--type=service", "--all", "--no-legend", "--no-pager", "--plain")
+	if err != nil {
+		return nil, err
+	}
+	return parseUnits(output), nil
+}
+
+func (s *service) ControlUnit(ctx context.Context, agentID, unit, action string) (*ActionResult, error) {
+	if err := validate(unit, action); err != nil {
+		return nil, err
+	}
+
+	result := &ActionResult{
+		AgentID: agentID,
+		Unit:    unit,
+		Action:  action,
+	}
+
+	if _, err := s.run(ctx, agentID, "systemctl", action, unit); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+	}
+
+	// Report the state the unit ended up in even when the action failed,
+	// since that is usually what the operator wants to know next
+	output, err := s.run(ctx, agentID, "systemctl",
+		"show", unit, "--property=ActiveState,SubState,UnitFileState", "--no-pager")
+	if err == nil {
+		props := parseProperties(output)
+		result.ActiveState = props["ActiveState"]
+		result.SubState = props["SubState"]
+		result.UnitFileState = props["UnitFileState"]
+	}
+
+	return result, nil
+}
+
+func (s *service) GetJournal(ctx context.Context, agentID, unit string, lines int) ([]JournalEntry, error) {
+	if !unitNamePattern.MatchString(unit) {
+		return nil, fmt.Errorf("invalid unit name %q", unit)
+	}
+	if lines <= 0 {
+		lines = defaultJournalLines
+	}
+	if lines > maxJournalLines {
+		lines = maxJournalLines
+	}
+
+	output, err := s.run(ctx, agentID, "journalctl",
+		"-u", unit, "-n", strconv.Itoa(lines), "-o", "json", "--no-pager")
+	if err != nil {
+		return nil, err
+	}
+	return parseJournal(output), nil
+}
+
+func (s *service) ControlFleet(ctx context.Context, sel agent.Selector, unit, action string) []ActionResult {
+	agents := s.agentManager.SelectAgents(sel)
+	results := make([]ActionResult, 0, len(agents))
+
+	if err := validate(unit, action); err != nil {
+		for _, a := range agents {
+			results = append(results, ActionResult{AgentID: a.ID, Unit: unit, Action: action, Error: err.Error()})
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for _, a := range agents {
+		wg.Add(1)
+		go func(agentID string) {
+			defer wg.Done()
+			result, err := s.ControlUnit(ctx, agentID, unit, action)
+			if err != nil {
+				result = &ActionResult{AgentID: agentID, Unit: unit, Action: action, Error: err.Error()}
+			}
+			mu.Lock()
+			results = append(results, *result)
+			mu.Unlock()
+		}(a.ID)
+	}
+
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].AgentID < results[j].AgentID })
+	return results
+}
+
+func (s *service) run(ctx context.Context, agentID, command string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	job, err := s.agentManager.RunCommandAndWait(ctx, agentID, agent.AgentCommand{
+		Command: command,
+		Args:    args,
+		Timeout: s.timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	return job.Result, nil
+}
+
+func validate(unit, action string) error {
+	if !validActions[action] {
+		return fmt.Errorf("unsupported action %q", action)
+	}
+	if !unitNamePattern.MatchString(unit) {
+		return fmt.Errorf("invalid unit name %q", unit)
+	}
+	return nil
+}
+
+// parseUnits parses `systemctl list-units --plain --no-legend` output, where
+// each line is: UNIT LOAD ACTIVE SUB DESCRIPTION...
+func parseUnits(output string) []Unit {
+	var units []Unit
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		units = append(units, Unit{
+			Name:        fields[0],
+			LoadState:   fields[1],
+			ActiveState: fields[2],
+			SubState:    fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+	return units
+}
+
+func parseProperties(output string) map[string]string {
+	props := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+	return props
+}
+
+// parseJournal parses `journalctl -o json`, one JSON object per line.
+func parseJournal(output string) []JournalEntry {
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil {
+			continue
+		}
+
+		entry := JournalEntry{Message: journalString(raw["MESSAGE"])}
+		if usec, err := strconv.ParseInt(journalString(raw["__REALTIME_TIMESTAMP"]), 10, 64); err == nil {
+			entry.Timestamp = time.UnixMicro(usec)
+		}
+		if prio, err := strconv.Atoi(journalString(raw["PRIORITY"])); err == nil {
+			entry.Priority = prio
+		}
+		entry.PID = journalString(raw["_PID"])
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// journalString handles journald fields, which are strings unless the value
+// is not valid UTF-8, in which case they are arrays of byte values.
+func journalString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []interface{}:
+		b := make([]byte, 0, len(val))
+		for _, n := range val {
+			if f, ok := n.(float64); ok {
+				b = append(b, byte(f))
+			}
+		}
+		return string(b)
+	}
+	return ""
+}
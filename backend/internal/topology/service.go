@@ -0,0 +1,310 @@
+// backend/internal/topology/service.go
+package topology
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+// Reports older than this no longer describe the host and are left out of
+// the graph.
+const reportTTL = 15 * time.Minute
+
+type storedReport struct {
+	report   *agent.ConnectionReport
+	received time.Time
+}
+
+type service struct {
+	agentManager *agent.Manager
+	reports      map[string]storedReport // agentID -> last report
+	mu           sync.RWMutex
+	timeout      time.Duration
+}
+
+func NewService(agentManager *agent.Manager) Service {
+	return &service{
+		agentManager: agentManager,
+		reports:      make(map[string]storedReport),
+		timeout:      time.Minute,
+	}
+}
+
+func (s *service) Report(agentID string, report *agent.ConnectionReport) error {
+	if _, exists := s.agentManager.GetAgent(agentID); !exists {
+		return fmt.Errorf("agent not found")
+	}
+
+	s.mu.Lock()
+	s.reports[agentID] = storedReport{report: report, received: time.Now()}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *service) Connections(ctx context.Context, agentID string, live bool) (*agent.ConnectionReport, error) {
+	if !live {
+		s.mu.RLock()
+		stored, exists := s.reports[agentID]
+		s.mu.RUnlock()
+		if !exists {
+			return nil, fmt.Errorf("no connection report for agent %s", agentID)
+		}
+		return stored.report, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	job, err := s.agentManager.RunCommandAndWait(ctx, agentID, agent.AgentCommand{
+		Command: agent.NetConnectionsCommand,
+		Timeout: s.timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var report agent.ConnectionReport
+	if err := json.Unmarshal([]byte(job.Result), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse connection report: %w", err)
+	}
+	if err := s.Report(agentID, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (s *service) Graph(sel agent.Selector, level string) (*Graph, error) {
+	if err := validLevel(level); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool)
+	for _, a := range s.agentManager.SelectAgents(sel) {
+		selected[a.ID] = true
+	}
+
+	full := s.build(level)
+	graph := &Graph{Level: level, Nodes: []Node{}, Edges: []Edge{}}
+	keep := make(map[string]bool)
+	for _, e := range full.edges {
+		if selected[full.nodes[e.Source].AgentID] || selected[full.nodes[e.Target].AgentID] {
+			graph.Edges = append(graph.Edges, e)
+			keep[e.Source] = true
+			keep[e.Target] = true
+		}
+	}
+	for id, n := range full.nodes {
+		// Selected agents without connections still show up
+		if keep[id] || (n.Type == NodeAgent && selected[n.AgentID] && full.reported[n.AgentID]) {
+			graph.Nodes = append(graph.Nodes, n)
+		}
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	return graph, nil
+}
+
+func (s *service) Impact(agentID string) (*Impact, error) {
+	if _, exists := s.agentManager.GetAgent(agentID); !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+
+	full := s.build(LevelAgent)
+	dependents := make(map[string]*Peer)
+	dependencies := make(map[string]*Peer)
+	for _, e := range full.edges {
+		switch agentID {
+		case full.nodes[e.Target].AgentID:
+			addPeer(dependents, full.nodes[e.Source], e)
+		case full.nodes[e.Source].AgentID:
+			addPeer(dependencies, full.nodes[e.Target], e)
+		}
+	}
+
+	return &Impact{
+		AgentID:      agentID,
+		Dependents:   sortedPeers(dependents),
+		Dependencies: sortedPeers(dependencies),
+	}, nil
+}
+
+func addPeer(peers map[string]*Peer, node Node, e Edge) {
+	p, exists := peers[node.ID]
+	if !exists {
+		p = &Peer{Node: node, Ports: []int{}}
+		peers[node.ID] = p
+	}
+	p.Connections += e.Count
+	for _, port := range p.Ports {
+		if port == e.Port {
+			return
+		}
+	}
+	p.Ports = append(p.Ports, e.Port)
+	sort.Ints(p.Ports)
+}
+
+func sortedPeers(peers map[string]*Peer) []Peer {
+	out := make([]Peer, 0, len(peers))
+	for _, p := range peers {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Connections != out[j].Connections {
+			return out[i].Connections > out[j].Connections
+		}
+		return out[i].Node.ID < out[j].Node.ID
+	})
+	return out
+}
+
+type builtGraph struct {
+	nodes    map[string]Node
+	edges    []Edge
+	reported map[string]bool // agents with a current report
+}
+
+type edgeKey struct {
+	source, target string
+	port           int
+}
+
+// build correlates every current report. Each connection is counted once:
+// from the client side when the client is a reporting agent, otherwise
+// from the server side with the client as an external (or silent agent)
+// node.
+func (s *service) build(level string) *builtGraph {
+	agents := make(map[string]*agent.Agent)
+	for _, a := range s.agentManager.ListAgents() {
+		agents[a.ID] = a
+	}
+
+	cutoff := time.Now().Add(-reportTTL)
+	reports := make(map[string]*agent.ConnectionReport)
+	s.mu.RLock()
+	for id, stored := range s.reports {
+		if _, exists := agents[id]; exists && stored.received.After(cutoff) {
+			reports[id] = stored.report
+		}
+	}
+	s.mu.RUnlock()
+
+	// Which agent owns each address: the registered address plus every
+	// concrete address the agent's sockets are bound to
+	owners := make(map[string]string)
+	for id, a := range agents {
+		if a.IPAddress != "" {
+			owners[normalizeIP(a.IPAddress)] = id
+		}
+	}
+	listeners := make(map[string]map[int]string) // agentID -> port -> process
+	for id, r := range reports {
+		listeners[id] = make(map[int]string)
+		for _, l := range r.Listening {
+			listeners[id][l.LocalPort] = l.Process
+		}
+		for _, sock := range append(r.Listening, r.Established...) {
+			if ip := net.ParseIP(sock.LocalAddr); ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+				owners[ip.String()] = id
+			}
+		}
+	}
+	ownerOf := func(addr, self string) string {
+		ip := net.ParseIP(addr)
+		if ip != nil && ip.IsLoopback() {
+			return self
+		}
+		return owners[normalizeIP(addr)]
+	}
+
+	g := &builtGraph{nodes: make(map[string]Node), reported: make(map[string]bool)}
+	node := func(agentID, process string) string {
+		a := agents[agentID]
+		n := Node{ID: agentID, Type: NodeAgent, AgentID: agentID, Hostname: a.Hostname}
+		_, hasReport := reports[agentID]
+		if level == LevelProcess && hasReport {
+			if process == "" {
+				process = "unknown"
+			}
+			n.ID = agentID + "/" + process
+			n.Type = NodeProcess
+			n.Process = process
+		}
+		g.nodes[n.ID] = n
+		return n.ID
+	}
+	external := func(addr string) string {
+		n := Node{ID: "external:" + addr, Type: NodeExternal, Address: addr}
+		g.nodes[n.ID] = n
+		return n.ID
+	}
+
+	counts := make(map[edgeKey]int)
+	for id, r := range reports {
+		g.reported[id] = true
+		if level == LevelAgent {
+			node(id, "")
+		}
+		for _, sock := range r.Established {
+			peer := ownerOf(sock.RemoteAddr, id)
+			_, peerReports := reports[peer]
+
+			var src, tgt string
+			var port int
+			if process, inbound := listeners[id][sock.LocalPort]; inbound {
+				if peerReports {
+					continue // counted on the client's side
+				}
+				if peer != "" {
+					src = node(peer, "")
+				} else {
+					src = external(sock.RemoteAddr)
+				}
+				tgt = node(id, process)
+				port = sock.LocalPort
+			} else {
+				src = node(id, sock.Process)
+				switch {
+				case peerReports:
+					tgt = node(peer, listeners[peer][sock.RemotePort])
+				case peer != "":
+					tgt = node(peer, "")
+				default:
+					tgt = external(sock.RemoteAddr)
+				}
+				port = sock.RemotePort
+			}
+			if src == tgt {
+				continue
+			}
+			counts[edgeKey{src, tgt, port}]++
+		}
+	}
+
+	for k, n := range counts {
+		g.edges = append(g.edges, Edge{Source: k.source, Target: k.target, Port: k.port, Count: n})
+	}
+	sort.Slice(g.edges, func(i, j int) bool {
+		a, b := g.edges[i], g.edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Port < b.Port
+	})
+	return g
+}
+
+func normalizeIP(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String()
+	}
+	return addr
+}
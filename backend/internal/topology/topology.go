@@ -0,0 +1,82 @@
+// backend/internal/topology/topology.go
+package topology
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type Service interface {
+	// Report records an agent's connection table.
+	Report(agentID string, report *agent.ConnectionReport) error
+	// Connections returns the agent's last report, or asks the agent for a
+	// fresh one when live is set.
+	Connections(ctx context.Context, agentID string, live bool) (*agent.ConnectionReport, error)
+	// Graph correlates the reports of all agents into a dependency graph,
+	// keeping the edges that touch an agent matched by sel.
+	Graph(sel agent.Selector, level string) (*Graph, error)
+	// Impact lists who depends on the agent and what it depends on.
+	Impact(agentID string) (*Impact, error)
+}
+
+// Graph levels for Service.Graph.
+const (
+	LevelProcess = "process" // one node per listening or connecting process
+	LevelAgent   = "agent"   // one node per agent
+)
+
+// Node types.
+const (
+	NodeAgent    = "agent"
+	NodeProcess  = "process"
+	NodeExternal = "external" // an address no agent claims
+)
+
+type Node struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	AgentID  string `json:"agent_id,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Process  string `json:"process,omitempty"`
+	Address  string `json:"address,omitempty"` // external nodes
+}
+
+// Edge points from the client to the server, with the number of
+// established connections seen to Port.
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Port   int    `json:"port"`
+	Count  int    `json:"count"`
+}
+
+type Graph struct {
+	Level string `json:"level"`
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+type Impact struct {
+	AgentID string `json:"agent_id"`
+	// Dependents connect to this agent and are affected when it goes down
+	Dependents []Peer `json:"dependents"`
+	// Dependencies are what this agent connects to
+	Dependencies []Peer `json:"dependencies"`
+}
+
+// Peer is a node on the other side of an agent's connections, aggregated
+// over ports.
+type Peer struct {
+	Node        Node  `json:"node"`
+	Ports       []int `json:"ports"`
+	Connections int   `json:"connections"`
+}
+
+func validLevel(level string) error {
+	if level != LevelProcess && level != LevelAgent {
+		return fmt.Errorf("level must be %s or %s", LevelProcess, LevelAgent)
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+// backend/internal/transfer/service.go
+package transfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/autosysadmin/backend/internal/agent"
+)
+
+type transferService struct {
+	agentManager *agent.Manager
+	config       Config
+	transfers    map[string]*Transfer // transferID -> transfer
+	content      map[string][]byte    // transferID -> upload source or download buffer
+	running      map[string]bool      // transferID -> worker active
+	mu           sync.RWMutex
+}
+
+func NewService(agentManager *agent.Manager, config Config) Service {
+	if config.Retention <= 0 {
+		config.Retention = DefaultConfig().Retention
+	}
+	return &transferService{
+		agentManager: agentManager,
+		config:       config,
+		transfers:    make(map[string]*Transfer),
+		content:      make(map[string][]byte),
+		running:      make(map[string]bool),
+	}
+}
+
+func (s *transferService) Upload(ctx context.Context, agentID string, req UploadRequest, content []byte) (*Transfer, error) {
+	if _, exists := s.agentManager.GetAgent(agentID); !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+	if !filepath.IsAbs(req.Path) {
+		return nil, fmt.Errorf("path must be absolute")
+	}
+	if int64(len(content)) > s.config.MaxFileSize {
+		return nil, fmt.Errorf("file exceeds maximum size of %d bytes", s.config.MaxFileSize)
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = "0644"
+	}
+	if _, err := strconv.ParseUint(mode, 8, 32); err != nil {
+		return nil, fmt.Errorf("invalid mode %q", req.Mode)
+	}
+
+	sum := sha256.Sum256(content)
+	transfer := &Transfer{
+		ID:        fmt.Sprintf("xfer-%s-%d", agentID, time.Now().UnixNano()),
+		AgentID:   agentID,
+		Direction: "upload",
+		Path:      filepath.Clean(req.Path),
+		Mode:      mode,
+		Owner:     req.Owner,
+		Group:     req.Group,
+		Size:      int64(len(content)),
+		SHA256:    hex.EncodeToString(sum[:]),
+		Status:    "pending",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	s.transfers[transfer.ID] = transfer
+	s.content[transfer.ID] = content
+	s.mu.Unlock()
+
+	s.start(transfer.ID)
+	return s.GetTransfer(transfer.ID)
+}
+
+func (s *transferService) Download(ctx context.Context, agentID, path string) (*Transfer, error) {
+	if _, exists := s.agentManager.GetAgent(agentID); !exists {
+		return nil, fmt.Errorf("agent not found")
+	}
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("path must be absolute")
+	}
+
+	transfer := &Transfer{
+		ID:        fmt.Sprintf("xfer-%s-%d", agentID, time.Now().UnixNano()),
+		AgentID:   agentID,
+		Direction: "download",
+		Path:      filepath.Clean(path),
+		Status:    "pending",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	s.transfers[transfer.ID] = transfer
+	s.mu.Unlock()
+
+	s.start(transfer.ID)
+	return s.GetTransfer(transfer.ID)
+}
+
+func (s *transferService) Resume(ctx context.Context, transferID string) (*Transfer, error) {
+	s.mu.RLock()
+	transfer, exists := s.transfers[transferID]
+	var status string
+	if exists {
+		status = transfer.Status
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("transfer not found")
+	}
+	if status != "failed" {
+		return nil, fmt.Errorf("only failed transfers can be resumed (status is %s)", status)
+	}
+
+	s.start(transferID)
+	return s.GetTransfer(transferID)
+}
+
+func (s *transferService) GetTransfer(transferID string) (*Transfer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transfer, exists := s.transfers[transferID]
+	if !exists {
+		return nil, fmt.Errorf("transfer not found")
+	}
+
+	t := *transfer
+	return &t, nil
+}
+
+func (s *transferService) GetContent(transferID string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transfer, exists := s.transfers[transferID]
+	if !exists {
+		return nil, fmt.Errorf("transfer not found")
+	}
+	if transfer.Direction != "download" || transfer.Status != "completed" {
+		return nil, fmt.Errorf("transfer %s has no downloadable content", transferID)
+	}
+
+	return s.content[transferID], nil
+}
+
+func (s *transferService) ListTransfers(agentID string) []Transfer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var transfers []Transfer
+	for _, transfer := range s.transfers {
+		if transfer.AgentID == agentID {
+			transfers = append(transfers, *transfer)
+		}
+	}
+	return transfers
+}
+
+// MaxFileSize is the configured upload and download size limit.
+func (s *transferService) MaxFileSize() int64 {
+	return s.config.MaxFileSize
+}
+
+// start launches the worker for a transfer unless one is already running.
+func (s *transferService) start(transferID string) {
+	s.mu.Lock()
+	if s.running[transferID] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[transferID] = true
+	transfer := s.transfers[transferID]
+	transfer.Status = "in-progress"
+	transfer.Error = ""
+	transfer.UpdatedAt = time.Now()
+	direction := transfer.Direction
+	s.mu.Unlock()
+
+	go func() {
+		var err error
+		if direction == "upload" {
+			err = s.runUpload(transferID)
+		} else {
+			err = s.runDownload(transferID)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.running, transferID)
+		if err != nil {
+			transfer.Status = "failed"
+			transfer.Error = err.Error()
+		} else {
+			transfer.Status = "completed"
+			if direction == "upload" {
+				// The agent has the file now; don't hold the source in memory
+				delete(s.content, transferID)
+			}
+		}
+		transfer.UpdatedAt = time.Now()
+
+		finishedAt := transfer.UpdatedAt
+		time.AfterFunc(s.config.Retention, func() { s.expire(transferID, finishedAt) })
+	}()
+}
+
+// expire forgets a finished transfer and its content once the retention
+// period has passed, unless it was resumed since.
+func (s *transferService) expire(transferID string, finishedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transfer, exists := s.transfers[transferID]
+	if !exists || s.running[transferID] || !transfer.UpdatedAt.Equal(finishedAt) {
+		return
+	}
+	delete(s.transfers, transferID)
+	delete(s.content, transferID)
+}
+
+func (s *transferService) runUpload(transferID string) error {
+	s.mu.RLock()
+	t := *s.transfers[transferID]
+	content := s.content[transferID]
+	s.mu.RUnlock()
+
+	partPath := partPathFor(t.Path, t.ID)
+
+	// Ask the agent how much of the partial file it already has so that a
+	// resumed upload continues where the last successful chunk ended
+	offset := int64(0)
+	if t.Transferred > 0 {
+		info, err := s.stat(t.AgentID, partPath)
+		if err != nil {
+			return err
+		}
+		if info.Exists && info.Size <= t.Size {
+			offset = info.Size
+		}
+	}
+	s.setTransferred(transferID, offset)
+
+	for offset < t.Size {
+		end := offset + s.config.ChunkSize
+		if end > t.Size {
+			end = t.Size
+		}
+
+		_, err := s.run(t.AgentID, agent.AgentCommand{
+			Command: agent.FileWriteChunkCommand,
+			Args: []string{
+				partPath,
+				strconv.FormatInt(offset, 10),
+				base64.StdEncoding.EncodeToString(content[offset:end]),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write chunk at offset %d: %w", offset, err)
+		}
+
+		offset = end
+		s.setTransferred(transferID, offset)
+	}
+
+	// Empty files never produce a chunk; create the partial file explicitly
+	if t.Size == 0 {
+		if _, err := s.run(t.AgentID, agent.AgentCommand{
+			Command: agent.FileWriteChunkCommand,
+			Args:    []string{partPath, "0", ""},
+		}); err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+	}
+
+	_, err := s.run(t.AgentID, agent.AgentCommand{
+		Command: agent.FileCommitCommand,
+		Args:    []string{partPath, t.Path, t.SHA256, t.Mode, t.Owner, t.Group},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit file: %w", err)
+	}
+	return nil
+}
+
+func (s *transferService) runDownload(transferID string) error {
+	s.mu.RLock()
+	t := *s.transfers[transferID]
+	buf := s.content[transferID]
+	s.mu.RUnlock()
+
+	info, err := s.stat(t.AgentID, t.Path)
+	if err != nil {
+		return err
+	}
+	if !info.Exists {
+		return fmt.Errorf("file %s not found on agent", t.Path)
+	}
+	if info.Size > s.config.MaxFileSize {
+		return fmt.Errorf("file exceeds maximum size of %d bytes", s.config.MaxFileSize)
+	}
+
+	// If the file changed since the transfer started, resuming would stitch
+	// two different versions together, so start over
+	if t.SHA256 != "" && t.SHA256 != info.SHA256 {
+		buf = nil
+	}
+
+	s.mu.Lock()
+	transfer := s.transfers[transferID]
+	transfer.Size = info.Size
+	transfer.SHA256 = info.SHA256
+	transfer.Mode = info.Mode
+	s.mu.Unlock()
+
+	for offset := int64(len(buf)); offset < info.Size; offset = int64(len(buf)) {
+		result, err := s.run(t.AgentID, agent.AgentCommand{
+			Command: agent.FileReadChunkCommand,
+			Args: []string{
+				t.Path,
+				strconv.FormatInt(offset, 10),
+				strconv.FormatInt(s.config.ChunkSize, 10),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read chunk at offset %d: %w", offset, err)
+		}
+
+		chunk, err := base64.StdEncoding.DecodeString(result)
+		if err != nil {
+			return fmt.Errorf("invalid chunk encoding at offset %d: %w", offset, err)
+		}
+		if len(chunk) == 0 {
+			return fmt.Errorf("file %s shrank during transfer", t.Path)
+		}
+
+		buf = append(buf, chunk...)
+		s.mu.Lock()
+		s.content[transferID] = buf
+		transfer.Transferred = int64(len(buf))
+		transfer.UpdatedAt = time.Now()
+		s.mu.Unlock()
+	}
+
+	sum := sha256.Sum256(buf)
+	if hex.EncodeToString(sum[:]) != info.SHA256 {
+		s.mu.Lock()
+		delete(s.content, transferID)
+		transfer.Transferred = 0
+		s.mu.Unlock()
+		return errors.New("checksum mismatch after download")
+	}
+	return nil
+}
+
+func (s *transferService) stat(agentID, path string) (*agent.FileInfo, error) {
+	result, err := s.run(agentID, agent.AgentCommand{
+		Command: agent.FileStatCommand,
+		Args:    []string{path},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var info agent.FileInfo
+	if err := json.Unmarshal([]byte(result), &info); err != nil {
+		return nil, fmt.Errorf("invalid stat result: %w", err)
+	}
+	return &info, nil
+}
+
+func (s *transferService) run(agentID string, cmd agent.AgentCommand) (string, error) {
+	cmd.Timeout = s.config.ChunkTimeout
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ChunkTimeout)
+	defer cancel()
+
+	job, err := s.agentManager.RunCommandAndWait(ctx, agentID, cmd)
+	if err != nil {
+		return "", err
+	}
+	return job.Result, nil
+}
+
+func (s *transferService) setTransferred(transferID string, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers[transferID].Transferred = n
+	s.transfers[transferID].UpdatedAt = time.Now()
+}
+
+// partPathFor places the partial file in the destination directory so the
+// final rename on the agent is atomic.
+func partPathFor(path, transferID string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%s.part", filepath.Base(path), transferID))
+}
@@ -0,0 +1,62 @@
+// backend/internal/transfer/transfer.go
+package transfer
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultChunkSize   = 512 * 1024        // 512KB per job
+	DefaultMaxFileSize = 256 * 1024 * 1024 // 256MB
+)
+
+type Service interface {
+	Upload(ctx context.Context, agentID string, req UploadRequest, content []byte) (*Transfer, error)
+	Download(ctx context.Context, agentID, path string) (*Transfer, error)
+	Resume(ctx context.Context, transferID string) (*Transfer, error)
+	GetTransfer(transferID string) (*Transfer, error)
+	GetContent(transferID string) ([]byte, error)
+	ListTransfers(agentID string) []Transfer
+	MaxFileSize() int64
+}
+
+type UploadRequest struct {
+	Path  string `json:"path" form:"path" binding:"required"`
+	Mode  string `json:"mode" form:"mode"` // octal, e.g. 0644
+	Owner string `json:"owner" form:"owner"`
+	Group string `json:"group" form:"group"`
+}
+
+type Transfer struct {
+	ID          string    `json:"id"`
+	AgentID     string    `json:"agent_id"`
+	Direction   string    `json:"direction"` // upload, download
+	Path        string    `json:"path"`
+	Mode        string    `json:"mode,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	Group       string    `json:"group,omitempty"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	Transferred int64     `json:"transferred"`
+	Status      string    `json:"status"` // pending, in-progress, completed, failed
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Config struct {
+	ChunkSize    int64
+	MaxFileSize  int64
+	ChunkTimeout time.Duration
+	Retention    time.Duration // how long finished transfers and their content are kept
+}
+
+func DefaultConfig() Config {
+	return Config{
+		ChunkSize:    DefaultChunkSize,
+		MaxFileSize:  DefaultMaxFileSize,
+		ChunkTimeout: 2 * time.Minute,
+		Retention:    time.Hour,
+	}
+}
@@ -0,0 +1,61 @@
+// backend/internal/tsdb/bstream.go
+package tsdb
+
+import "io"
+
+// bstream is an append-only bit stream, most significant bit first.
+type bstream struct {
+	b []byte
+	n uint64 // bits written
+}
+
+func (s *bstream) writeBit(bit bool) {
+	if s.n%8 == 0 {
+		s.b = append(s.b, 0)
+	}
+	if bit {
+		s.b[len(s.b)-1] |= 1 << (7 - s.n%8)
+	}
+	s.n++
+}
+
+// writeBits writes the low nbits of u.
+func (s *bstream) writeBits(u uint64, nbits int) {
+	for i := nbits - 1; i >= 0; i-- {
+		s.writeBit(u&(1<<uint(i)) != 0)
+	}
+}
+
+func (s *bstream) reader() *breader {
+	return &breader{b: s.b, n: s.n}
+}
+
+type breader struct {
+	b   []byte
+	n   uint64 // bits available
+	pos uint64
+}
+
+func (r *breader) readBit() (bool, error) {
+	if r.pos >= r.n {
+		return false, io.ErrUnexpectedEOF
+	}
+	bit := r.b[r.pos/8]&(1<<(7-r.pos%8)) != 0
+	r.pos++
+	return bit, nil
+}
+
+func (r *breader) readBits(nbits int) (uint64, error) {
+	if r.pos+uint64(nbits) > r.n {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var u uint64
+	for i := 0; i < nbits; i++ {
+		bit, _ := r.readBit()
+		u <<= 1
+		if bit {
+			u |= 1
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,287 @@
+// backend/internal/tsdb/chunk.go
+package tsdb
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// A chunk is sealed after this many points: 1h of 30s samples, 2h of
+// minute rollups or 5 days of hourly ones.
+const samplesPerChunk = 120
+
+// noLeading marks a column that hasn't written a leading/trailing window yet.
+const noLeading = 0xff
+
+// chunk is a Gorilla-compressed block of points: timestamps are stored as
+// delta-of-deltas and each value column is XORed against its previous
+// value, so a regularly scraped, slowly changing series costs a couple of
+// bits per sample. Raw chunks have one column, rollup chunks four.
+type chunk struct {
+	cols   int
+	stream bstream
+	count  int
+	minT   int64
+	maxT   int64
+
+	// Appender state; rebuilt by replaying the stream when loaded
+	delta    int64
+	vals     []float64
+	leading  []uint8
+	trailing []uint8
+}
+
+func newChunk(cols int) *chunk {
+	c := &chunk{
+		cols:     cols,
+		vals:     make([]float64, cols),
+		leading:  make([]uint8, cols),
+		trailing: make([]uint8, cols),
+	}
+	for i := range c.leading {
+		c.leading[i] = noLeading
+	}
+	return c
+}
+
+// loadChunk rebuilds a chunk from its encoded stream.
+func loadChunk(cols int, data []byte, nbits uint64, count int) (*chunk, error) {
+	c := newChunk(cols)
+	c.stream = bstream{b: data, n: nbits}
+	c.count = count
+
+	it := c.iterator()
+	for it.next() {
+		if it.read == 1 {
+			c.minT = it.t
+		}
+	}
+	if it.err != nil {
+		return nil, fmt.Errorf("corrupt chunk: %w", it.err)
+	}
+	if it.read != count {
+		return nil, fmt.Errorf("corrupt chunk: read %d of %d points", it.read, count)
+	}
+	c.maxT = it.t
+	c.delta = it.delta
+	copy(c.vals, it.vals)
+	copy(c.leading, it.leading)
+	copy(c.trailing, it.trailing)
+	return c, nil
+}
+
+func (c *chunk) full() bool {
+	return c.count >= samplesPerChunk
+}
+
+// append adds a point; t must be after the chunk's last timestamp.
+func (c *chunk) append(t int64, vals ...float64) {
+	if c.count == 0 {
+		c.stream.writeBits(uint64(t), 64)
+		for i, v := range vals {
+			c.stream.writeBits(math.Float64bits(v), 64)
+			c.vals[i] = v
+		}
+		c.minT = t
+	} else {
+		delta := t - c.maxT
+		writeDoD(&c.stream, delta-c.delta)
+		c.delta = delta
+		for i, v := range vals {
+			c.writeXOR(i, v)
+		}
+	}
+	c.maxT = t
+	c.count++
+}
+
+func writeDoD(s *bstream, dod int64) {
+	switch {
+	case dod == 0:
+		s.writeBit(false)
+	case fitsBits(dod, 14):
+		s.writeBits(0b10, 2)
+		s.writeBits(uint64(dod), 14)
+	case fitsBits(dod, 17):
+		s.writeBits(0b110, 3)
+		s.writeBits(uint64(dod), 17)
+	case fitsBits(dod, 20):
+		s.writeBits(0b1110, 4)
+		s.writeBits(uint64(dod), 20)
+	default:
+		s.writeBits(0b1111, 4)
+		s.writeBits(uint64(dod), 64)
+	}
+}
+
+// fitsBits reports whether x round-trips through an nbits two's complement
+// field as decoded by readDoD.
+func fitsBits(x int64, nbits uint) bool {
+	return -(1<<(nbits-1))+1 <= x && x <= 1<<(nbits-1)
+}
+
+func (c *chunk) writeXOR(col int, v float64) {
+	xor := math.Float64bits(v) ^ math.Float64bits(c.vals[col])
+	c.vals[col] = v
+	if xor == 0 {
+		c.stream.writeBit(false)
+		return
+	}
+	c.stream.writeBit(true)
+
+	leading := uint8(bits.LeadingZeros64(xor))
+	trailing := uint8(bits.TrailingZeros64(xor))
+	if leading > 31 {
+		leading = 31 // it gets 5 bits
+	}
+	if c.leading[col] != noLeading && leading >= c.leading[col] && trailing >= c.trailing[col] {
+		// Meaningful bits fit in the previous window
+		c.stream.writeBit(false)
+		c.stream.writeBits(xor>>c.trailing[col], 64-int(c.leading[col])-int(c.trailing[col]))
+		return
+	}
+
+	c.leading[col], c.trailing[col] = leading, trailing
+	sigbits := 64 - leading - trailing
+	c.stream.writeBit(true)
+	c.stream.writeBits(uint64(leading), 5)
+	c.stream.writeBits(uint64(sigbits), 6) // 64 wraps to 0, which can't otherwise occur
+	c.stream.writeBits(xor>>trailing, int(sigbits))
+}
+
+type chunkIterator struct {
+	r        *breader
+	count    int
+	read     int
+	t        int64
+	delta    int64
+	vals     []float64
+	leading  []uint8
+	trailing []uint8
+	err      error
+}
+
+func (c *chunk) iterator() *chunkIterator {
+	it := &chunkIterator{
+		r:        c.stream.reader(),
+		count:    c.count,
+		vals:     make([]float64, c.cols),
+		leading:  make([]uint8, c.cols),
+		trailing: make([]uint8, c.cols),
+	}
+	for i := range it.leading {
+		it.leading[i] = noLeading
+	}
+	return it
+}
+
+// next decodes the next point into it.t and it.vals.
+func (it *chunkIterator) next() bool {
+	if it.err != nil || it.read >= it.count {
+		return false
+	}
+	if it.read == 0 {
+		t, err := it.r.readBits(64)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.t = int64(t)
+		for i := range it.vals {
+			v, err := it.r.readBits(64)
+			if err != nil {
+				it.err = err
+				return false
+			}
+			it.vals[i] = math.Float64frombits(v)
+		}
+	} else {
+		dod, err := readDoD(it.r)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.delta += dod
+		it.t += it.delta
+		for i := range it.vals {
+			if err := it.readXOR(i); err != nil {
+				it.err = err
+				return false
+			}
+		}
+	}
+	it.read++
+	return true
+}
+
+func readDoD(r *breader) (int64, error) {
+	prefix := 0
+	for prefix < 4 {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		prefix++
+	}
+
+	var size int
+	switch prefix {
+	case 0:
+		return 0, nil
+	case 1:
+		size = 14
+	case 2:
+		size = 17
+	case 3:
+		size = 20
+	default:
+		size = 64
+	}
+	u, err := r.readBits(size)
+	if err != nil {
+		return 0, err
+	}
+	if size < 64 && u > 1<<(size-1) {
+		return int64(u) - 1<<size, nil
+	}
+	return int64(u), nil
+}
+
+func (it *chunkIterator) readXOR(col int) error {
+	changed, err := it.r.readBit()
+	if err != nil || !changed {
+		return err
+	}
+	newWindow, err := it.r.readBit()
+	if err != nil {
+		return err
+	}
+	if newWindow {
+		leading, err := it.r.readBits(5)
+		if err != nil {
+			return err
+		}
+		sigbits, err := it.r.readBits(6)
+		if err != nil {
+			return err
+		}
+		if sigbits == 0 {
+			sigbits = 64
+		}
+		it.leading[col] = uint8(leading)
+		it.trailing[col] = uint8(64 - leading - sigbits)
+	} else if it.leading[col] == noLeading {
+		return fmt.Errorf("value reuses a window before setting one")
+	}
+
+	u, err := it.r.readBits(64 - int(it.leading[col]) - int(it.trailing[col]))
+	if err != nil {
+		return err
+	}
+	it.vals[col] = math.Float64frombits(math.Float64bits(it.vals[col]) ^ u<<it.trailing[col])
+	return nil
+}
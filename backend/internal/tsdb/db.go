@@ -0,0 +1,479 @@
+// backend/internal/tsdb/db.go
+package tsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricName is the label holding a series' metric name.
+const MetricName = "__name__"
+
+// ErrOutOfOrder is returned for samples at or before their series' newest
+// timestamp. Such samples are dropped.
+var ErrOutOfOrder = errors.New("out of order sample")
+
+// Labels identify a series.
+type Labels map[string]string
+
+// String is the canonical form of the label set, e.g.
+// cpu{agent_id="a1",mount="/"}, and is unique per series.
+func (l Labels) String() string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		if name != MetricName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(l[MetricName])
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", name, l[name])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (l Labels) copy() Labels {
+	c := make(Labels, len(l))
+	for name, value := range l {
+		c[name] = value
+	}
+	return c
+}
+
+// Sample is one value to append to the series identified by Labels.
+type Sample struct {
+	Labels Labels
+	Time   time.Time
+	Value  float64
+}
+
+// Point is a raw sample or a rollup interval. Raw samples have Count 1 and
+// their value in Min, Max and Sum.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Sum   float64   `json:"sum"`
+	Count int64     `json:"count"`
+}
+
+func (p Point) Avg() float64 {
+	if p.Count == 0 {
+		return 0
+	}
+	return p.Sum / float64(p.Count)
+}
+
+type Series struct {
+	Labels Labels  `json:"labels"`
+	Points []Point `json:"points"`
+}
+
+// Resolution is the interval between points a query reads.
+type Resolution time.Duration
+
+const (
+	Raw    Resolution = 0
+	Minute            = Resolution(time.Minute)
+	Hour              = Resolution(time.Hour)
+)
+
+func (r Resolution) String() string {
+	switch r {
+	case Raw:
+		return "raw"
+	case Minute:
+		return "1m"
+	case Hour:
+		return "1h"
+	}
+	return time.Duration(r).String()
+}
+
+func (r Resolution) millis() int64 {
+	return time.Duration(r).Milliseconds()
+}
+
+type Options struct {
+	RawRetention       time.Duration // raw samples
+	MinuteRetention    time.Duration // 1m rollups
+	HourRetention      time.Duration // 1h rollups
+	CheckpointInterval time.Duration // how often memory is snapshotted and the WAL truncated
+}
+
+var DefaultOptions = Options{
+	RawRetention:       48 * time.Hour,
+	MinuteRetention:    30 * 24 * time.Hour,
+	HourRetention:      400 * 24 * time.Hour,
+	CheckpointInterval: 15 * time.Minute,
+}
+
+// DB is an embedded time-series store. Series live in memory as compressed
+// chunks; every append is written to a WAL first, and the whole store is
+// periodically snapshotted so the WAL stays short.
+type DB struct {
+	opts     Options
+	dir      string // empty for a memory-only store
+	series   map[string]*series
+	postings map[string]map[uint64]*series // name=value -> series
+	nextRef  uint64
+	wal      *wal
+	mu       sync.RWMutex
+
+	checkpointMu sync.Mutex // one checkpoint at a time
+}
+
+// Open loads the store in dir, replaying its WAL, or creates it. An empty
+// dir keeps everything in memory. Zero options take their defaults.
+func Open(dir string, opts Options) (*DB, error) {
+	if opts.RawRetention <= 0 {
+		opts.RawRetention = DefaultOptions.RawRetention
+	}
+	if opts.MinuteRetention <= 0 {
+		opts.MinuteRetention = DefaultOptions.MinuteRetention
+	}
+	if opts.HourRetention <= 0 {
+		opts.HourRetention = DefaultOptions.HourRetention
+	}
+	if opts.CheckpointInterval <= 0 {
+		opts.CheckpointInterval = DefaultOptions.CheckpointInterval
+	}
+
+	db := &DB{
+		opts:     opts,
+		dir:      dir,
+		series:   make(map[string]*series),
+		postings: make(map[string]map[uint64]*series),
+	}
+	if dir == "" {
+		return db, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create tsdb directory: %w", err)
+	}
+	if err := db.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := db.replayWAL(); err != nil {
+		return nil, err
+	}
+
+	// Start from a clean WAL so refs in it never predate the snapshot
+	w, err := openWAL(filepath.Join(dir, walFile))
+	if err != nil {
+		return nil, err
+	}
+	db.wal = w
+	if err := db.Checkpoint(); err != nil {
+		w.close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Append adds samples to their series, creating new series as needed.
+// Out-of-order samples are dropped and reported with ErrOutOfOrder once
+// the rest are stored. An invalid sample rejects the whole batch, so
+// nothing is applied in memory without also reaching the WAL.
+func (db *DB) Append(samples ...Sample) error {
+	for i, sample := range samples {
+		if sample.Labels[MetricName] == "" {
+			return fmt.Errorf("sample %d has no %s label", i, MetricName)
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var dropped int
+	logged := make([]walSample, 0, len(samples))
+	for _, sample := range samples {
+		s := db.getOrCreate(sample.Labels)
+		t := sample.Time.UnixMilli()
+		if err := s.append(t, sample.Value); err != nil {
+			dropped++
+			continue
+		}
+		if db.wal != nil && !db.wal.logged[s.ref] {
+			db.wal.logSeries(s.ref, s.labels)
+		}
+		logged = append(logged, walSample{ref: s.ref, t: t, v: sample.Value})
+	}
+
+	if db.wal != nil && len(logged) > 0 {
+		if err := db.wal.logSamples(logged); err != nil {
+			return fmt.Errorf("failed to write WAL: %w", err)
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("%w: dropped %d of %d samples", ErrOutOfOrder, dropped, len(samples))
+	}
+	return nil
+}
+
+func (db *DB) getOrCreate(labels Labels) *series {
+	key := labels.String()
+	if s, ok := db.series[key]; ok {
+		return s
+	}
+
+	db.nextRef++
+	own := labels.copy()
+	s := newSeries(db.nextRef, own)
+	db.series[key] = s
+	for name, value := range own {
+		p := postingKey(name, value)
+		if db.postings[p] == nil {
+			db.postings[p] = make(map[uint64]*series)
+		}
+		db.postings[p][s.ref] = s
+	}
+	return s
+}
+
+func (db *DB) deleteSeries(key string, s *series) {
+	delete(db.series, key)
+	for name, value := range s.labels {
+		p := postingKey(name, value)
+		delete(db.postings[p], s.ref)
+		if len(db.postings[p]) == 0 {
+			delete(db.postings, p)
+		}
+	}
+}
+
+func postingKey(name, value string) string {
+	return name + "\xff" + value
+}
+
+// Query returns the series carrying every label in match, with their
+// points in [start, end] at the given resolution. Series with no points in
+// the range are left out.
+func (db *DB) Query(match Labels, start, end time.Time, res Resolution) ([]Series, error) {
+	matchers := make([]*Matcher, 0, len(match))
+	for name, value := range match {
+		matchers = append(matchers, &Matcher{Name: name, Type: MatchEqual, Value: value})
+	}
+	return db.Select(matchers, start, end, res)
+}
+
+// Select is Query with any kind of label matchers.
+func (db *DB) Select(matchers []*Matcher, start, end time.Time, res Resolution) ([]Series, error) {
+	if res != Raw && res != Minute && res != Hour {
+		return nil, fmt.Errorf("unsupported resolution %s", res)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end is before start")
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	mint, maxt := start.UnixMilli(), end.UnixMilli()
+	var result []Series
+	for _, s := range db.selectLocked(matchers) {
+		if points := s.points(mint, maxt, res); len(points) > 0 {
+			result = append(result, Series{Labels: s.labels.copy(), Points: points})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Labels.String() < result[j].Labels.String()
+	})
+	return result, nil
+}
+
+// LatestSample is the newest raw sample of a series.
+type LatestSample struct {
+	Labels Labels
+	Time   time.Time
+	Value  float64
+}
+
+// Latest returns the newest sample of every series matching the matchers
+// that has one at or after since.
+func (db *DB) Latest(matchers []*Matcher, since time.Time) []LatestSample {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	mint := since.UnixMilli()
+	var result []LatestSample
+	for _, s := range db.selectLocked(matchers) {
+		if len(s.raw) == 0 || s.lastT < mint {
+			continue
+		}
+		head := s.raw[len(s.raw)-1]
+		result = append(result, LatestSample{Labels: s.labels.copy(), Time: fromMillis(head.maxT), Value: head.vals[0]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Labels.String() < result[j].Labels.String() })
+	return result
+}
+
+// SeriesLabels returns the label sets of the series matching every
+// matcher, sorted.
+func (db *DB) SeriesLabels(matchers []*Matcher) []Labels {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	selected := db.selectLocked(matchers)
+	result := make([]Labels, 0, len(selected))
+	for _, s := range selected {
+		result = append(result, s.labels.copy())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].String() < result[j].String() })
+	return result
+}
+
+// selectLocked intersects the postings of the equality matchers, starting
+// from the smallest, and filters the candidates with the other matchers.
+func (db *DB) selectLocked(matchers []*Matcher) []*series {
+	var lists []map[uint64]*series
+	var filters []*Matcher
+	for _, m := range matchers {
+		if m.Type != MatchEqual || m.Value == "" {
+			filters = append(filters, m)
+			continue
+		}
+		list := db.postings[postingKey(m.Name, m.Value)]
+		if len(list) == 0 {
+			return nil
+		}
+		lists = append(lists, list)
+	}
+
+	candidates := make(map[uint64]*series)
+	if len(lists) == 0 {
+		for _, s := range db.series {
+			candidates[s.ref] = s
+		}
+	} else {
+		sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+		for ref, s := range lists[0] {
+			candidates[ref] = s
+		}
+		for _, list := range lists[1:] {
+			for ref := range candidates {
+				if _, ok := list[ref]; !ok {
+					delete(candidates, ref)
+				}
+			}
+		}
+	}
+
+	selected := make([]*series, 0, len(candidates))
+	for _, s := range candidates {
+		matches := true
+		for _, m := range filters {
+			if !m.Matches(s.labels[m.Name]) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			selected = append(selected, s)
+		}
+	}
+	return selected
+}
+
+// ResolutionFor is the finest resolution still retained at start, so a
+// range query reads raw samples for recent data and rollups for older.
+func (db *DB) ResolutionFor(start time.Time) Resolution {
+	age := time.Since(start)
+	switch {
+	case age <= db.opts.RawRetention:
+		return Raw
+	case age <= db.opts.MinuteRetention:
+		return Minute
+	}
+	return Hour
+}
+
+// Truncate applies retention, dropping expired chunks and empty series.
+func (db *DB) Truncate(now time.Time) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	rawCutoff := now.Add(-db.opts.RawRetention).UnixMilli()
+	levelCutoffs := [len(rollupSteps)]int64{
+		now.Add(-db.opts.MinuteRetention).UnixMilli(),
+		now.Add(-db.opts.HourRetention).UnixMilli(),
+	}
+	for key, s := range db.series {
+		if s.truncate(rawCutoff, levelCutoffs) {
+			db.deleteSeries(key, s)
+		}
+	}
+}
+
+// Run syncs the WAL every second, applies retention every minute and
+// checkpoints every CheckpointInterval until ctx is done.
+func (db *DB) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	lastTruncate, lastCheckpoint := time.Now(), time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := db.sync(); err != nil {
+				fmt.Printf("Failed to sync metrics WAL: %v\n", err)
+			}
+			if now.Sub(lastTruncate) >= time.Minute {
+				db.Truncate(now)
+				lastTruncate = now
+			}
+			if now.Sub(lastCheckpoint) >= db.opts.CheckpointInterval {
+				if err := db.Checkpoint(); err != nil {
+					fmt.Printf("Failed to checkpoint metrics: %v\n", err)
+				}
+				lastCheckpoint = now
+			}
+		}
+	}
+}
+
+func (db *DB) sync() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.wal == nil {
+		return nil
+	}
+	return db.wal.sync()
+}
+
+// Close checkpoints the store and closes its WAL.
+func (db *DB) Close() error {
+	if db.wal == nil {
+		return nil
+	}
+	if err := db.Checkpoint(); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	err := db.wal.close()
+	db.wal = nil
+	return err
+}
+
+func fromMillis(t int64) time.Time {
+	return time.UnixMilli(t)
+}
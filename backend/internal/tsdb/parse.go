@@ -0,0 +1,508 @@
+// backend/internal/tsdb/parse.go
+package tsdb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The query language is a small PromQL subset:
+//
+//	cpu{env="prod",agent_id=~"web-.*"}
+//	rate(network_interface_in_bytes{interface="eth0"}[5m])
+//	percentile(95, cpu) by (env)
+//	max by (agent_id) (irate(network_interface_out_bytes[1m]))
+//
+// Aggregations are avg, min, max, sum, count and percentile(q, ...) with q
+// from 0 to 100, and may be nested.
+
+type MatchType int
+
+const (
+	MatchEqual MatchType = iota
+	MatchNotEqual
+	MatchRegexp
+	MatchNotRegexp
+)
+
+func (t MatchType) String() string {
+	return [...]string{"=", "!=", "=~", "!~"}[t]
+}
+
+// Matcher selects series by one label. A missing label matches as "".
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+	re    *regexp.Regexp
+}
+
+func NewMatcher(t MatchType, name, value string) (*Matcher, error) {
+	m := &Matcher{Name: name, Type: t, Value: value}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex for %s: %w", name, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+func (m *Matcher) Matches(value string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	}
+	return !m.re.MatchString(value)
+}
+
+func (m *Matcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
+
+// Expr is a parsed query.
+type Expr interface {
+	String() string
+}
+
+type selectorExpr struct {
+	matchers []*Matcher
+	window   time.Duration // range selectors only, e.g. [5m]
+}
+
+type callExpr struct {
+	fn  string // rate, irate
+	arg *selectorExpr
+}
+
+type aggregateExpr struct {
+	op    string
+	param float64 // percentile
+	by    []string
+	expr  Expr
+}
+
+func (e *selectorExpr) String() string {
+	var name string
+	var parts []string
+	for _, m := range e.matchers {
+		if m.Name == MetricName && m.Type == MatchEqual && name == "" {
+			name = m.Value
+			continue
+		}
+		parts = append(parts, m.String())
+	}
+	s := name
+	if len(parts) > 0 || name == "" {
+		s += "{" + strings.Join(parts, ",") + "}"
+	}
+	if e.window > 0 {
+		s += "[" + formatDuration(e.window) + "]"
+	}
+	return s
+}
+
+func (e *callExpr) String() string {
+	return e.fn + "(" + e.arg.String() + ")"
+}
+
+func (e *aggregateExpr) String() string {
+	s := e.op + "("
+	if e.op == "percentile" {
+		s += strconv.FormatFloat(e.param, 'g', -1, 64) + ", "
+	}
+	s += e.expr.String() + ")"
+	if len(e.by) > 0 {
+		s += " by (" + strings.Join(e.by, ", ") + ")"
+	}
+	return s
+}
+
+var aggregations = map[string]bool{"avg": true, "min": true, "max": true, "sum": true, "count": true, "percentile": true}
+
+var functions = map[string]bool{"rate": true, "irate": true}
+
+// ParseExpr parses a query.
+func ParseExpr(input string) (Expr, error) {
+	p, err := newParser(input)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return expr, nil
+}
+
+// ParseSelector parses a series selector such as cpu{env="prod"}.
+func ParseSelector(input string) ([]*Matcher, error) {
+	p, err := newParser(input)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := p.selector()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	if sel.window > 0 {
+		return nil, fmt.Errorf("a series selector takes no range")
+	}
+	return sel.matchers, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber // also durations such as 5m, which start with a digit
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func newParser(input string) (*parser, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens}, nil
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(input) && rune(input[end]) != c {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, fmt.Errorf("parse error at %d: unterminated string", i)
+			}
+			raw := input[i+1 : end]
+			if c == '\'' {
+				raw = strings.ReplaceAll(strings.ReplaceAll(raw, `\'`, `'`), `"`, `\"`)
+			}
+			s, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("parse error at %d: invalid string", i)
+			}
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i = end + 1
+		case c == '_' || c == ':' || unicode.IsLetter(c):
+			end := i
+			for end < len(input) && isIdentChar(rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[i:end], pos: i})
+			i = end
+		case unicode.IsDigit(c) || c == '.':
+			end := i
+			for end < len(input) && (isIdentChar(rune(input[end])) || input[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: input[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range punctuation {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("parse error at %d: unexpected character %q", i, c)
+			}
+			tokens = append(tokens, token{kind: tokPunct, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+// Two-character operators come first so "!=" isn't read as "!".
+var punctuation = []string{"!=", "=~", "!~", "=", "(", ")", "{", "}", "[", "]", ","}
+
+func isIdentChar(c rune) bool {
+	return c == '_' || c == ':' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("parse error at %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(punct string) error {
+	if t := p.next(); t.kind != tokPunct || t.text != punct {
+		return p.errorf(t, "expected %q, got %q", punct, t.text)
+	}
+	return nil
+}
+
+func (p *parser) accept(punct string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expr() (Expr, error) {
+	t := p.peek()
+	if t.kind == tokIdent {
+		following := p.tokens[p.pos+1]
+		opensCall := following.kind == tokPunct && following.text == "("
+		opensBy := following.kind == tokIdent && following.text == "by"
+		switch {
+		case aggregations[t.text] && (opensCall || opensBy):
+			return p.aggregate()
+		case functions[t.text] && opensCall:
+			return p.call()
+		}
+	}
+	sel, err := p.selector()
+	if err != nil {
+		return nil, err
+	}
+	if sel.window > 0 {
+		return nil, p.errorf(t, "a range selector is only valid in rate or irate")
+	}
+	return sel, nil
+}
+
+func (p *parser) aggregate() (Expr, error) {
+	agg := &aggregateExpr{op: p.next().text}
+	var err error
+	if t := p.peek(); t.kind == tokIdent && t.text == "by" {
+		if agg.by, err = p.grouping(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	if agg.op == "percentile" {
+		t := p.next()
+		q, err := strconv.ParseFloat(t.text, 64)
+		if t.kind != tokNumber || err != nil || q < 0 || q > 100 {
+			return nil, p.errorf(t, "percentile needs a number from 0 to 100 first")
+		}
+		agg.param = q
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+	if agg.expr, err = p.expr(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == tokIdent && p.peek().text == "by" {
+		if agg.by != nil {
+			return nil, p.errorf(p.peek(), "by given twice")
+		}
+		if agg.by, err = p.grouping(); err != nil {
+			return nil, err
+		}
+	}
+	return agg, nil
+}
+
+// grouping parses "by (label, ...)".
+func (p *parser) grouping() ([]string, error) {
+	p.next()
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	for !p.accept(")") {
+		t := p.next()
+		if t.kind != tokIdent {
+			return nil, p.errorf(t, "expected a label name, got %q", t.text)
+		}
+		labels = append(labels, t.text)
+		if !p.accept(",") {
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return labels, nil
+}
+
+func (p *parser) call() (Expr, error) {
+	call := &callExpr{fn: p.next().text}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	sel, err := p.selector()
+	if err != nil {
+		return nil, err
+	}
+	if sel.window == 0 {
+		return nil, p.errorf(t, "%s needs a range selector such as [5m]", call.fn)
+	}
+	call.arg = sel
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+func (p *parser) selector() (*selectorExpr, error) {
+	sel := &selectorExpr{}
+	start := p.peek()
+	if start.kind == tokIdent {
+		p.next()
+		sel.matchers = append(sel.matchers, &Matcher{Name: MetricName, Type: MatchEqual, Value: start.text})
+	}
+
+	if p.accept("{") {
+		for !p.accept("}") {
+			name := p.next()
+			if name.kind != tokIdent {
+				return nil, p.errorf(name, "expected a label name, got %q", name.text)
+			}
+			op := p.next()
+			var t MatchType
+			switch op.text {
+			case "=":
+				t = MatchEqual
+			case "!=":
+				t = MatchNotEqual
+			case "=~":
+				t = MatchRegexp
+			case "!~":
+				t = MatchNotRegexp
+			default:
+				return nil, p.errorf(op, "expected a match operator, got %q", op.text)
+			}
+			value := p.next()
+			if value.kind != tokString {
+				return nil, p.errorf(value, "expected a quoted value, got %q", value.text)
+			}
+			m, err := NewMatcher(t, name.text, value.text)
+			if err != nil {
+				return nil, p.errorf(value, "%v", err)
+			}
+			sel.matchers = append(sel.matchers, m)
+			if !p.accept(",") {
+				if err := p.expect("}"); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	} else if start.kind != tokIdent {
+		return nil, p.errorf(start, "expected a metric name or selector, got %q", start.text)
+	}
+
+	if len(sel.matchers) == 0 {
+		return nil, p.errorf(start, "selector matches every series")
+	}
+
+	if p.accept("[") {
+		t := p.next()
+		window, err := ParseDuration(t.text)
+		if t.kind != tokNumber || err != nil || window <= 0 {
+			return nil, p.errorf(t, "invalid range %q", t.text)
+		}
+		sel.window = window
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+var durationPattern = regexp.MustCompile(`^(\d+)(ms|s|m|h|d|w)`)
+
+// ParseDuration parses durations such as 30s, 5m, 7d or 1h30m.
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	var d time.Duration
+	for rest := s; rest != ""; {
+		m := durationPattern.FindStringSubmatch(rest)
+		if m == nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n) * durationUnits[m[2]]
+		rest = rest[len(m[0]):]
+	}
+	return d, nil
+}
+
+func formatDuration(d time.Duration) string {
+	for _, unit := range []string{"w", "d", "h", "m", "s"} {
+		if d%durationUnits[unit] == 0 {
+			return strconv.FormatInt(int64(d/durationUnits[unit]), 10) + unit
+		}
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+}
@@ -0,0 +1,334 @@
+// backend/internal/tsdb/query.go
+package tsdb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// maxBuckets bounds the points per series a query can ask for.
+const maxBuckets = 11000
+
+// Result is a query evaluated over step-wide buckets from Start.
+type Result struct {
+	Query      string         `json:"query"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Step       time.Duration  `json:"step"`
+	Resolution string         `json:"resolution"` // the stored data read: raw, 1m or 1h
+	Series     []ResultSeries `json:"series"`
+}
+
+type ResultSeries struct {
+	Labels Labels        `json:"labels"`
+	Points []ResultPoint `json:"points"`
+}
+
+// ResultPoint is the value of a bucket, timestamped with its start.
+// Buckets without data are left out.
+type ResultPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Exec evaluates a query over [start, end] in step-wide buckets.
+//
+// A series' value in a bucket is the average of its points there; rate and
+// irate are per second. Aggregations combine every point the grouped series
+// have in a bucket: avg, min and max over the points, sum and count over the
+// series' bucket values, and percentile over the point values. Points are
+// raw samples while they're retained and the step allows, otherwise 1m or
+// 1h rollup averages.
+func (db *DB) Exec(query string, start, end time.Time, step time.Duration) (*Result, error) {
+	expr, err := ParseExpr(query)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end is before start")
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	buckets := int(end.Sub(start)/step) + 1
+	if buckets > maxBuckets {
+		return nil, fmt.Errorf("%d buckets exceeds the limit of %d; use a larger step", buckets, maxBuckets)
+	}
+
+	ev := &evaluator{
+		db:      db,
+		start:   start,
+		end:     end,
+		step:    step,
+		buckets: buckets,
+		res:     db.resolutionForStep(start, step),
+	}
+	series, err := ev.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Query:      expr.String(),
+		Start:      start,
+		End:        end,
+		Step:       step,
+		Resolution: ev.res.String(),
+		Series:     []ResultSeries{},
+	}
+	for _, s := range series {
+		rs := ResultSeries{Labels: s.labels}
+		for i, b := range s.buckets {
+			v := b.avg()
+			if b.count == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			rs.Points = append(rs.Points, ResultPoint{Time: start.Add(time.Duration(i) * step), Value: v})
+		}
+		if len(rs.Points) > 0 {
+			result.Series = append(result.Series, rs)
+		}
+	}
+	sort.Slice(result.Series, func(i, j int) bool {
+		return result.Series[i].Labels.String() < result.Series[j].Labels.String()
+	})
+	return result, nil
+}
+
+// resolutionForStep reads rollups rather than raw samples when a bucket
+// spans at least a rollup interval, or raw data is no longer retained.
+func (db *DB) resolutionForStep(start time.Time, step time.Duration) Resolution {
+	res := db.ResolutionFor(start)
+	for _, r := range rollupSteps {
+		if time.Duration(r) <= step && r > res {
+			res = r
+		}
+	}
+	return res
+}
+
+// rangeResolution is the coarsest resolution up to max that still has a
+// few points per window, so a rate over 5m reads raw samples or 1m rollups
+// even when the step is an hour.
+func (db *DB) rangeResolution(start time.Time, window time.Duration, max Resolution) Resolution {
+	res := db.ResolutionFor(start)
+	for _, r := range rollupSteps {
+		if r > res && r <= max && 2*time.Duration(r) <= window {
+			res = r
+		}
+	}
+	return res
+}
+
+type evaluator struct {
+	db      *DB
+	start   time.Time
+	end     time.Time
+	step    time.Duration
+	buckets int
+	res     Resolution
+}
+
+type evalSeries struct {
+	labels  Labels
+	buckets []bucketStats
+}
+
+// bucketStats accumulates the points falling in one bucket.
+type bucketStats struct {
+	min    float64
+	max    float64
+	sum    float64
+	count  int64
+	values []float64 // point averages, for percentiles
+}
+
+func (b *bucketStats) add(p Point) {
+	if b.count == 0 {
+		b.min, b.max = p.Min, p.Max
+	}
+	b.min = math.Min(b.min, p.Min)
+	b.max = math.Max(b.max, p.Max)
+	b.sum += p.Sum
+	b.count += p.Count
+	b.values = append(b.values, p.Avg())
+}
+
+func (b *bucketStats) addValue(v float64) {
+	b.add(Point{Min: v, Max: v, Sum: v, Count: 1})
+}
+
+func (b *bucketStats) avg() float64 {
+	if b.count == 0 {
+		return math.NaN()
+	}
+	return b.sum / float64(b.count)
+}
+
+func (ev *evaluator) eval(expr Expr) ([]evalSeries, error) {
+	switch e := expr.(type) {
+	case *selectorExpr:
+		series, err := ev.db.Select(e.matchers, ev.start, ev.end, ev.res)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]evalSeries, 0, len(series))
+		for _, s := range series {
+			out = append(out, ev.bucketize(s.Labels, s.Points))
+		}
+		return out, nil
+
+	case *callExpr:
+		// Read back one window so the first buckets have a full one
+		from := ev.start.Add(-e.arg.window)
+		series, err := ev.db.Select(e.arg.matchers, from, ev.end, ev.db.rangeResolution(from, e.arg.window, ev.res))
+		if err != nil {
+			return nil, err
+		}
+		out := make([]evalSeries, 0, len(series))
+		for _, s := range series {
+			delete(s.Labels, MetricName) // a rate is no longer the metric itself
+			out = append(out, ev.bucketize(s.Labels, ratePoints(s.Points, e.arg.window, e.fn == "irate")))
+		}
+		return out, nil
+
+	case *aggregateExpr:
+		inner, err := ev.eval(e.expr)
+		if err != nil {
+			return nil, err
+		}
+		return ev.aggregate(e, inner), nil
+	}
+	return nil, fmt.Errorf("unsupported expression %s", expr)
+}
+
+func (ev *evaluator) bucketize(labels Labels, points []Point) evalSeries {
+	s := evalSeries{labels: labels, buckets: make([]bucketStats, ev.buckets)}
+	for _, p := range points {
+		if p.Time.Before(ev.start) || p.Time.After(ev.end) {
+			continue
+		}
+		s.buckets[int(p.Time.Sub(ev.start)/ev.step)].add(p)
+	}
+	return s
+}
+
+func (ev *evaluator) aggregate(e *aggregateExpr, inner []evalSeries) []evalSeries {
+	groups := make(map[string][]evalSeries)
+	groupLabels := make(map[string]Labels)
+	for _, s := range inner {
+		labels := make(Labels, len(e.by))
+		for _, name := range e.by {
+			if v, ok := s.labels[name]; ok {
+				labels[name] = v
+			}
+		}
+		key := labels.String()
+		groups[key] = append(groups[key], s)
+		groupLabels[key] = labels
+	}
+
+	out := make([]evalSeries, 0, len(groups))
+	for key, members := range groups {
+		agg := evalSeries{labels: groupLabels[key], buckets: make([]bucketStats, ev.buckets)}
+		for i := range agg.buckets {
+			if v, ok := aggregateBucket(e, members, i); ok {
+				agg.buckets[i].addValue(v)
+			}
+		}
+		out = append(out, agg)
+	}
+	return out
+}
+
+func aggregateBucket(e *aggregateExpr, members []evalSeries, i int) (float64, bool) {
+	var all bucketStats
+	var seriesSum float64
+	var seriesCount int
+	for _, s := range members {
+		b := &s.buckets[i]
+		if b.count == 0 {
+			continue
+		}
+		if seriesCount == 0 {
+			all.min, all.max = b.min, b.max
+		}
+		all.min = math.Min(all.min, b.min)
+		all.max = math.Max(all.max, b.max)
+		all.sum += b.sum
+		all.count += b.count
+		seriesSum += b.avg()
+		seriesCount++
+		if e.op == "percentile" {
+			all.values = append(all.values, b.values...)
+		}
+	}
+	if seriesCount == 0 {
+		return 0, false
+	}
+
+	switch e.op {
+	case "avg":
+		return all.avg(), true
+	case "min":
+		return all.min, true
+	case "max":
+		return all.max, true
+	case "sum":
+		return seriesSum, true
+	case "count":
+		return float64(seriesCount), true
+	}
+	return percentile(all.values, e.param), true
+}
+
+// percentile interpolates linearly between the closest ranks.
+func percentile(values []float64, q float64) float64 {
+	sort.Float64s(values)
+	rank := q / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= len(values) {
+		return values[len(values)-1]
+	}
+	return values[lower] + (rank-float64(lower))*(values[lower+1]-values[lower])
+}
+
+// ratePoints turns counter points into per-second rates, one per point
+// with an earlier point inside the window. A decrease is taken as a counter
+// reset. rate averages over the whole window; irate uses the last two
+// points only.
+func ratePoints(points []Point, window time.Duration, instant bool) []Point {
+	// increase[i] is the counter's increase from the first point to point i
+	increase := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		increase[i] = increase[i-1] + counterDelta(points[i-1].Avg(), points[i].Avg())
+	}
+
+	var out []Point
+	first := 0
+	for i, p := range points {
+		for !points[first].Time.Add(window).After(p.Time) {
+			first++
+		}
+		from := first
+		if instant {
+			from = i - 1
+		}
+		if from < first || from >= i {
+			continue
+		}
+		elapsed := p.Time.Sub(points[from].Time).Seconds()
+		v := (increase[i] - increase[from]) / elapsed
+		out = append(out, Point{Time: p.Time, Min: v, Max: v, Sum: v, Count: 1})
+	}
+	return out
+}
+
+func counterDelta(prev, cur float64) float64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
@@ -0,0 +1,174 @@
+// backend/internal/tsdb/series.go
+package tsdb
+
+import (
+	"math"
+	"sort"
+)
+
+// Rollup levels kept for every series, finest first.
+var rollupSteps = [...]Resolution{Minute, Hour}
+
+type series struct {
+	ref    uint64
+	labels Labels
+	lastT  int64    // newest raw timestamp, in unix milliseconds
+	raw    []*chunk // oldest first; only the last one is appended to
+	levels [len(rollupSteps)]*rollup
+}
+
+func newSeries(ref uint64, labels Labels) *series {
+	s := &series{ref: ref, labels: labels, lastT: math.MinInt64}
+	for i, step := range rollupSteps {
+		s.levels[i] = &rollup{step: step.millis()}
+	}
+	return s
+}
+
+// bucket is a rollup interval being accumulated or read back.
+type bucket struct {
+	T     int64 // interval start, unix milliseconds
+	Min   float64
+	Max   float64
+	Sum   float64
+	Count int64
+}
+
+// rollup downsamples a series into fixed intervals of min/max/sum/count.
+type rollup struct {
+	step   int64
+	chunks []*chunk
+	open   bucket // the current interval, not yet in a chunk
+}
+
+func (s *series) append(t int64, v float64) error {
+	if t <= s.lastT {
+		return ErrOutOfOrder
+	}
+	if len(s.raw) == 0 || s.raw[len(s.raw)-1].full() {
+		s.raw = append(s.raw, newChunk(1))
+	}
+	s.raw[len(s.raw)-1].append(t, v)
+	s.lastT = t
+
+	// NaN would poison min/max/avg for the whole interval
+	if !math.IsNaN(v) {
+		for _, r := range s.levels {
+			r.add(t, v)
+		}
+	}
+	return nil
+}
+
+func (r *rollup) add(t int64, v float64) {
+	start := t - t%r.step
+	if r.open.Count > 0 && r.open.T != start {
+		r.flush()
+	}
+	if r.open.Count == 0 {
+		r.open = bucket{T: start, Min: v, Max: v}
+	}
+	r.open.Min = math.Min(r.open.Min, v)
+	r.open.Max = math.Max(r.open.Max, v)
+	r.open.Sum += v
+	r.open.Count++
+}
+
+func (r *rollup) flush() {
+	if len(r.chunks) == 0 || r.chunks[len(r.chunks)-1].full() {
+		r.chunks = append(r.chunks, newChunk(4))
+	}
+	b := r.open
+	r.chunks[len(r.chunks)-1].append(b.T, b.Min, b.Max, b.Sum, float64(b.Count))
+	r.open = bucket{}
+}
+
+// points returns the series' points in [mint, maxt] at the resolution. For
+// rollups, an interval is included if it starts in the range.
+func (s *series) points(mint, maxt int64, res Resolution) []Point {
+	var points []Point
+	if res == Raw {
+		for _, c := range overlapping(s.raw, mint, maxt) {
+			it := c.iterator()
+			for it.next() {
+				if it.t >= mint && it.t <= maxt {
+					v := it.vals[0]
+					points = append(points, Point{Time: fromMillis(it.t), Min: v, Max: v, Sum: v, Count: 1})
+				}
+			}
+		}
+		return points
+	}
+
+	r := s.level(res)
+	if r == nil {
+		return nil
+	}
+	for _, c := range overlapping(r.chunks, mint, maxt) {
+		it := c.iterator()
+		for it.next() {
+			if it.t >= mint && it.t <= maxt {
+				points = append(points, Point{
+					Time:  fromMillis(it.t),
+					Min:   it.vals[0],
+					Max:   it.vals[1],
+					Sum:   it.vals[2],
+					Count: int64(it.vals[3]),
+				})
+			}
+		}
+	}
+	if b := r.open; b.Count > 0 && b.T >= mint && b.T <= maxt {
+		points = append(points, Point{Time: fromMillis(b.T), Min: b.Min, Max: b.Max, Sum: b.Sum, Count: b.Count})
+	}
+	return points
+}
+
+func (s *series) level(res Resolution) *rollup {
+	for i, step := range rollupSteps {
+		if step == res {
+			return s.levels[i]
+		}
+	}
+	return nil
+}
+
+// overlapping returns the chunks that may hold points in [mint, maxt].
+// Chunks are ordered and don't overlap, so both ends are binary searched.
+func overlapping(chunks []*chunk, mint, maxt int64) []*chunk {
+	from := sort.Search(len(chunks), func(i int) bool { return chunks[i].maxT >= mint })
+	to := sort.Search(len(chunks), func(i int) bool { return chunks[i].minT > maxt })
+	if from >= to {
+		return nil
+	}
+	return chunks[from:to]
+}
+
+// truncate drops data older than the retention cutoffs and reports whether
+// the series is now empty.
+func (s *series) truncate(rawCutoff int64, levelCutoffs [len(rollupSteps)]int64) bool {
+	s.raw = dropBefore(s.raw, rawCutoff)
+	empty := len(s.raw) == 0
+	for i, r := range s.levels {
+		r.chunks = dropBefore(r.chunks, levelCutoffs[i])
+		if r.open.Count > 0 && r.open.T < levelCutoffs[i] {
+			r.open = bucket{}
+		}
+		if len(r.chunks) > 0 || r.open.Count > 0 {
+			empty = false
+		}
+	}
+	return empty
+}
+
+// dropBefore removes the leading chunks whose newest point is before cutoff.
+func dropBefore(chunks []*chunk, cutoff int64) []*chunk {
+	n := 0
+	for n < len(chunks) && chunks[n].maxT < cutoff {
+		n++
+	}
+	if n == 0 {
+		return chunks
+	}
+	return append([]*chunk(nil), chunks[n:]...)
+}
@@ -0,0 +1,512 @@
+// backend/internal/tsdb/wal.go
+package tsdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	walFile      = "wal"
+	snapshotFile = "snapshot"
+
+	snapshotVersion = 1
+)
+
+// WAL record types. A record is the type byte, the payload length as a
+// uvarint, the payload and a CRC32 of the payload.
+const (
+	recordSeries  byte = 1 // ref, then label name/value pairs
+	recordSamples byte = 2 // ref, timestamp, value repeated
+)
+
+type walSample struct {
+	ref uint64
+	t   int64
+	v   float64
+}
+
+type wal struct {
+	path   string
+	f      *os.File
+	w      *bufio.Writer
+	dirty  bool            // written since the last fsync
+	logged map[uint64]bool // series with a record since the last checkpoint
+}
+
+func openWAL(path string) (*wal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	return &wal{path: path, f: f, w: bufio.NewWriter(f), logged: make(map[uint64]bool)}, nil
+}
+
+// logSeries buffers a series record; it's written with the next samples.
+func (w *wal) logSeries(ref uint64, labels Labels) {
+	payload := binary.AppendUvarint(nil, ref)
+	payload = binary.AppendUvarint(payload, uint64(len(labels)))
+	for name, value := range labels {
+		payload = appendString(payload, name)
+		payload = appendString(payload, value)
+	}
+	w.writeRecord(recordSeries, payload)
+	w.logged[ref] = true
+}
+
+// logSamples writes a samples record and everything buffered before it to
+// the file. The data survives a process crash from here; Run fsyncs it
+// within a second to survive a machine crash too.
+func (w *wal) logSamples(samples []walSample) error {
+	payload := make([]byte, 0, len(samples)*16)
+	for _, s := range samples {
+		payload = binary.AppendUvarint(payload, s.ref)
+		payload = binary.AppendVarint(payload, s.t)
+		payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(s.v))
+	}
+	w.writeRecord(recordSamples, payload)
+	w.dirty = true
+	return w.w.Flush()
+}
+
+func (w *wal) writeRecord(typ byte, payload []byte) {
+	w.w.WriteByte(typ)
+	w.w.Write(binary.AppendUvarint(nil, uint64(len(payload))))
+	w.w.Write(payload)
+	w.w.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(payload)))
+}
+
+func (w *wal) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return w.f.Sync()
+}
+
+// size flushes the WAL and returns its length in bytes.
+func (w *wal) size() (int64, error) {
+	if err := w.w.Flush(); err != nil {
+		return 0, err
+	}
+	info, err := w.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// discard drops the first offset bytes of the WAL once a snapshot holds
+// them. What follows was written after the snapshot was taken and is kept.
+func (w *wal) discard(offset int64) error {
+	size, err := w.size()
+	if err != nil {
+		return fmt.Errorf("failed to flush WAL: %w", err)
+	}
+	if size == offset {
+		return w.reset()
+	}
+
+	src, err := os.Open(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL: %w", err)
+	}
+	defer src.Close()
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read WAL: %w", err)
+	}
+
+	tmp := w.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL: %w", err)
+	}
+	_, err = io.Copy(f, src)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, w.path)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rewrite WAL: %w", err)
+	}
+
+	// f was opened without O_APPEND; reopen so later writes append
+	f.Close()
+	next, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen WAL: %w", err)
+	}
+	w.f.Close()
+	w.f = next
+	w.w.Reset(next)
+	w.dirty = false
+	return nil
+}
+
+// reset empties the WAL once a snapshot holds everything in it.
+func (w *wal) reset() error {
+	w.w.Reset(w.f)
+	if err := w.f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL: %w", err)
+	}
+	w.dirty = false
+	return w.f.Sync()
+}
+
+func (w *wal) close() error {
+	if err := w.sync(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// replayWAL applies the WAL on top of the loaded snapshot. A torn or
+// corrupt record ends the replay and the WAL is cut there: it's the tail a
+// crash left half-written. Samples already in the snapshot are out of
+// order and skipped.
+func (db *DB) replayWAL() error {
+	path := filepath.Join(db.dir, walFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open WAL: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	refs := make(map[uint64]*series) // WAL ref -> series
+	var offset int64
+	for {
+		typ, payload, n, err := readRecord(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			err = db.applyRecord(typ, payload, refs)
+		}
+		if err != nil {
+			fmt.Printf("Truncating metrics WAL at offset %d: %v\n", offset, err)
+			if err := os.Truncate(path, offset); err != nil {
+				return fmt.Errorf("failed to truncate WAL: %w", err)
+			}
+			return nil
+		}
+		offset += n
+	}
+}
+
+func readRecord(r *bufio.Reader) (byte, []byte, int64, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, 0, err // a clean io.EOF between records
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, 0, unexpectedEOF(err)
+	}
+	if size > 64<<20 {
+		return 0, nil, 0, fmt.Errorf("record of %d bytes", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, 0, unexpectedEOF(err)
+	}
+	var sum [4]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return 0, nil, 0, unexpectedEOF(err)
+	}
+	if binary.BigEndian.Uint32(sum[:]) != crc32.ChecksumIEEE(payload) {
+		return 0, nil, 0, fmt.Errorf("checksum mismatch")
+	}
+	return typ, payload, 1 + int64(uvarintLen(size)) + int64(size) + 4, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func uvarintLen(x uint64) int {
+	return len(binary.AppendUvarint(nil, x))
+}
+
+func (db *DB) applyRecord(typ byte, payload []byte, refs map[uint64]*series) error {
+	d := decoder{b: payload}
+	switch typ {
+	case recordSeries:
+		ref := d.uvarint()
+		labels := make(Labels)
+		for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+			name := d.string()
+			labels[name] = d.string()
+		}
+		if d.err != nil {
+			return d.err
+		}
+		refs[ref] = db.getOrCreate(labels)
+	case recordSamples:
+		for len(d.b) > 0 && d.err == nil {
+			ref, t, v := d.uvarint(), d.varint(), d.float()
+			if d.err != nil {
+				break
+			}
+			s, ok := refs[ref]
+			if !ok {
+				return fmt.Errorf("sample for unknown series %d", ref)
+			}
+			s.append(t, v) // out of order means it's already in the snapshot
+		}
+		return d.err
+	default:
+		return fmt.Errorf("unknown record type %d", typ)
+	}
+	return nil
+}
+
+type decoder struct {
+	b   []byte
+	err error
+}
+
+var errShortRecord = errors.New("short record")
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errShortRecord
+		return 0
+	}
+	d.b = d.b[n:]
+	return x
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errShortRecord
+		return 0
+	}
+	d.b = d.b[n:]
+	return x
+}
+
+func (d *decoder) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) < 8 {
+		d.err = errShortRecord
+		return 0
+	}
+	v := math.Float64frombits(binary.BigEndian.Uint64(d.b))
+	d.b = d.b[8:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if uint64(len(d.b)) < n {
+		d.err = errShortRecord
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+type snapshot struct {
+	Version int
+	Series  []snapshotSeries
+}
+
+type snapshotSeries struct {
+	Labels Labels
+	LastT  int64
+	Raw    []snapshotChunk
+	Levels []snapshotRollup
+}
+
+type snapshotRollup struct {
+	Chunks []snapshotChunk
+	Open   bucket
+}
+
+type snapshotChunk struct {
+	Data  []byte
+	Bits  uint64
+	Count int
+}
+
+// Checkpoint writes every series to the snapshot and drops what it holds
+// from the WAL. The series are copied under the lock; encoding and syncing
+// the snapshot happen outside it so appends carry on meanwhile.
+func (db *DB) Checkpoint() error {
+	db.checkpointMu.Lock()
+	defer db.checkpointMu.Unlock()
+
+	db.mu.Lock()
+	if db.wal == nil {
+		db.mu.Unlock()
+		return nil
+	}
+	offset, err := db.wal.size()
+	if err != nil {
+		db.mu.Unlock()
+		return fmt.Errorf("failed to flush WAL: %w", err)
+	}
+	// Series are logged again on their next append, so the part of the WAL
+	// kept after the snapshot names every series it refers to
+	db.wal.logged = make(map[uint64]bool)
+	snap := db.snapshotLocked()
+	db.mu.Unlock()
+
+	if err := db.writeSnapshot(snap); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.wal == nil {
+		return nil
+	}
+	// A crash before this only means replaying samples the snapshot has
+	return db.wal.discard(offset)
+}
+
+// snapshotLocked copies the series for a snapshot. Closed chunks are never
+// written again and are shared; the open chunk of each list is copied.
+func (db *DB) snapshotLocked() snapshot {
+	snap := snapshot{Version: snapshotVersion, Series: make([]snapshotSeries, 0, len(db.series))}
+	for _, s := range db.series {
+		ss := snapshotSeries{Labels: s.labels, LastT: s.lastT, Raw: snapshotChunks(s.raw)}
+		for _, r := range s.levels {
+			ss.Levels = append(ss.Levels, snapshotRollup{Chunks: snapshotChunks(r.chunks), Open: r.open})
+		}
+		snap.Series = append(snap.Series, ss)
+	}
+	return snap
+}
+
+func (db *DB) writeSnapshot(snap snapshot) error {
+	path := filepath.Join(db.dir, snapshotFile)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(snap)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+func snapshotChunks(chunks []*chunk) []snapshotChunk {
+	out := make([]snapshotChunk, len(chunks))
+	for i, c := range chunks {
+		data := c.stream.b
+		if i == len(chunks)-1 {
+			// Appends write into the last byte of the open chunk
+			data = append([]byte(nil), data...)
+		}
+		out[i] = snapshotChunk{Data: data, Bits: c.stream.n, Count: c.count}
+	}
+	return out
+}
+
+func (db *DB) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(db.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	var snap snapshot
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	for _, ss := range snap.Series {
+		s := db.getOrCreate(ss.Labels)
+		s.lastT = ss.LastT
+		if s.raw, err = loadChunks(1, ss.Raw); err != nil {
+			return fmt.Errorf("series %s: %w", ss.Labels, err)
+		}
+		for i, r := range s.levels {
+			if i >= len(ss.Levels) {
+				break
+			}
+			if r.chunks, err = loadChunks(4, ss.Levels[i].Chunks); err != nil {
+				return fmt.Errorf("series %s: %w", ss.Labels, err)
+			}
+			r.open = ss.Levels[i].Open
+		}
+	}
+	return nil
+}
+
+func loadChunks(cols int, in []snapshotChunk) ([]*chunk, error) {
+	chunks := make([]*chunk, 0, len(in))
+	for _, sc := range in {
+		c, err := loadChunk(cols, sc.Data, sc.Bits, sc.Count)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, nil
+}
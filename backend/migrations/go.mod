@@ -10,6 +10,7 @@ require (
     github.com/swaggo/swag v1.16.1
     gorm.io/driver/postgres v1.5.2
     gorm.io/gorm v1.25.2
+    gopkg.in/yaml.v3 v3.0.1
 )
 
 require (